@@ -0,0 +1,248 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/alvarezcarlos/payment/app/utils"
+	"github.com/google/uuid"
+)
+
+const maxVerificationAttempts = 3
+
+var (
+	ErrBankAccountNotFound    = errors.New("bank account not found")
+	ErrInvalidBankAccount     = errors.New("invalid bank account")
+	ErrBankAccountNotVerified = errors.New("bank account is not verified")
+	ErrVerificationFailed     = errors.New("micro-deposit amounts don't match")
+	ErrVerificationExhausted  = errors.New("bank account verification failed, too many attempts")
+	ErrPayoutCurrencyChange   = errors.New("the payout currency can't change while the merchant has funds")
+)
+
+type bankAccountUseCase struct {
+	repository      repository.BankAccountRepository
+	settlements     repository.SettlementRepository
+	cipher          *utils.Cipher
+	defaultCurrency string
+	logger          *slog.Logger
+}
+
+func NewBankAccountUseCase(repository repository.BankAccountRepository, settlements repository.SettlementRepository,
+	cipher *utils.Cipher, defaultCurrency string, logger *slog.Logger) BankAccountUseCaseInterface {
+	return &bankAccountUseCase{repository: repository, settlements: settlements, cipher: cipher,
+		defaultCurrency: defaultCurrency, logger: logger}
+}
+
+// Create validates and registers a payout destination with its account number encrypted, two micro-deposits
+// are simulated and the account stays pending until the merchant confirms their amounts
+func (b *bankAccountUseCase) Create(ctx context.Context, account *entity.BankAccount, accountNumber string) (*entity.BankAccount, error) {
+	switch account.Type {
+	case entity.BankAccountIBAN:
+		accountNumber = utils.NormalizeIBAN(accountNumber)
+		if !utils.ValidIBAN(accountNumber) {
+			return nil, ErrInvalidBankAccount
+		}
+		account.Country, account.RoutingNumber = accountNumber[:2], ""
+	case entity.BankAccountACH:
+		if !utils.ValidRoutingNumber(account.RoutingNumber) || len(accountNumber) < 4 || len(accountNumber) > 17 {
+			return nil, ErrInvalidBankAccount
+		}
+		account.Country = "US"
+	default:
+		return nil, ErrInvalidBankAccount
+	}
+
+	encrypted, err := b.cipher.Encrypt(accountNumber)
+	if err != nil {
+		b.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error creating bank account")
+	}
+
+	account.ID = uuid.New()
+	account.Currency = strings.ToUpper(account.Currency)
+	account.AccountNumberEncrypted = encrypted
+	account.Last4 = accountNumber[len(accountNumber)-4:]
+	account.Status = entity.BankAccountPendingVerification
+	account.MicroDeposit1, account.MicroDeposit2 = microDeposit(), microDeposit()
+	account.CreatedAt, account.UpdatedAt = time.Now(), time.Now()
+	if err = b.repository.Create(ctx, account); err != nil {
+		b.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error creating bank account")
+	}
+
+	b.logger.InfoContext(ctx, "bank account created", "bank_account_id", account.ID, "merchant_id", account.MerchantID)
+	b.logger.DebugContext(ctx, "simulated micro-deposits sent", "bank_account_id", account.ID,
+		"amount_1", account.MicroDeposit1, "amount_2", account.MicroDeposit2)
+	return account, nil
+}
+
+// List the bank accounts of the merchant
+func (b *bankAccountUseCase) List(ctx context.Context, merchantID uint) ([]entity.BankAccount, error) {
+	accounts, err := b.repository.ListByMerchant(ctx, merchantID)
+	if err != nil {
+		b.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error fetching bank accounts")
+	}
+	return accounts, nil
+}
+
+// Verify confirms the micro-deposit amounts, the first verified account becomes the default one
+func (b *bankAccountUseCase) Verify(ctx context.Context, id uuid.UUID, merchantID uint, amounts [2]float64) (*entity.BankAccount, error) {
+	account, err := b.get(ctx, id, merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch account.Status {
+	case entity.BankAccountVerified:
+		return account, nil
+	case entity.BankAccountVerificationFailed:
+		return nil, ErrVerificationExhausted
+	}
+
+	expected := [2]int64{cents(account.MicroDeposit1), cents(account.MicroDeposit2)}
+	given := [2]int64{cents(amounts[0]), cents(amounts[1])}
+	matches := given == expected || given == [2]int64{expected[1], expected[0]}
+
+	if !matches {
+		account.VerificationAttempts++
+		result := ErrVerificationFailed
+		if account.VerificationAttempts >= maxVerificationAttempts {
+			account.Status = entity.BankAccountVerificationFailed
+			result = ErrVerificationExhausted
+		}
+		if err = b.repository.Update(ctx, account); err != nil {
+			b.logger.ErrorContext(ctx, err.Error())
+		}
+		return nil, result
+	}
+
+	account.Status = entity.BankAccountVerified
+	account.UpdatedAt = time.Now()
+	if err = b.repository.Update(ctx, account); err != nil {
+		b.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error verifying bank account")
+	}
+
+	if _, err = b.repository.GetDefault(ctx, merchantID); err != nil {
+		if err = b.checkPayoutCurrency(ctx, account); err != nil {
+			b.logger.WarnContext(ctx, "verified bank account not made the default", "bank_account_id", account.ID, "reason", err.Error())
+		} else if err = b.repository.SetDefault(ctx, account); err != nil {
+			b.logger.ErrorContext(ctx, err.Error())
+		}
+	}
+	return account, nil
+}
+
+// SetDefault selects the verified account receiving the merchant payouts
+func (b *bankAccountUseCase) SetDefault(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.BankAccount, error) {
+	account, err := b.get(ctx, id, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status != entity.BankAccountVerified {
+		return nil, ErrBankAccountNotVerified
+	}
+	if err = b.checkPayoutCurrency(ctx, account); err != nil {
+		return nil, err
+	}
+	if err = b.repository.SetDefault(ctx, account); err != nil {
+		b.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error updating bank account")
+	}
+	return account, nil
+}
+
+// Delete removes a bank account of the merchant
+func (b *bankAccountUseCase) Delete(ctx context.Context, id uuid.UUID, merchantID uint) error {
+	account, err := b.get(ctx, id, merchantID)
+	if err != nil {
+		return err
+	}
+	if err = b.repository.Delete(ctx, account); err != nil {
+		b.logger.ErrorContext(ctx, err.Error())
+		return errors.New("error deleting bank account")
+	}
+	return nil
+}
+
+// RotateKeys re-encrypts the account numbers sealed with an older key of the ring with the current key, once
+// done the older keys can be removed from ENCRYPTION_KEYS. It returns the number of accounts re-encrypted
+func (b *bankAccountUseCase) RotateKeys(ctx context.Context) (int, error) {
+	accounts, err := b.repository.ListNotEncryptedWith(ctx, b.cipher.CurrentKeyID())
+	if err != nil {
+		b.logger.ErrorContext(ctx, err.Error())
+		return 0, errors.New("error rotating encryption keys")
+	}
+
+	rotated := 0
+	for i := range accounts {
+		account := &accounts[i]
+		accountNumber, err := b.cipher.Decrypt(account.AccountNumberEncrypted)
+		if err != nil {
+			b.logger.ErrorContext(ctx, "error decrypting bank account", "bank_account_id", account.ID, "error", err.Error())
+			return rotated, errors.New("error rotating encryption keys")
+		}
+		if account.AccountNumberEncrypted, err = b.cipher.Encrypt(accountNumber); err != nil {
+			b.logger.ErrorContext(ctx, err.Error())
+			return rotated, errors.New("error rotating encryption keys")
+		}
+		if err = b.repository.Update(ctx, account); err != nil {
+			b.logger.ErrorContext(ctx, err.Error())
+			return rotated, errors.New("error rotating encryption keys")
+		}
+		rotated++
+	}
+	b.logger.InfoContext(ctx, "bank accounts re-encrypted", "key_id", b.cipher.CurrentKeyID(), "accounts", rotated)
+	return rotated, nil
+}
+
+// checkPayoutCurrency the account can only become the default in another currency than the merchant is paid out
+// in while the merchant has no funds, the balance would otherwise be paid out in a currency it wasn't taken in
+func (b *bankAccountUseCase) checkPayoutCurrency(ctx context.Context, account *entity.BankAccount) error {
+	if account.Currency == payoutCurrency(ctx, b.repository, account.MerchantID, b.defaultCurrency) {
+		return nil
+	}
+	balance, err := b.settlements.Balance(ctx, account.MerchantID, time.Now())
+	if err != nil {
+		b.logger.ErrorContext(ctx, err.Error())
+		return errors.New("error updating bank account")
+	}
+	if roundCents(balance.Balance) != 0 {
+		return ErrPayoutCurrencyChange
+	}
+	return nil
+}
+
+func (b *bankAccountUseCase) get(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.BankAccount, error) {
+	account, err := b.repository.GetByID(ctx, id)
+	if err != nil || account.MerchantID != merchantID {
+		return nil, ErrBankAccountNotFound
+	}
+	return account, nil
+}
+
+// payoutCurrency the currency the merchant is paid out in, the one of its default bank account or the default
+// currency while it has none. The balance and the payouts of a merchant hold this currency only
+func payoutCurrency(ctx context.Context, accounts repository.BankAccountRepository, merchantID uint, defaultCurrency string) string {
+	if account, err := accounts.GetDefault(ctx, merchantID); err == nil {
+		return account.Currency
+	}
+	return defaultCurrency
+}
+
+// microDeposit random amount between 0.01 and 0.99
+func microDeposit() float64 {
+	return float64(rand.Intn(99)+1) / 100
+}
+
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
@@ -0,0 +1,308 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/config"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDisputeNotFound      = errors.New("dispute not found")
+	ErrDisputeInvalidStatus = errors.New("invalid dispute status for the operation")
+	ErrDisputeDeadline      = errors.New("the dispute response deadline has passed")
+	ErrEvidenceRequired     = errors.New("at least one evidence is required")
+	ErrInvalidEvidence      = errors.New("invalid evidence file")
+)
+
+var evidenceContentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/png":       true,
+	"image/jpeg":      true,
+	"text/plain":      true,
+}
+
+type disputeUseCase struct {
+	disputes repository.DisputeRepository
+	payments repository.PaymentRepository
+	acquirer repository.Acquirer
+	files    repository.FileStore
+	settings config.DisputeConfig
+	logger   *slog.Logger
+}
+
+func NewDisputeUseCase(disputes repository.DisputeRepository, payments repository.PaymentRepository,
+	acquirer repository.Acquirer, files repository.FileStore, settings config.DisputeConfig,
+	logger *slog.Logger) DisputeUseCaseInterface {
+	return &disputeUseCase{
+		disputes: disputes,
+		payments: payments,
+		acquirer: acquirer,
+		files:    files,
+		settings: settings,
+		logger:   logger,
+	}
+}
+
+// Sync opens the disputes notified by the acquirer and closes as lost the ones the merchant didn't answer in time
+func (d *disputeUseCase) Sync(ctx context.Context, now time.Time) error {
+	var failed int
+	// a notice is acknowledged once its dispute is recorded, the ones that failed are retried on the next sync
+	for _, notice := range d.acquirer.Disputes() {
+		if err := d.open(ctx, notice, now); err != nil {
+			d.logger.ErrorContext(ctx, err.Error(), "payment_id", notice.PaymentID)
+			failed++
+			continue
+		}
+		d.acquirer.AckDispute(notice.PaymentID)
+	}
+
+	overdue, err := d.disputes.Overdue(ctx, now)
+	if err != nil {
+		return err
+	}
+	for i := range overdue {
+		if err = d.lose(ctx, &overdue[i], now); err != nil {
+			d.logger.ErrorContext(ctx, err.Error(), "dispute_id", overdue[i].ID)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d disputes failed to sync", failed)
+	}
+	return nil
+}
+
+// List the disputes of the merchant, optionally filtered by status
+func (d *disputeUseCase) List(ctx context.Context, merchantID uint, status entity.DisputeStatusEnum) ([]entity.Dispute, error) {
+	disputes, err := d.disputes.List(ctx, merchantID, status)
+	if err != nil {
+		d.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error fetching disputes")
+	}
+	return disputes, nil
+}
+
+// GetByID retrieve a dispute with its evidence, only the merchant of the disputed payment can see it
+func (d *disputeUseCase) GetByID(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.Dispute, error) {
+	dispute, err := d.disputes.GetByID(ctx, id)
+	if err != nil || dispute.MerchantID != merchantID {
+		return nil, ErrDisputeNotFound
+	}
+	return dispute, nil
+}
+
+// AddEvidence stores a document contesting the dispute, evidence is accepted until it is submitted or the
+// response deadline passes
+func (d *disputeUseCase) AddEvidence(ctx context.Context, id uuid.UUID, merchantID uint, evidence *entity.DisputeEvidence,
+	content io.Reader) (*entity.DisputeEvidence, error) {
+	dispute, err := d.respondable(ctx, id, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if !evidenceContentTypes[evidence.ContentType] || evidence.Size <= 0 || evidence.Size > d.settings.MaxEvidenceSize {
+		return nil, ErrInvalidEvidence
+	}
+
+	evidence.ID = uuid.New()
+	evidence.DisputeID = dispute.ID
+	evidence.FileName = path.Base(evidence.FileName)
+	evidence.Path = fmt.Sprintf("disputes/%d/%s/%s", merchantID, dispute.ID, evidence.ID)
+	evidence.CreatedAt = time.Now()
+
+	file, err := d.files.Create(evidence.Path)
+	if err != nil {
+		d.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error storing evidence")
+	}
+	written, err := io.Copy(file, io.LimitReader(content, d.settings.MaxEvidenceSize+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		d.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error storing evidence")
+	}
+	if written > d.settings.MaxEvidenceSize {
+		return nil, ErrInvalidEvidence
+	}
+	evidence.Size = written
+
+	if err = d.disputes.AddEvidence(ctx, evidence); err != nil {
+		d.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error storing evidence")
+	}
+	d.logger.InfoContext(ctx, "dispute evidence added", "dispute_id", dispute.ID, "evidence_id", evidence.ID)
+	return evidence, nil
+}
+
+// Submit sends the evidence to the acquirer, the dispute stays under review until it is resolved
+func (d *disputeUseCase) Submit(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.Dispute, error) {
+	dispute, err := d.respondable(ctx, id, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(dispute.Evidence) == 0 {
+		return nil, ErrEvidenceRequired
+	}
+
+	dispute.Status, dispute.UpdatedAt = entity.DisputeUnderReview, time.Now()
+	err = d.disputes.Transition(ctx, dispute, entity.DisputeNeedsResponse, nil)
+	if errors.Is(err, repository.ErrStatusChanged) {
+		return nil, ErrDisputeInvalidStatus
+	}
+	if err != nil {
+		d.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error submitting dispute")
+	}
+	d.logger.InfoContext(ctx, "dispute submitted for review", "dispute_id", dispute.ID)
+	return dispute, nil
+}
+
+// Accept the merchant concedes the dispute, it is lost without waiting for the deadline
+func (d *disputeUseCase) Accept(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.Dispute, error) {
+	dispute, err := d.respondable(ctx, id, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	err = d.lose(ctx, dispute, time.Now())
+	if errors.Is(err, repository.ErrStatusChanged) {
+		return nil, ErrDisputeInvalidStatus
+	}
+	if err != nil {
+		d.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error accepting dispute")
+	}
+	return dispute, nil
+}
+
+// Resolve records the decision of the acquirer on a dispute under review, a won dispute gives the
+// disputed amount back to the merchant
+func (d *disputeUseCase) Resolve(ctx context.Context, id uuid.UUID, won bool) (*entity.Dispute, error) {
+	dispute, err := d.disputes.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrDisputeNotFound
+	}
+	if dispute.Status != entity.DisputeUnderReview {
+		return nil, ErrDisputeInvalidStatus
+	}
+
+	now := time.Now()
+	if !won {
+		err = d.lose(ctx, dispute, now)
+		if errors.Is(err, repository.ErrStatusChanged) {
+			return nil, ErrDisputeInvalidStatus
+		}
+		if err != nil {
+			d.logger.ErrorContext(ctx, err.Error())
+			return nil, errors.New("error resolving dispute")
+		}
+		return dispute, nil
+	}
+
+	payment, err := d.payments.GetByID(ctx, dispute.PaymentID)
+	if err != nil {
+		d.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error resolving dispute")
+	}
+	payment.AddState(entity.Succeeded)
+	payment.UpdatedAt = now
+	dispute.Status, dispute.ResolvedAt, dispute.UpdatedAt = entity.DisputeWon, &now, now
+	reversal := entity.BalanceTransaction{
+		MerchantID:  dispute.MerchantID,
+		PaymentID:   &dispute.PaymentID,
+		Type:        entity.TransactionDisputeReversal,
+		Amount:      dispute.Amount,
+		AvailableOn: now,
+		CreatedAt:   now,
+	}
+	err = d.disputes.Transition(ctx, dispute, entity.DisputeUnderReview, payment, reversal)
+	if errors.Is(err, repository.ErrStatusChanged) {
+		return nil, ErrDisputeInvalidStatus
+	}
+	if err != nil {
+		d.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error resolving dispute")
+	}
+	d.logger.InfoContext(ctx, "dispute won", "dispute_id", dispute.ID, "payment_id", dispute.PaymentID)
+	return dispute, nil
+}
+
+// open registers the dispute of a captured payment and withdraws the disputed amount from the merchant
+func (d *disputeUseCase) open(ctx context.Context, notice repository.DisputeNotice, now time.Time) error {
+	if _, err := d.disputes.GetByPaymentID(ctx, notice.PaymentID); err == nil {
+		return nil
+	}
+
+	payment, err := d.payments.GetByID(ctx, notice.PaymentID)
+	if err != nil {
+		return err
+	}
+	if payment.CurrentState() != entity.Succeeded {
+		d.logger.WarnContext(ctx, "dispute ignored, payment is not captured", "payment_id", payment.ID, "status", payment.Status)
+		return nil
+	}
+
+	dispute := &entity.Dispute{
+		ID:            uuid.New(),
+		PaymentID:     payment.ID,
+		MerchantID:    payment.MerchantID,
+		Amount:        payment.Amount,
+		Currency:      payment.Currency,
+		Reason:        notice.Reason,
+		Status:        entity.DisputeNeedsResponse,
+		EvidenceDueBy: now.Add(d.settings.ResponseWindow),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	payment.AddState(entity.Disputed)
+	payment.UpdatedAt = now
+	withdrawal := entity.BalanceTransaction{
+		MerchantID:  payment.MerchantID,
+		PaymentID:   &payment.ID,
+		Type:        entity.TransactionDispute,
+		Amount:      -payment.Amount,
+		AvailableOn: now,
+		CreatedAt:   now,
+	}
+	if err = d.disputes.Open(ctx, dispute, payment, withdrawal); err != nil {
+		return err
+	}
+	d.logger.InfoContext(ctx, "dispute opened", "dispute_id", dispute.ID, "payment_id", payment.ID, "reason", dispute.Reason)
+	return nil
+}
+
+// lose closes the dispute in favour of the cardholder, the funds withdrawn on open are not returned
+func (d *disputeUseCase) lose(ctx context.Context, dispute *entity.Dispute, now time.Time) error {
+	from := dispute.Status
+	dispute.Status, dispute.ResolvedAt, dispute.UpdatedAt = entity.DisputeLost, &now, now
+	if err := d.disputes.Transition(ctx, dispute, from, nil); err != nil {
+		return err
+	}
+	d.logger.InfoContext(ctx, "dispute lost", "dispute_id", dispute.ID, "payment_id", dispute.PaymentID)
+	return nil
+}
+
+// respondable the dispute of the merchant when it still accepts a response
+func (d *disputeUseCase) respondable(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.Dispute, error) {
+	dispute, err := d.GetByID(ctx, id, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if dispute.Status != entity.DisputeNeedsResponse {
+		return nil, ErrDisputeInvalidStatus
+	}
+	if time.Now().After(dispute.EvidenceDueBy) {
+		return nil, ErrDisputeDeadline
+	}
+	return dispute, nil
+}
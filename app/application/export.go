@@ -0,0 +1,332 @@
+package application
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/config"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/alvarezcarlos/payment/app/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const exportBatchSize = 500
+
+var (
+	ErrExportNotFound  = errors.New("export not found")
+	ErrExportNotReady  = errors.New("export is not completed")
+	ErrInvalidDateSpan = errors.New("invalid date range")
+	ErrExportQueueFull = errors.New("too many exports in progress, try again later")
+)
+
+// exportRow one accounting line of the export, a payment and its refund are written as separate rows with
+// the fees charged for each of them
+type exportRow struct {
+	Type               string  `json:"type"`
+	PaymentID          string  `json:"payment_id"`
+	Amount             float64 `json:"amount"`
+	Fee                float64 `json:"fee"`
+	Net                float64 `json:"net"`
+	Status             string  `json:"status"`
+	CardLast4          string  `json:"card_last4"`
+	CustomerPersonalID uint    `json:"customer_personal_id"`
+	Reference          string  `json:"reference"`
+	CreatedAt          string  `json:"created_at"`
+}
+
+var exportHeader = []string{"type", "payment_id", "amount", "fee", "net", "status", "card_last4",
+	"customer_personal_id", "reference", "created_at"}
+
+func (r exportRow) record() []string {
+	return []string{r.Type, r.PaymentID, strconv.FormatFloat(r.Amount, 'f', 2, 64),
+		strconv.FormatFloat(r.Fee, 'f', 2, 64), strconv.FormatFloat(r.Net, 'f', 2, 64), r.Status, r.CardLast4,
+		strconv.Itoa(int(r.CustomerPersonalID)), csvText(r.Reference), r.CreatedAt}
+}
+
+// csvText keeps a spreadsheet from evaluating a merchant supplied cell as a formula by prefixing it with a quote
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+type exportUseCase struct {
+	exports  repository.ExportRepository
+	payments repository.PaymentRepository
+	files    repository.FileStore
+	settings config.ExportConfig
+	queue    chan entity.Export
+	logger   *slog.Logger
+}
+
+func NewExportUseCase(exports repository.ExportRepository, payments repository.PaymentRepository,
+	files repository.FileStore, settings config.ExportConfig, logger *slog.Logger) ExportUseCaseInterface {
+	return &exportUseCase{exports: exports, payments: payments, files: files, settings: settings,
+		queue: make(chan entity.Export, settings.QueueSize), logger: logger}
+}
+
+// Start generates the queued exports on Workers goroutines until the context is cancelled, an export interrupted
+// by the cancellation is failed and the ones still queued are left pending for FailAbandoned
+func (e *exportUseCase) Start(ctx context.Context) {
+	for i := 0; i < e.settings.Workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case export := <-e.queue:
+					e.run(ctx, export)
+				}
+			}
+		}()
+	}
+}
+
+// Create registers the export job and queues the generation of the file, its status must be polled with GetByID.
+// The export is failed right away when the queue is full
+func (e *exportUseCase) Create(ctx context.Context, export *entity.Export) (*entity.Export, error) {
+	if !export.To.After(export.From) {
+		return nil, ErrInvalidDateSpan
+	}
+	export.ID = uuid.New()
+	export.Status = entity.ExportPending
+	export.FileName = fmt.Sprintf("exports/%d/%s.%s", export.MerchantID, export.ID, export.Format)
+	export.CreatedAt = time.Now()
+	if err := e.exports.Create(ctx, export); err != nil {
+		e.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error creating export")
+	}
+
+	select {
+	case e.queue <- *export:
+	default:
+		now := time.Now()
+		export.Status, export.Error, export.CompletedAt = entity.ExportFailed, ErrExportQueueFull.Error(), &now
+		if err := e.exports.Update(ctx, export); err != nil {
+			e.logger.ErrorContext(ctx, err.Error())
+		}
+		return nil, ErrExportQueueFull
+	}
+	return export, nil
+}
+
+// GetByID retrieve an export of the merchant
+func (e *exportUseCase) GetByID(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.Export, error) {
+	export, err := e.exports.GetByID(ctx, id)
+	if err != nil || export.MerchantID != merchantID {
+		return nil, ErrExportNotFound
+	}
+	return export, nil
+}
+
+// Open returns the generated file of a completed export
+func (e *exportUseCase) Open(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.Export, io.ReadCloser, error) {
+	export, err := e.GetByID(ctx, id, merchantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.Status != entity.ExportCompleted {
+		return nil, nil, ErrExportNotReady
+	}
+	file, err := e.files.Open(export.FileName)
+	if err != nil {
+		e.logger.ErrorContext(ctx, err.Error())
+		return nil, nil, errors.New("error opening export file")
+	}
+	return export, file, nil
+}
+
+// FailAbandoned fails the exports left unfinished by an instance that stopped while generating them
+func (e *exportUseCase) FailAbandoned(ctx context.Context, now time.Time) error {
+	failed, err := e.exports.FailAbandoned(ctx, now.Add(-e.settings.Timeout), "export abandoned")
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		e.logger.WarnContext(ctx, "abandoned exports failed", "count", failed)
+	}
+	return nil
+}
+
+func (e *exportUseCase) run(ctx context.Context, export entity.Export) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			e.logger.ErrorContext(ctx, "export panicked", "export_id", export.ID, "panic", fmt.Sprint(recovered))
+			now := time.Now()
+			export.Status, export.Error, export.CompletedAt = entity.ExportFailed, "internal error", &now
+			if err := e.exports.Update(context.WithoutCancel(ctx), &export); err != nil {
+				e.logger.ErrorContext(ctx, err.Error())
+			}
+		}
+	}()
+
+	export.Status = entity.ExportRunning
+	if err := e.exports.Update(ctx, &export); err != nil {
+		e.logger.ErrorContext(ctx, err.Error())
+	}
+
+	rows, err := e.write(ctx, &export)
+	now := time.Now()
+	export.CompletedAt = &now
+	export.RowCount = rows
+	if err != nil {
+		e.logger.ErrorContext(ctx, "export failed", "export_id", export.ID, "error", err.Error())
+		export.Status, export.Error = entity.ExportFailed, err.Error()
+	} else {
+		export.Status = entity.ExportCompleted
+	}
+
+	// the outcome is recorded even when the export was interrupted by a shutdown
+	if err = e.exports.Update(context.WithoutCancel(ctx), &export); err != nil {
+		e.logger.ErrorContext(ctx, err.Error())
+	}
+	e.logger.InfoContext(ctx, "export finished", "export_id", export.ID, "status", export.Status, "rows", rows)
+}
+
+// write streams the payments and then the refunds of the range in batches to the file store, the export runs
+// detached from the request that created it so it is traced on its own. The file is only complete once it is
+// closed, so a close error fails the export
+func (e *exportUseCase) write(ctx context.Context, export *entity.Export) (rows int, err error) {
+	ctx, span := tracing.Start(ctx, "exportUseCase.write", attribute.String("export.id", export.ID.String()))
+	defer span.End()
+
+	file, err := e.files.Create(export.FileName)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	var emit func(exportRow) error
+	var flush func() error
+	switch export.Format {
+	case entity.ExportCSV:
+		w := csv.NewWriter(file)
+		if err = w.Write(exportHeader); err != nil {
+			return 0, err
+		}
+		emit = func(r exportRow) error { return w.Write(r.record()) }
+		flush = func() error { w.Flush(); return w.Error() }
+	default:
+		encoder := json.NewEncoder(file)
+		emit = func(r exportRow) error { return encoder.Encode(r) }
+		flush = func() error { return nil }
+	}
+
+	filter := repository.PaymentFilter{
+		MerchantID: export.MerchantID,
+		From:       &export.From,
+		To:         &export.To,
+		SortBy:     repository.SortByCreatedAt,
+		Limit:      exportBatchSize,
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return rows, err
+		}
+		payments, err := e.payments.List(repository.ReadOnly(ctx), filter)
+		if err != nil {
+			return rows, err
+		}
+		for _, payment := range payments {
+			row, ok := chargeRow(payment)
+			if !ok {
+				continue
+			}
+			if err = emit(row); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+		if len(payments) < exportBatchSize {
+			break
+		}
+		last := payments[len(payments)-1]
+		filter.After = &repository.PaymentCursor{ID: last.ID, CreatedAt: last.CreatedAt, SortBy: repository.SortByCreatedAt}
+	}
+
+	// refunds belong to the range they were made in, whenever their payment was created
+	var afterID uint
+	for {
+		if err := ctx.Err(); err != nil {
+			return rows, err
+		}
+		refunds, err := e.exports.Refunds(ctx, export.MerchantID, export.From, export.To, afterID, exportBatchSize)
+		if err != nil {
+			return rows, err
+		}
+		for _, refund := range refunds {
+			if refund.PaymentID == nil {
+				continue
+			}
+			payment, err := e.payments.GetByID(repository.ReadOnly(ctx), *refund.PaymentID)
+			if err != nil {
+				return rows, err
+			}
+			if err = emit(refundRow(*payment, refund)); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+		if len(refunds) < exportBatchSize {
+			break
+		}
+		afterID = refunds[len(refunds)-1].ID
+	}
+	return rows, flush()
+}
+
+// chargeRow maps a payment to its accounting line, payments that never succeeded move no money and are skipped
+func chargeRow(payment entity.Payment) (exportRow, bool) {
+	if _, succeeded := statesToMap(payment.States)[string(entity.Succeeded)]; !succeeded {
+		return exportRow{}, false
+	}
+	row := baseRow(payment)
+	row.Type, row.Amount, row.Fee = "payment", payment.Amount, feeTotal(payment, entity.FeeProcessing)
+	row.Net = roundCents(row.Amount - row.Fee)
+	row.CreatedAt = payment.CreatedAt.Format(time.RFC3339)
+	return row, true
+}
+
+// refundRow maps the refund transaction of a payment to its accounting line, dated when the refund was made
+func refundRow(payment entity.Payment, refund entity.BalanceTransaction) exportRow {
+	row := baseRow(payment)
+	row.Type, row.Amount, row.Fee = "refund", -payment.Amount, feeTotal(payment, entity.FeeRefund)
+	row.Net = roundCents(row.Amount - row.Fee)
+	row.CreatedAt = refund.CreatedAt.Format(time.RFC3339)
+	return row
+}
+
+func baseRow(payment entity.Payment) exportRow {
+	return exportRow{
+		PaymentID:          payment.ID.String(),
+		Status:             string(payment.CurrentState()),
+		CardLast4:          payment.CardLast4,
+		CustomerPersonalID: payment.CustomerPersonalID,
+		Reference:          payment.Reference,
+	}
+}
+
+func feeTotal(payment entity.Payment, feeType entity.FeeTypeEnum) float64 {
+	var total float64
+	for _, line := range payment.FeeLines {
+		if line.Type == feeType {
+			total += line.Amount
+		}
+	}
+	return roundCents(total)
+}
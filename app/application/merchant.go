@@ -1,40 +1,341 @@
 package application
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
+	"strings"
 	"time"
+	"unicode"
 
+	"github.com/alvarezcarlos/payment/app/config"
 	"github.com/alvarezcarlos/payment/app/domain/entity"
 	"github.com/alvarezcarlos/payment/app/domain/repository"
 	"github.com/alvarezcarlos/payment/app/utils"
+	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrWeakPassword       = errors.New("password does not meet the policy")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrMerchantLocked     = errors.New("too many failed attempts, account temporarily locked")
+	ErrTOTPRequired       = errors.New("two-factor code required")
+	ErrInvalidTOTP        = errors.New("invalid two-factor code")
+	ErrTOTPNotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrMerchantNotFound   = errors.New("merchant not found")
+	ErrMerchantSuspended  = errors.New("merchant account is suspended")
+)
+
+// dummyHash is compared against when the merchant doesn't exist so unknown names take as long as wrong passwords
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// LoginRequest credentials and client information of a login attempt
+type LoginRequest struct {
+	Name      string
+	Password  string
+	OTP       string
+	IP        string
+	UserAgent string
+}
+
 type merchantUseCase struct {
 	repository repository.MerchantRepository
+	security   config.SecurityConfig
 	logger     *slog.Logger
 }
 
-func NewMerchantUseCase(repository repository.MerchantRepository, logger *slog.Logger) MerchantUseCaseInterface {
+func NewMerchantUseCase(repository repository.MerchantRepository, security config.SecurityConfig,
+	logger *slog.Logger) MerchantUseCaseInterface {
 	return &merchantUseCase{
 		repository: repository,
+		security:   security,
 		logger:     logger}
 }
 
-// Create merchant assigning random founds
-func (m *merchantUseCase) Create(merchant *entity.Merchant) (*entity.Merchant, error) {
+// Create merchant assigning random founds, the password is checked against the policy and stored hashed
+func (m *merchantUseCase) Create(ctx context.Context, merchant *entity.Merchant) (*entity.Merchant, error) {
+	if err := m.validatePassword(merchant.Password); err != nil {
+		return nil, err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(merchant.Password), bcrypt.DefaultCost)
+	if err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error creating merchant")
+	}
+	merchant.Password = string(hashed)
 	merchant.CreatedAt, merchant.UpdatedAt = time.Now(), time.Now()
 	merchant.Balance = utils.RandomFloat()
-	merch, err := m.repository.Create(merchant)
+	merch, err := m.repository.Create(ctx, merchant)
 	if err != nil {
-		m.logger.Error(err.Error())
+		m.logger.ErrorContext(ctx, err.Error())
 		return nil, errors.New("error creating merchant")
 	}
-	m.logger.Info("merchant created", merchant)
+	m.logger.InfoContext(ctx, "merchant created", "merchant_id", merch.ID)
 	return merch, nil
 }
 
 // GetByName get merchant by name
-func (m *merchantUseCase) GetByName(name string) (*entity.Merchant, error) {
-	return m.repository.GetByName(name)
+func (m *merchantUseCase) GetByName(ctx context.Context, name string) (*entity.Merchant, error) {
+	return m.repository.GetByName(ctx, name)
+}
+
+// Login verifies the credentials and the TOTP code when enrolled. Consecutive failures lock the account
+// for a period that doubles on every new lockout, and every attempt is recorded as a login event
+func (m *merchantUseCase) Login(ctx context.Context, req LoginRequest) (*entity.Merchant, error) {
+	now := time.Now()
+	merchant, err := m.repository.GetByName(ctx, req.Name)
+	if err != nil {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(req.Password))
+		m.audit(ctx, &entity.Merchant{Name: req.Name}, entity.LoginFailed, req)
+		return nil, ErrInvalidCredentials
+	}
+
+	if merchant.IsLocked(now) {
+		m.audit(ctx, merchant, entity.LoginLocked, req)
+		return nil, ErrMerchantLocked
+	}
+
+	if err = bcrypt.CompareHashAndPassword([]byte(merchant.Password), []byte(req.Password)); err != nil {
+		m.audit(ctx, merchant, entity.LoginFailed, req)
+		return nil, m.registerFailure(ctx, merchant, now)
+	}
+
+	if merchant.TOTPEnabled {
+		if req.OTP == "" {
+			m.audit(ctx, merchant, entity.LoginTOTPRequired, req)
+			return nil, ErrTOTPRequired
+		}
+		accepted, err := m.acceptTOTP(ctx, merchant, req.OTP, now)
+		if err != nil {
+			m.logger.ErrorContext(ctx, err.Error())
+			return nil, errors.New("error logging in")
+		}
+		if !accepted {
+			m.audit(ctx, merchant, entity.LoginTOTPFailed, req)
+			if err = m.registerFailure(ctx, merchant, now); errors.Is(err, ErrMerchantLocked) {
+				return nil, err
+			}
+			return nil, ErrInvalidTOTP
+		}
+	}
+
+	if merchant.IsSuspended() {
+		m.audit(ctx, merchant, entity.LoginSuspended, req)
+		return nil, ErrMerchantSuspended
+	}
+
+	merchant.FailedLoginAttempts, merchant.LockoutCount, merchant.LockedUntil = 0, 0, nil
+	if err = m.repository.Update(ctx, merchant); err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+	}
+	m.audit(ctx, merchant, entity.LoginSucceeded, req)
+	return merchant, nil
+}
+
+// EnrollTOTP generates a new TOTP secret for the merchant, it is not enforced on login until confirmed with VerifyTOTP.
+// Once two-factor is enabled a current code is required, so a stolen token can't replace the secret or disable it
+func (m *merchantUseCase) EnrollTOTP(ctx context.Context, name, code string) (string, string, error) {
+	merchant, err := m.repository.GetByName(ctx, name)
+	if err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+		return "", "", errors.New("error enrolling two-factor authentication")
+	}
+
+	if merchant.TOTPEnabled {
+		if code == "" {
+			return "", "", ErrTOTPRequired
+		}
+		accepted, err := m.acceptTOTP(ctx, merchant, code, time.Now())
+		if err != nil {
+			m.logger.ErrorContext(ctx, err.Error())
+			return "", "", errors.New("error enrolling two-factor authentication")
+		}
+		if !accepted {
+			m.audit(ctx, merchant, entity.TOTPVerifyFailure, LoginRequest{})
+			return "", "", ErrInvalidTOTP
+		}
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+		return "", "", errors.New("error enrolling two-factor authentication")
+	}
+
+	merchant.TOTPSecret, merchant.TOTPEnabled = secret, false
+	if err = m.repository.Update(ctx, merchant); err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+		return "", "", errors.New("error enrolling two-factor authentication")
+	}
+	m.audit(ctx, merchant, entity.TOTPEnrolled, LoginRequest{})
+	return secret, utils.TOTPURL(m.security.TOTPIssuer, merchant.Name, secret), nil
+}
+
+// VerifyTOTP confirms the enrollment with a code from the authenticator and enables two-factor on login
+func (m *merchantUseCase) VerifyTOTP(ctx context.Context, name, code string) error {
+	merchant, err := m.repository.GetByName(ctx, name)
+	if err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+		return errors.New("error verifying two-factor authentication")
+	}
+
+	if merchant.TOTPSecret == "" {
+		return ErrTOTPNotEnrolled
+	}
+
+	accepted, err := m.acceptTOTP(ctx, merchant, code, time.Now())
+	if err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+		return errors.New("error verifying two-factor authentication")
+	}
+	if !accepted {
+		m.audit(ctx, merchant, entity.TOTPVerifyFailure, LoginRequest{})
+		return ErrInvalidTOTP
+	}
+
+	merchant.TOTPEnabled = true
+	if err = m.repository.Update(ctx, merchant); err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+		return errors.New("error verifying two-factor authentication")
+	}
+	m.audit(ctx, merchant, entity.TOTPEnabled, LoginRequest{})
+	return nil
+}
+
+// Suspend blocks the merchant login, the API calls of the tokens already issued and its payouts, payments
+// already created keep being processed
+func (m *merchantUseCase) Suspend(ctx context.Context, name, reason string) (*entity.Merchant, error) {
+	merchant, err := m.repository.GetByName(ctx, name)
+	if err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+		return nil, ErrMerchantNotFound
+	}
+	if merchant.IsSuspended() {
+		return merchant, nil
+	}
+
+	now := time.Now()
+	merchant.SuspendedAt, merchant.SuspensionReason, merchant.UpdatedAt = &now, reason, now
+	if err = m.repository.UpdateSuspension(ctx, merchant); err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error suspending merchant")
+	}
+	m.audit(ctx, merchant, entity.MerchantSuspended, LoginRequest{})
+	m.logger.WarnContext(ctx, "merchant suspended", "merchant_id", merchant.ID, "reason", reason)
+	return merchant, nil
+}
+
+// Reactivate lifts the suspension of the merchant
+func (m *merchantUseCase) Reactivate(ctx context.Context, name string) (*entity.Merchant, error) {
+	merchant, err := m.repository.GetByName(ctx, name)
+	if err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+		return nil, ErrMerchantNotFound
+	}
+	if !merchant.IsSuspended() {
+		return merchant, nil
+	}
+
+	merchant.SuspendedAt, merchant.SuspensionReason, merchant.UpdatedAt = nil, "", time.Now()
+	if err = m.repository.UpdateSuspension(ctx, merchant); err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error reactivating merchant")
+	}
+	m.audit(ctx, merchant, entity.MerchantReactivated, LoginRequest{})
+	m.logger.InfoContext(ctx, "merchant reactivated", "merchant_id", merchant.ID)
+	return merchant, nil
+}
+
+// acceptTOTP checks the code and consumes its time step, so a code can't be replayed while it is still valid
+func (m *merchantUseCase) acceptTOTP(ctx context.Context, merchant *entity.Merchant, code string, now time.Time) (bool, error) {
+	step, ok := utils.MatchTOTP(merchant.TOTPSecret, code, now)
+	if !ok {
+		return false, nil
+	}
+	return m.repository.UseTOTPStep(ctx, merchant.ID, step)
+}
+
+// registerFailure counts a failed attempt and locks the merchant once the limit is reached. The attempts are
+// counted by the repository, so every concurrent failure is seen, and only one of them applies the lockout
+func (m *merchantUseCase) registerFailure(ctx context.Context, merchant *entity.Merchant, now time.Time) error {
+	attempts, lockouts, err := m.repository.RegisterFailedLogin(ctx, merchant.ID)
+	if err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+		return ErrInvalidCredentials
+	}
+	if attempts < m.security.MaxFailedLogins {
+		return ErrInvalidCredentials
+	}
+	lockedUntil := now.Add(m.lockoutDuration(lockouts))
+	locked, err := m.repository.Lock(ctx, merchant.ID, m.security.MaxFailedLogins, lockedUntil)
+	if err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+	}
+	if locked {
+		m.logger.WarnContext(ctx, "merchant locked after failed logins", "merchant_id", merchant.ID, "locked_until", lockedUntil)
+	}
+	return ErrMerchantLocked
+}
+
+// lockoutDuration doubles the base lockout for every previous lockout, capped at the configured maximum
+func (m *merchantUseCase) lockoutDuration(previousLockouts int) time.Duration {
+	d := float64(m.security.LockoutDuration) * math.Pow(2, float64(previousLockouts))
+	if d > float64(m.security.MaxLockoutDuration) {
+		return m.security.MaxLockoutDuration
+	}
+	return time.Duration(d)
+}
+
+func (m *merchantUseCase) validatePassword(password string) error {
+	var problems []string
+	if len(password) < m.security.PasswordMinLength {
+		problems = append(problems, fmt.Sprintf("at least %d characters", m.security.PasswordMinLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	if m.security.PasswordRequireUpper && !upper {
+		problems = append(problems, "an uppercase letter")
+	}
+	if m.security.PasswordRequireLower && !lower {
+		problems = append(problems, "a lowercase letter")
+	}
+	if m.security.PasswordRequireDigit && !digit {
+		problems = append(problems, "a digit")
+	}
+	if m.security.PasswordRequireSymbol && !symbol {
+		problems = append(problems, "a symbol")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w, it requires %s", ErrWeakPassword, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func (m *merchantUseCase) audit(ctx context.Context, merchant *entity.Merchant, event entity.LoginEventEnum, req LoginRequest) {
+	loginEvent := &entity.LoginEvent{
+		MerchantID:   merchant.ID,
+		MerchantName: merchant.Name,
+		Event:        event,
+		IP:           req.IP,
+		UserAgent:    req.UserAgent,
+		CreatedAt:    time.Now(),
+	}
+	if err := m.repository.CreateLoginEvent(ctx, loginEvent); err != nil {
+		m.logger.ErrorContext(ctx, err.Error())
+	}
 }
@@ -0,0 +1,326 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/alvarezcarlos/payment/app/infrastructure/memory"
+	"github.com/alvarezcarlos/payment/app/utils"
+)
+
+const testPassword = "Corr3ct-Horse"
+
+// merchantFixture a merchant use case over the in-memory repository, the lockout is reached after 3 failures
+type merchantFixture struct {
+	useCase   application.MerchantUseCaseInterface
+	db        *memory.Database
+	merchants repository.MerchantRepository
+}
+
+func newMerchantFixture(t *testing.T) *merchantFixture {
+	t.Helper()
+	security := defaultConfig(t).Security
+	security.MaxFailedLogins = 3
+	db := memory.NewDatabase()
+	merchants := memory.NewMerchantRepository(db)
+	return &merchantFixture{
+		useCase:   application.NewMerchantUseCase(merchants, security, discardLogger),
+		db:        db,
+		merchants: merchants,
+	}
+}
+
+func (f *merchantFixture) create(t *testing.T, name string) *entity.Merchant {
+	t.Helper()
+	merchant, err := f.useCase.Create(context.Background(), &entity.Merchant{Name: name, Password: testPassword})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return merchant
+}
+
+func (f *merchantFixture) lastEvent(t *testing.T) entity.LoginEventEnum {
+	t.Helper()
+	events := f.db.LoginEvents()
+	if len(events) == 0 {
+		t.Fatal("no login event recorded")
+	}
+	return events[len(events)-1].Event
+}
+
+func TestMerchantCreatePasswordPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{name: "strong password", password: testPassword},
+		{name: "too short", password: "Sh0rt", wantErr: application.ErrWeakPassword},
+		{name: "no uppercase", password: "lowercase-only1", wantErr: application.ErrWeakPassword},
+		{name: "no lowercase", password: "UPPERCASE-ONLY1", wantErr: application.ErrWeakPassword},
+		{name: "no digit", password: "No-Digits-Here", wantErr: application.ErrWeakPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newMerchantFixture(t)
+			merchant, err := f.useCase.Create(context.Background(), &entity.Merchant{Name: "acme", Password: tt.password})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if merchant.ID == 0 || merchant.Password == tt.password {
+				t.Errorf("merchant not stored with a hashed password: %+v", merchant)
+			}
+		})
+	}
+
+	t.Run("duplicated name", func(t *testing.T) {
+		f := newMerchantFixture(t)
+		f.create(t, "acme")
+		if _, err := f.useCase.Create(context.Background(), &entity.Merchant{Name: "acme", Password: testPassword}); err == nil {
+			t.Fatal("expected a duplicated merchant to be rejected")
+		}
+	})
+}
+
+func TestMerchantLoginLockout(t *testing.T) {
+	tests := []struct {
+		name      string
+		passwords []string
+		wantErrs  []error
+		wantEvent entity.LoginEventEnum
+	}{
+		{
+			name:      "valid credentials",
+			passwords: []string{testPassword},
+			wantErrs:  []error{nil},
+			wantEvent: entity.LoginSucceeded,
+		},
+		{
+			name:      "wrong password",
+			passwords: []string{"Wrong-Passw0rd"},
+			wantErrs:  []error{application.ErrInvalidCredentials},
+			wantEvent: entity.LoginFailed,
+		},
+		{
+			name:      "locked after the failed attempts",
+			passwords: []string{"Wrong-1", "Wrong-2", "Wrong-3", testPassword},
+			wantErrs: []error{application.ErrInvalidCredentials, application.ErrInvalidCredentials,
+				application.ErrMerchantLocked, application.ErrMerchantLocked},
+			wantEvent: entity.LoginLocked,
+		},
+		{
+			name:      "a success resets the failures",
+			passwords: []string{"Wrong-1", "Wrong-2", testPassword, "Wrong-3"},
+			wantErrs: []error{application.ErrInvalidCredentials, application.ErrInvalidCredentials, nil,
+				application.ErrInvalidCredentials},
+			wantEvent: entity.LoginFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newMerchantFixture(t)
+			f.create(t, "acme")
+			for i, password := range tt.passwords {
+				_, err := f.useCase.Login(context.Background(), application.LoginRequest{Name: "acme", Password: password, IP: "10.0.0.1"})
+				if !errors.Is(err, tt.wantErrs[i]) {
+					t.Fatalf("attempt %d: error = %v, want %v", i+1, err, tt.wantErrs[i])
+				}
+			}
+			if event := f.lastEvent(t); event != tt.wantEvent {
+				t.Errorf("last event = %q, want %q", event, tt.wantEvent)
+			}
+		})
+	}
+
+	t.Run("unknown merchant", func(t *testing.T) {
+		f := newMerchantFixture(t)
+		_, err := f.useCase.Login(context.Background(), application.LoginRequest{Name: "nobody", Password: testPassword})
+		if !errors.Is(err, application.ErrInvalidCredentials) {
+			t.Fatalf("error = %v, want %v", err, application.ErrInvalidCredentials)
+		}
+		if event := f.lastEvent(t); event != entity.LoginFailed {
+			t.Errorf("last event = %q, want %q", event, entity.LoginFailed)
+		}
+	})
+
+	t.Run("lockout doubles", func(t *testing.T) {
+		f := newMerchantFixture(t)
+		f.create(t, "acme")
+		for lockout, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+			for i := 0; i < 3; i++ {
+				_, _ = f.useCase.Login(context.Background(), application.LoginRequest{Name: "acme", Password: "Wrong-Passw0rd"})
+			}
+			merchant, err := f.merchants.GetByName(context.Background(), "acme")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if merchant.LockedUntil == nil {
+				t.Fatalf("lockout %d: merchant not locked", lockout+1)
+			}
+			if got := time.Until(*merchant.LockedUntil); got > want || got < want-5*time.Second {
+				t.Errorf("lockout %d: locked for %s, want %s", lockout+1, got, want)
+			}
+			// the lock expires, the next failures start a new lockout
+			expired := time.Now().Add(-time.Second)
+			merchant.LockedUntil = &expired
+			if err = f.merchants.Update(context.Background(), merchant); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestMerchantSuspension(t *testing.T) {
+	tests := []struct {
+		name       string
+		suspend    bool
+		reactivate bool
+		wantErr    error
+		wantEvent  entity.LoginEventEnum
+	}{
+		{name: "active", wantEvent: entity.LoginSucceeded},
+		{name: "suspended", suspend: true, wantErr: application.ErrMerchantSuspended, wantEvent: entity.LoginSuspended},
+		{name: "reactivated", suspend: true, reactivate: true, wantEvent: entity.LoginSucceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newMerchantFixture(t)
+			f.create(t, "acme")
+			if tt.suspend {
+				merchant, err := f.useCase.Suspend(context.Background(), "acme", "chargeback ratio")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !merchant.IsSuspended() || merchant.SuspensionReason != "chargeback ratio" {
+					t.Fatalf("merchant not suspended: %+v", merchant)
+				}
+			}
+			if tt.reactivate {
+				merchant, err := f.useCase.Reactivate(context.Background(), "acme")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if merchant.IsSuspended() {
+					t.Fatal("merchant still suspended")
+				}
+			}
+
+			_, err := f.useCase.Login(context.Background(), application.LoginRequest{Name: "acme", Password: testPassword})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if event := f.lastEvent(t); event != tt.wantEvent {
+				t.Errorf("last event = %q, want %q", event, tt.wantEvent)
+			}
+		})
+	}
+
+	t.Run("unknown merchant", func(t *testing.T) {
+		f := newMerchantFixture(t)
+		if _, err := f.useCase.Suspend(context.Background(), "nobody", "fraud"); !errors.Is(err, application.ErrMerchantNotFound) {
+			t.Fatalf("error = %v, want %v", err, application.ErrMerchantNotFound)
+		}
+		if _, err := f.useCase.Reactivate(context.Background(), "nobody"); !errors.Is(err, application.ErrMerchantNotFound) {
+			t.Fatalf("error = %v, want %v", err, application.ErrMerchantNotFound)
+		}
+	})
+}
+
+func TestMerchantTOTP(t *testing.T) {
+	t.Run("verify before enrolling", func(t *testing.T) {
+		f := newMerchantFixture(t)
+		f.create(t, "acme")
+		if err := f.useCase.VerifyTOTP(context.Background(), "acme", "123456"); !errors.Is(err, application.ErrTOTPNotEnrolled) {
+			t.Fatalf("error = %v, want %v", err, application.ErrTOTPNotEnrolled)
+		}
+	})
+
+	f := newMerchantFixture(t)
+	f.create(t, "acme")
+	secret, url, err := f.useCase.EnrollTOTP(context.Background(), "acme", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == "" || url == "" {
+		t.Fatal("expected a secret and an otpauth url")
+	}
+	// the code isn't required on login until the enrollment is verified
+	if _, err = f.useCase.Login(context.Background(), application.LoginRequest{Name: "acme", Password: testPassword}); err != nil {
+		t.Fatalf("login before verifying the enrollment: %v", err)
+	}
+	if err = f.useCase.VerifyTOTP(context.Background(), "acme", "000000"); !errors.Is(err, application.ErrInvalidTOTP) {
+		t.Fatalf("verify with a wrong code: error = %v, want %v", err, application.ErrInvalidTOTP)
+	}
+	waitForFreshStep()
+	now := time.Now()
+	// the previous, current and next steps are accepted once each
+	previous, current, next := totpCode(t, secret, now.Add(-30*time.Second)), totpCode(t, secret, now),
+		totpCode(t, secret, now.Add(30*time.Second))
+	if err = f.useCase.VerifyTOTP(context.Background(), "acme", previous); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		otp       string
+		wantErr   error
+		wantEvent entity.LoginEventEnum
+	}{
+		{name: "missing code", wantErr: application.ErrTOTPRequired, wantEvent: entity.LoginTOTPRequired},
+		{name: "wrong code", otp: "000000", wantErr: application.ErrInvalidTOTP, wantEvent: entity.LoginTOTPFailed},
+		{name: "code used to verify", otp: previous, wantErr: application.ErrInvalidTOTP, wantEvent: entity.LoginTOTPFailed},
+		{name: "valid code", otp: current, wantEvent: entity.LoginSucceeded},
+		{name: "replayed code", otp: current, wantErr: application.ErrInvalidTOTP, wantEvent: entity.LoginTOTPFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.useCase.Login(context.Background(), application.LoginRequest{Name: "acme", Password: testPassword, OTP: tt.otp})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if event := f.lastEvent(t); event != tt.wantEvent {
+				t.Errorf("last event = %q, want %q", event, tt.wantEvent)
+			}
+		})
+	}
+
+	t.Run("enroll again", func(t *testing.T) {
+		if _, _, err := f.useCase.EnrollTOTP(context.Background(), "acme", ""); !errors.Is(err, application.ErrTOTPRequired) {
+			t.Fatalf("without a code: error = %v, want %v", err, application.ErrTOTPRequired)
+		}
+		if _, _, err := f.useCase.EnrollTOTP(context.Background(), "acme", current); !errors.Is(err, application.ErrInvalidTOTP) {
+			t.Fatalf("with a used code: error = %v, want %v", err, application.ErrInvalidTOTP)
+		}
+		merchant, err := f.merchants.GetByName(context.Background(), "acme")
+		if err != nil || !merchant.TOTPEnabled || merchant.TOTPSecret != secret {
+			t.Fatalf("two-factor changed by a rejected enrollment: %+v (%v)", merchant, err)
+		}
+		if _, _, err = f.useCase.EnrollTOTP(context.Background(), "acme", next); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// waitForFreshStep keeps the codes computed by a test within the TOTP time steps they were computed for
+func waitForFreshStep() {
+	if elapsed := time.Now().Unix() % 30; elapsed >= 25 {
+		time.Sleep(time.Duration(31-elapsed) * time.Second)
+	}
+}
+
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	code, err := utils.TOTPCode(secret, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
@@ -1,17 +1,26 @@
 package application
 
 import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/labstack/gommon/log"
 	"log/slog"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
+	"github.com/alvarezcarlos/payment/app/config"
 	"github.com/alvarezcarlos/payment/app/domain/entity"
 	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/alvarezcarlos/payment/app/metrics"
+	"github.com/alvarezcarlos/payment/app/tracing"
 	"github.com/alvarezcarlos/payment/app/utils"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -22,153 +31,591 @@ const (
 	errorCreatingPayment = "error creating payment"
 )
 
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var (
+	ErrPaymentNotFound     = errors.New("payment not found")
+	ErrClientSecretExpired = errors.New("client secret expired")
+	ErrInvalidCursor       = errors.New("invalid cursor")
+	ErrPaymentNotInReview  = errors.New("payment is not in review")
+	ErrNoPendingChallenge  = errors.New("payment has no pending authentication challenge")
+	ErrUnknownState        = errors.New("unknown payment state")
+	ErrSameState           = errors.New("payment is already in that state")
+	ErrCurrencyMismatch    = errors.New("the payment currency must be the currency the merchant is paid out in")
+)
+
+const declineAuthenticationFailed = "authentication_failed"
+
+// Customer contact details given by the customer on checkout, used by the fraud screening
+type Customer struct {
+	Country string
+	Email   string
+}
+
+// PaymentPage a page of a payment listing, NextCursor is empty on the last page
+type PaymentPage struct {
+	Payments   []entity.Payment `json:"payments"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	HasMore    bool             `json:"has_more"`
+}
+
 type paymentUseCase struct {
-	repository repository.PaymentRepository
-	logger     *slog.Logger
+	repository  repository.PaymentRepository
+	pricing     repository.PricingRepository
+	reserves    repository.ReserveRepository
+	reviews     repository.ReviewRepository
+	accounts    repository.BankAccountRepository
+	acquirer    repository.Acquirer
+	risk        RiskEngineInterface
+	settings    config.PaymentConfig
+	settlement  config.SettlementConfig
+	defaults    config.PricingConfig
+	fingerprint string
+	reviewTTL   time.Duration
+	logger      *slog.Logger
 }
 
-func NewPaymentUseCase(paymentRepository repository.PaymentRepository, logger *slog.Logger) PaymentUseCaseInterface {
-	return &paymentUseCase{repository: paymentRepository, logger: logger}
+func NewPaymentUseCase(paymentRepository repository.PaymentRepository, pricingRepository repository.PricingRepository,
+	reserveRepository repository.ReserveRepository, reviewRepository repository.ReviewRepository,
+	bankAccountRepository repository.BankAccountRepository, acquirer repository.Acquirer, risk RiskEngineInterface,
+	settings config.Configuration, logger *slog.Logger) PaymentUseCaseInterface {
+	return &paymentUseCase{
+		repository:  paymentRepository,
+		pricing:     pricingRepository,
+		reserves:    reserveRepository,
+		reviews:     reviewRepository,
+		accounts:    bankAccountRepository,
+		acquirer:    acquirer,
+		risk:        risk,
+		settings:    settings.Payments,
+		settlement:  settings.Settlement,
+		fingerprint: settings.Encryption.FingerprintKey,
+		reviewTTL:   settings.Reviews.Timeout,
+		defaults:    settings.Pricing,
+		logger:      logger,
+	}
 }
 
 // Create payment can only be accessed by a Merchant, that will partially populate it with fields like
 // Amount and other merchant information and the Customer should be redirected with the payment_id for processing.
-func (p *paymentUseCase) Create(payment *entity.Payment) (*entity.Payment, error) {
+// A short-lived client secret is issued so the checkout page can read the payment status. The payment must be in
+// the currency the merchant is paid out in, the balance has no room for another one
+func (p *paymentUseCase) Create(ctx context.Context, payment *entity.Payment) (*entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "paymentUseCase.Create")
+	defer span.End()
+
+	merchant, err := p.repository.GetMerchantByID(ctx, payment.MerchantID)
+	if err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New(errorCreatingPayment)
+	}
+	if merchant.IsSuspended() {
+		return nil, ErrMerchantSuspended
+	}
+
 	payment.ID = uuid.New()
-	payment.States = append(payment.States, entity.SetState(entity.Pending))
+	if payment.Currency == "" {
+		payment.Currency = p.settings.DefaultCurrency
+	}
+	payment.Currency = strings.ToUpper(payment.Currency)
+	if payment.Currency != payoutCurrency(ctx, p.accounts, merchant.ID, p.settings.DefaultCurrency) {
+		return nil, ErrCurrencyMismatch
+	}
+	payment.AddState(entity.Pending)
 	payment.CreatedAt, payment.UpdatedAt = time.Now(), time.Now()
-	if err := p.repository.Create(payment); err != nil {
-		p.logger.Error(err.Error())
+	token, err := utils.RandomToken(24)
+	if err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
 		return nil, errors.New(errorCreatingPayment)
 	}
-	p.logger.Info("payment created ", payment)
+	payment.ClientSecret = fmt.Sprintf("%s_secret_%s", payment.ID, token)
+	payment.ClientSecretExpiresAt = payment.CreatedAt.Add(p.settings.ClientSecretTTL)
+	if err = p.repository.Create(ctx, payment); err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New(errorCreatingPayment)
+	}
+	p.logger.InfoContext(ctx, "payment created", "payment_id", payment.ID)
+	return payment, nil
+}
+
+// GetByID retrieve a payment details by Id, only the merchant owning the payment can see it
+func (p *paymentUseCase) GetByID(ctx context.Context, uuid uuid.UUID, merchantId string) (*entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "paymentUseCase.GetByID")
+	defer span.End()
+
+	payment, err := p.repository.GetByID(repository.ReadOnly(ctx), uuid)
+	if err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, ErrPaymentNotFound
+	}
+	if strconv.Itoa(int(payment.MerchantID)) != merchantId {
+		p.logger.WarnContext(ctx, "merchant tried to read a payment it doesn't own", "payment_id", uuid, "merchant_id", merchantId)
+		return nil, ErrPaymentNotFound
+	}
 	return payment, nil
 }
 
-// GetByID retrieve a payment details by Id
-func (p *paymentUseCase) GetByID(uuid uuid.UUID) (*entity.Payment, error) {
-	payment, err := p.repository.GetByID(uuid)
+// GetByClientSecret retrieve a payment for the customer facing checkout, the client secret issued
+// at creation must match and not be expired
+func (p *paymentUseCase) GetByClientSecret(ctx context.Context, uuid uuid.UUID, clientSecret string) (*entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "paymentUseCase.GetByClientSecret")
+	defer span.End()
+
+	payment, err := p.repository.GetByID(repository.ReadOnly(ctx), uuid)
 	if err != nil {
-		p.logger.Error(err.Error())
-		return nil, errors.New("error fetching payment")
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, ErrPaymentNotFound
+	}
+	if payment.ClientSecret == "" || subtle.ConstantTimeCompare([]byte(payment.ClientSecret), []byte(clientSecret)) != 1 {
+		return nil, ErrPaymentNotFound
+	}
+	if time.Now().After(payment.ClientSecretExpiresAt) {
+		return nil, ErrClientSecretExpired
 	}
 	return payment, nil
 }
 
+// List returns a page of the merchant payments, the cursor is the opaque next_cursor of the previous page and is
+// only valid with the sort it was issued for
+func (p *paymentUseCase) List(ctx context.Context, filter repository.PaymentFilter, cursor string) (*PaymentPage, error) {
+	ctx, span := tracing.Start(ctx, "paymentUseCase.List")
+	defer span.End()
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	}
+	if filter.Limit > maxPageSize {
+		filter.Limit = maxPageSize
+	}
+	if filter.SortBy == "" {
+		filter.SortBy = repository.SortByCreatedAt
+	}
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil || after.SortBy != filter.SortBy || after.Descending != filter.Descending {
+			return nil, ErrInvalidCursor
+		}
+		filter.After = after
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+	payments, err := p.repository.List(repository.ReadOnly(ctx), filter)
+	if err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error listing payments")
+	}
+
+	page := &PaymentPage{Payments: payments}
+	if len(payments) > limit {
+		page.Payments, page.HasMore = payments[:limit], true
+		last := page.Payments[limit-1]
+		page.NextCursor = encodeCursor(repository.PaymentCursor{ID: last.ID, CreatedAt: last.CreatedAt, Amount: last.Amount,
+			SortBy: filter.SortBy, Descending: filter.Descending})
+	}
+	return page, nil
+}
+
+// Search finds the merchant payments matching every term by prefix in the customer name or personal id,
+// description, reference or metadata values, and containing the given metadata pairs
+func (p *paymentUseCase) Search(ctx context.Context, merchantID uint, query string, metadata map[string]string, limit int) ([]entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "paymentUseCase.Search")
+	defer span.End()
+
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	// the terms are split like the indexed text, so a reference such as ORD-1234 matches as ord and 1234
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	search := repository.PaymentSearch{MerchantID: merchantID, Terms: terms, Metadata: metadata, Limit: limit}
+
+	payments, err := p.repository.Search(repository.ReadOnly(ctx), search)
+	if err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error searching payments")
+	}
+	return payments, nil
+}
+
 // ProcessPayment the business core functionality, it allows the customer to complete the payment,
-// also stores the information of the card assigning random founds to it and perform the transaction with the bank
+// also stores the information of the card assigning random founds to it and perform the transaction with the bank.
+// The payment is screened by the risk engine first, a blocked payment is rejected without reaching the acquirer
+// and a flagged one waits InReview for a reviewer decision
 func (p *paymentUseCase) ProcessPayment(
+	ctx context.Context,
 	payment *entity.Payment,
-	card *entity.Card) (*entity.Payment, error) {
+	card *entity.Card,
+	customer Customer) (*entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "paymentUseCase.ProcessPayment", attribute.String("payment.id", payment.ID.String()))
+	defer span.End()
+
+	pay, err := p.repository.GetByID(ctx, payment.ID)
+	if err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, fmt.Errorf(errorProcessing, paymentConst)
+	}
+
+	// only pay pending or rejected operations, the ones waiting for a review or a 3DS challenge are resumed
+	// by their decision, and nothing is stored for the others
+	statesMap := statesToMap(pay.States)
+	if _, isSucceeded := statesMap[string(entity.Succeeded)]; isSucceeded || pay.CurrentState() == entity.InReview ||
+		pay.CurrentState() == entity.RequiresAction {
+		return nil, fmt.Errorf(errorInvalidState)
+	}
+
 	card.Balance = utils.RandomFloat()
-	err := p.repository.CreateCard(card)
+	if err = p.repository.CreateCard(ctx, card); err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, fmt.Errorf(errorProcessing, paymentConst)
+	}
+
+	pay.CardNumber = card.Number
+	pay.CardLast4 = card.Number[len(card.Number)-4:]
+	pay.CardBrand = utils.CardBrand(card.Number)
+	pay.CardFingerprint = utils.CardFingerprint(p.fingerprint, card.Number)
+	pay.CustomerPersonalID, pay.CustomerName = card.HolderID, card.HolderName
+	pay.CustomerCountry = strings.ToUpper(customer.Country)
+	pay.CustomerEmail = strings.ToLower(customer.Email)
+	pay.DeclineCode = ""
+
+	_, riskSpan := tracing.Start(ctx, "riskEngine.Evaluate")
+	assessment := p.risk.Evaluate(ctx, pay)
+	riskSpan.SetAttributes(attribute.String("risk.outcome", string(assessment.Outcome)))
+	riskSpan.End()
+	pay.RiskOutcome, pay.RiskRules = assessment.Outcome, assessment.Rules
+	switch assessment.Outcome {
+	case entity.RiskBlock:
+		p.logger.WarnContext(ctx, "payment blocked by risk rules", "payment_id", pay.ID, "decline_code", assessment.DeclineCode)
+		pay.AddState(entity.Rejected)
+		pay.DeclineCode = assessment.DeclineCode
+		p.risk.RecordAttempt(ctx, pay)
+	case entity.RiskReview:
+		if err = p.queueForReview(ctx, pay); err != nil {
+			return nil, fmt.Errorf(errorProcessing, paymentConst)
+		}
+	default:
+		if err = p.authenticate(ctx, pay, card); err != nil {
+			return nil, err
+		}
+	}
+
+	updatedPayment, err := p.repository.Update(ctx, pay)
 	if err != nil {
-		p.logger.Error(err.Error())
 		return nil, fmt.Errorf(errorProcessing, paymentConst)
 	}
+	metrics.PaymentOutcome(string(updatedPayment.CurrentState()), updatedPayment.DeclineCode)
+	p.logger.InfoContext(ctx, "payment processed successfully")
+	return updatedPayment, nil
+}
+
+// CompleteAuthentication finishes the 3-D Secure challenge of a payment RequiresAction with the code the
+// cardholder entered on the ACS page, an authenticated payment is captured and a failed one declined
+func (p *paymentUseCase) CompleteAuthentication(ctx context.Context, id uuid.UUID, transactionID, code string) (*entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "paymentUseCase.CompleteAuthentication", attribute.String("payment.id", id.String()))
+	defer span.End()
+
+	pay, err := p.repository.GetByID(ctx, id)
+	if err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, ErrPaymentNotFound
+	}
+	if pay.CurrentState() != entity.RequiresAction || pay.ThreeDSTransactionID == "" ||
+		subtle.ConstantTimeCompare([]byte(pay.ThreeDSTransactionID), []byte(transactionID)) != 1 {
+		return nil, ErrNoPendingChallenge
+	}
+
+	pay.ThreeDSTransactionID, pay.NextActionURL = "", ""
+	if p.acquirer.VerifyChallenge(ctx, transactionID, code) {
+		pay.ThreeDSStatus, pay.LiabilityShift = entity.ThreeDSAuthenticated, true
+		p.logger.InfoContext(ctx, "3DS challenge completed, resuming with the acquirer", "payment_id", pay.ID)
+		if err = p.sendToAcquirer(ctx, pay, paymentConst); err != nil {
+			return nil, errors.New("from acquirer " + err.Error())
+		}
+	} else {
+		p.logger.WarnContext(ctx, "3DS challenge failed", "payment_id", pay.ID)
+		pay.ThreeDSStatus = entity.ThreeDSFailed
+		pay.AddState(entity.Rejected)
+		pay.DeclineCode = declineAuthenticationFailed
+	}
+	p.risk.RecordAttempt(ctx, pay)
 
-	pay, err := p.repository.GetByID(payment.ID)
+	updatedPayment, err := p.repository.Update(ctx, pay)
 	if err != nil {
-		p.logger.Error(err.Error())
+		p.logger.ErrorContext(ctx, err.Error())
 		return nil, fmt.Errorf(errorProcessing, paymentConst)
 	}
+	metrics.PaymentOutcome(string(updatedPayment.CurrentState()), updatedPayment.DeclineCode)
+	return updatedPayment, nil
+}
 
-	pay.CardNumber = card.Number
+// Inspect retrieve any payment by id for the operators, without the merchant ownership check of GetByID
+func (p *paymentUseCase) Inspect(ctx context.Context, id uuid.UUID) (*entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "paymentUseCase.Inspect", attribute.String("payment.id", id.String()))
+	defer span.End()
 
-	//only pay pending or rejected operations
-	statesMap := statesToMap(pay.States)
-	if _, isSucceeded := statesMap[string(entity.Succeeded)]; isSucceeded {
-		return nil, fmt.Errorf(errorInvalidState)
+	payment, err := p.repository.GetByID(ctx, id)
+	if err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, ErrPaymentNotFound
+	}
+	return payment, nil
+}
+
+// ForceTransition moves a payment to the given state without going through the acquirer, to repair payments
+// left in an inconsistent state. No funds are moved, the balances must be corrected separately
+func (p *paymentUseCase) ForceTransition(ctx context.Context, id uuid.UUID, state entity.StateEnum, operator,
+	reason string) (*entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "paymentUseCase.ForceTransition", attribute.String("payment.id", id.String()))
+	defer span.End()
+
+	if entity.SetState(state).ID == 0 {
+		return nil, ErrUnknownState
+	}
+	pay, err := p.repository.GetByID(ctx, id)
+	if err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, ErrPaymentNotFound
+	}
+	previous := pay.CurrentState()
+	if previous == state {
+		return nil, ErrSameState
+	}
+
+	pay.AddState(state)
+	pay.UpdatedAt = time.Now()
+	updatedPayment, err := p.repository.Update(ctx, pay)
+	if err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error updating payment")
+	}
+	p.logger.WarnContext(ctx, "payment state forced by an operator", "payment_id", pay.ID, "from", previous,
+		"to", state, "operator", operator, "reason", reason)
+	return updatedPayment, nil
+}
+
+// authenticate runs the 3-D Secure authentication before sending the payment to the acquirer, a challenge
+// leaves the payment RequiresAction until it is completed on the ACS page
+func (p *paymentUseCase) authenticate(ctx context.Context, pay *entity.Payment, card *entity.Card) error {
+	result := p.acquirer.Authenticate(ctx, pay, card)
+	pay.ThreeDSStatus, pay.LiabilityShift = result.Status, result.Status == entity.ThreeDSAuthenticated
+	pay.ThreeDSTransactionID, pay.NextActionURL = "", ""
+
+	switch result.Status {
+	case entity.ThreeDSChallengeRequired:
+		pay.ThreeDSTransactionID = result.TransactionID
+		pay.NextActionURL = fmt.Sprintf("/api/3ds/acs/%s/%s", pay.ID, result.TransactionID)
+		pay.AddState(entity.RequiresAction)
+		p.logger.InfoContext(ctx, "payment requires 3DS challenge", "payment_id", pay.ID)
+		return nil
+	case entity.ThreeDSFailed:
+		p.logger.WarnContext(ctx, "3DS authentication failed", "payment_id", pay.ID)
+		pay.AddState(entity.Rejected)
+		pay.DeclineCode = declineAuthenticationFailed
+	default:
+		p.logger.InfoContext(ctx, "initializing payment process with the acquirer")
+		if err := p.sendToAcquirer(ctx, pay, paymentConst); err != nil {
+			return errors.New("from acquirer " + err.Error())
+		}
 	}
+	p.risk.RecordAttempt(ctx, pay)
+	return nil
+}
+
+// ResumeAfterReview continues the processing of a payment waiting InReview, an approved payment is authenticated
+// and sent to the acquirer and a rejected one is declined with the given code
+func (p *paymentUseCase) ResumeAfterReview(ctx context.Context, id uuid.UUID, approved bool, declineCode string) (*entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "paymentUseCase.ResumeAfterReview", attribute.String("payment.id", id.String()))
+	defer span.End()
 
-	p.logger.Info("initializing payment process with mock Bank")
-	err = p.BankSimulator(pay, paymentConst)
+	pay, err := p.repository.GetByID(ctx, id)
 	if err != nil {
-		return nil, errors.New("from bank" + err.Error())
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, ErrPaymentNotFound
+	}
+	if pay.CurrentState() != entity.InReview {
+		return nil, ErrPaymentNotInReview
+	}
+
+	if approved {
+		p.logger.InfoContext(ctx, "reviewed payment approved, resuming processing", "payment_id", pay.ID)
+		card, err := p.repository.GetCardByNumber(ctx, pay.CardNumber)
+		if err != nil {
+			p.logger.ErrorContext(ctx, err.Error())
+			return nil, fmt.Errorf(errorProcessing, paymentConst)
+		}
+		if err = p.authenticate(ctx, pay, card); err != nil {
+			return nil, err
+		}
+	} else {
+		pay.AddState(entity.Rejected)
+		pay.DeclineCode = declineCode
+		p.risk.RecordAttempt(ctx, pay)
 	}
 
-	updatedPayment, err := p.repository.Update(pay)
+	updatedPayment, err := p.repository.Update(ctx, pay)
 	if err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
 		return nil, fmt.Errorf(errorProcessing, paymentConst)
 	}
-	p.logger.Info("payment processed successfully")
+	metrics.PaymentOutcome(string(updatedPayment.CurrentState()), updatedPayment.DeclineCode)
 	return updatedPayment, nil
 }
 
+// queueForReview holds the payment InReview until a reviewer decides on it
+func (p *paymentUseCase) queueForReview(ctx context.Context, pay *entity.Payment) error {
+	now := time.Now()
+	review := &entity.Review{
+		ID:         uuid.New(),
+		PaymentID:  pay.ID,
+		MerchantID: pay.MerchantID,
+		Amount:     pay.Amount,
+		Rules:      pay.RiskRules,
+		Status:     entity.ReviewPending,
+		DueAt:      now.Add(p.reviewTTL),
+		CreatedAt:  now,
+	}
+	if err := p.reviews.Create(ctx, review); err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
+		return err
+	}
+	pay.AddState(entity.InReview)
+	p.logger.InfoContext(ctx, "payment queued for manual review", "payment_id", pay.ID, "review_id", review.ID)
+	return nil
+}
+
 // ProcessRefund payment can only be accessed by a Merchant, to execute the devolution for client money
-func (p *paymentUseCase) ProcessRefund(uuid uuid.UUID, merchantId string) error {
-	pay, err := p.repository.GetByID(uuid)
+func (p *paymentUseCase) ProcessRefund(ctx context.Context, uuid uuid.UUID, merchantId string) error {
+	ctx, span := tracing.Start(ctx, "paymentUseCase.ProcessRefund", attribute.String("payment.id", uuid.String()))
+	defer span.End()
+
+	pay, err := p.repository.GetByID(ctx, uuid)
 	if err != nil {
-		p.logger.Error(err.Error())
+		p.logger.ErrorContext(ctx, err.Error())
 		return fmt.Errorf(errorProcessing, refundConst)
 	}
 
 	if strconv.Itoa(int(pay.MerchantID)) != merchantId {
-		log.Error("merchants don't match")
+		p.logger.WarnContext(ctx, "merchant tried to refund a payment it doesn't own", "payment_id", uuid, "merchant_id", merchantId)
 		return fmt.Errorf(errorProcessing, refundConst)
 	}
 
-	p.logger.Info("payment to be refunded", pay)
+	p.logger.InfoContext(ctx, "payment to be refunded", "payment_id", pay.ID)
 
 	//only refund a successful operation
 	statesMap := statesToMap(pay.States)
 	_, isRefunded := statesMap[string(entity.Refunded)]
 	_, isSucceeded := statesMap[string(entity.Succeeded)]
 
-	if isRefunded || !isSucceeded {
+	if isRefunded || !isSucceeded || pay.CurrentState() == entity.Disputed {
 		return errors.New(fmt.Sprintf("%s ", errorInvalidState))
 	}
 
-	err = p.BankSimulator(pay, refundConst)
+	err = p.sendToAcquirer(ctx, pay, refundConst)
 	if err != nil {
-		p.logger.Error(err.Error())
+		p.logger.ErrorContext(ctx, err.Error())
 		return fmt.Errorf(errorProcessing, refundConst)
 	}
 
-	_, err = p.repository.Update(pay)
+	_, err = p.repository.Update(ctx, pay)
 	if err != nil {
 		return fmt.Errorf(errorProcessing, refundConst)
 	}
+	metrics.PaymentOutcome(string(entity.Refunded), "")
 
 	return nil
 }
 
-// BankSimulator simulates the communication with the Bank
-// It fails if it isn't enough found for an operation
-func (p *paymentUseCase) BankSimulator(payment *entity.Payment, op string) error {
-	card, err := p.repository.GetCardByNumber(payment.CardNumber)
+// sendToAcquirer performs the operation with the acquirer and moves the funds of the merchant.
+// It fails if it isn't enough found for an operation. The merchant is credited the payment amount net of the
+// fees of its pricing plan minus its rolling reserve, and refunds debit the amount plus the refund fees
+func (p *paymentUseCase) sendToAcquirer(ctx context.Context, payment *entity.Payment, op string) error {
+	card, err := p.repository.GetCardByNumber(ctx, payment.CardNumber)
 	if err != nil {
 		return err
 	}
 
-	merch, err := p.repository.GetMerchantByID(payment.MerchantID)
+	merch, err := p.repository.GetMerchantByID(ctx, payment.MerchantID)
 	if err != nil {
 		return err
 	}
 
+	plan := planFor(ctx, p.pricing, p.defaults, merch.ID)
+	now := time.Now()
+	transaction := entity.BalanceTransaction{
+		MerchantID: merch.ID,
+		PaymentID:  &payment.ID,
+		CreatedAt:  now,
+	}
+	feeTransaction := transaction
+	feeTransaction.Type = entity.TransactionFee
+
+	var delta float64
 	if op == paymentConst {
-		card.Balance = card.Balance - payment.Amount
-		merch.Balance = merch.Balance + payment.Amount
-		if card.Balance < 0 {
-			p.logger.Error("insufficient founds in card balance")
-			payment.States = append(payment.States, entity.SetState(entity.Rejected))
+		fee := processingFee(plan, payment)
+		if resp := p.acquirer.Capture(ctx, payment, card); !resp.Approved {
+			p.logger.ErrorContext(ctx, "payment declined by the acquirer", "payment_id", payment.ID, "decline_code", resp.DeclineCode)
+			payment.AddState(entity.Rejected)
+			payment.DeclineCode = resp.DeclineCode
 			return nil
 		}
-		payment.States = append(payment.States, entity.SetState(entity.Succeeded))
+		delta = payment.Amount - fee.Amount
+		payment.AddState(entity.Succeeded)
+		payment.FeeLines = append(payment.FeeLines, fee)
+		payment.FeeAmount = fee.Amount
+		payment.NetAmount = roundCents(payment.Amount - fee.Amount)
+		transaction.Type, transaction.Amount = entity.TransactionPayment, payment.Amount
+		transaction.AvailableOn = availableOn(now, p.settlement.DelayDays)
+		feeTransaction.Amount, feeTransaction.AvailableOn = -fee.Amount, transaction.AvailableOn
 	} else {
-		card.Balance = card.Balance + payment.Amount
-		merch.Balance = merch.Balance - payment.Amount
-		if merch.Balance < 0 {
-			p.logger.Error("insufficient founds in merchant balance")
+		fees := refundFees(plan, payment)
+		feeDelta := 0.0
+		for _, fee := range fees {
+			feeDelta += fee.Amount
+		}
+		delta = -payment.Amount - feeDelta
+		if merch.Balance+delta < 0 {
+			p.logger.ErrorContext(ctx, "insufficient founds in merchant balance")
+			return fmt.Errorf(errorProcessing, refundConst)
+		}
+		if resp := p.acquirer.Refund(ctx, payment, card); !resp.Approved {
+			p.logger.ErrorContext(ctx, "refund declined by the acquirer", "payment_id", payment.ID, "decline_code", resp.DeclineCode)
 			return fmt.Errorf(errorProcessing, refundConst)
 		}
-		payment.States = append(payment.States, entity.SetState(entity.Refunded))
+		payment.AddState(entity.Refunded)
+		payment.FeeLines = append(payment.FeeLines, fees...)
+		payment.FeeAmount = roundCents(payment.FeeAmount + feeDelta)
+		payment.NetAmount = roundCents(payment.NetAmount - payment.Amount - feeDelta)
+		transaction.Type, transaction.Amount = entity.TransactionRefund, -payment.Amount
+		transaction.AvailableOn = now
+		feeTransaction.Amount, feeTransaction.AvailableOn = -roundCents(feeDelta), now
+	}
+
+	transactions := []entity.BalanceTransaction{transaction}
+	if feeTransaction.Amount != 0 {
+		transactions = append(transactions, feeTransaction)
+	}
+	if op == paymentConst {
+		if policy, err := p.reserves.GetPolicy(ctx, merch.ID); err == nil {
+			transactions = append(transactions, reserveTransactions(policy, transaction)...)
+		}
+	}
+	err = p.repository.UpdateCardAndBalance(ctx, card, merch.ID, delta, transactions...)
+	if errors.Is(err, repository.ErrInsufficientFunds) {
+		p.logger.ErrorContext(ctx, "insufficient founds in merchant balance")
+		return fmt.Errorf(errorProcessing, refundConst)
 	}
-	p.logger.Info("processing operation in mock Bank")
-	err = p.repository.UpdateCardAndMerchant(card, merch)
 	if err != nil {
-		p.logger.Error(err.Error())
-		return errors.New("error from mock bank api")
+		p.logger.ErrorContext(ctx, err.Error())
+		return errors.New("error from acquirer api")
 	}
 
 	return nil
@@ -181,3 +628,20 @@ func statesToMap(states []entity.State) map[string]uint {
 	}
 	return statesMap
 }
+
+func encodeCursor(cursor repository.PaymentCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(cursor string) (*repository.PaymentCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var after repository.PaymentCursor
+	if err = json.Unmarshal(raw, &after); err != nil {
+		return nil, err
+	}
+	return &after, nil
+}
@@ -0,0 +1,709 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/config"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/alvarezcarlos/payment/app/infrastructure/memory"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const testCardNumber = "4242424242424242"
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+type acquirerStub struct {
+	capture   repository.AcquirerResponse
+	refund    repository.AcquirerResponse
+	threeDS   repository.ThreeDSResult
+	challenge bool
+	captures  int
+}
+
+func (a *acquirerStub) Capture(context.Context, *entity.Payment, *entity.Card) repository.AcquirerResponse {
+	a.captures++
+	return a.capture
+}
+
+func (a *acquirerStub) Refund(context.Context, *entity.Payment, *entity.Card) repository.AcquirerResponse {
+	return a.refund
+}
+
+func (a *acquirerStub) Authenticate(context.Context, *entity.Payment, *entity.Card) repository.ThreeDSResult {
+	return a.threeDS
+}
+
+func (a *acquirerStub) VerifyChallenge(context.Context, string, string) bool {
+	return a.challenge
+}
+
+func (a *acquirerStub) Disputes() []repository.DisputeNotice {
+	return nil
+}
+
+func (a *acquirerStub) AckDispute(uuid.UUID) {}
+
+type riskStub struct {
+	assessment application.RiskAssessment
+	attempts   int
+}
+
+func (r *riskStub) Evaluate(context.Context, *entity.Payment) application.RiskAssessment {
+	return r.assessment
+}
+
+func (r *riskStub) RecordAttempt(context.Context, *entity.Payment) {
+	r.attempts++
+}
+
+func (r *riskStub) PruneVelocity(context.Context, time.Time) error {
+	return nil
+}
+
+// pricingStub no merchant has a plan of its own, the configured default applies
+type pricingStub struct{}
+
+func (pricingStub) GetByMerchant(context.Context, uint) (*entity.PricingPlan, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (pricingStub) Save(context.Context, *entity.PricingPlan) error {
+	return nil
+}
+
+// reserveStub no merchant has a reserve policy
+type reserveStub struct{}
+
+func (reserveStub) GetPolicy(context.Context, uint) (*entity.ReservePolicy, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (reserveStub) SavePolicy(context.Context, *entity.ReservePolicy) error {
+	return nil
+}
+
+func (reserveStub) CreateHold(context.Context, *entity.BalanceHold, entity.BalanceTransaction) error {
+	return nil
+}
+
+func (reserveStub) GetHold(context.Context, uuid.UUID) (*entity.BalanceHold, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (reserveStub) ReleaseHold(context.Context, *entity.BalanceHold, entity.BalanceTransaction) error {
+	return nil
+}
+
+func (reserveStub) ListHolds(context.Context, uint) ([]entity.BalanceHold, error) {
+	return nil, nil
+}
+
+type reviewStub struct {
+	created []entity.Review
+}
+
+func (r *reviewStub) Create(_ context.Context, review *entity.Review) error {
+	r.created = append(r.created, *review)
+	return nil
+}
+
+func (r *reviewStub) GetByID(context.Context, uuid.UUID) (*entity.Review, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *reviewStub) List(context.Context, entity.ReviewStatusEnum, int) ([]entity.Review, error) {
+	return r.created, nil
+}
+
+func (r *reviewStub) Decide(context.Context, *entity.Review) error {
+	return nil
+}
+
+func (r *reviewStub) Update(context.Context, *entity.Review) error {
+	return nil
+}
+
+func (r *reviewStub) Overdue(context.Context, time.Time) ([]entity.Review, error) {
+	return nil, nil
+}
+
+// paymentFixture a payment use case over the in-memory repositories with a merchant already created
+type paymentFixture struct {
+	useCase  application.PaymentUseCaseInterface
+	db       *memory.Database
+	payments repository.PaymentRepository
+	merchant *entity.Merchant
+	acquirer *acquirerStub
+	risk     *riskStub
+	reviews  *reviewStub
+}
+
+// defaultConfig the configuration with the defaults of every setting
+func defaultConfig(t *testing.T) config.Configuration {
+	t.Helper()
+	cfg, err := config.Defaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func newPaymentFixture(t *testing.T, balance float64) *paymentFixture {
+	t.Helper()
+	db := memory.NewDatabase()
+	merchant, err := memory.NewMerchantRepository(db).Create(context.Background(), &entity.Merchant{Name: "acme", Balance: balance})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &paymentFixture{
+		db:       db,
+		payments: memory.NewPaymentRepository(db),
+		merchant: merchant,
+		acquirer: &acquirerStub{capture: repository.AcquirerResponse{Approved: true}, refund: repository.AcquirerResponse{Approved: true}},
+		risk:     &riskStub{assessment: application.RiskAssessment{Outcome: entity.RiskAllow}},
+		reviews:  &reviewStub{},
+	}
+	f.useCase = application.NewPaymentUseCase(f.payments, pricingStub{}, reserveStub{}, f.reviews,
+		memory.NewBankAccountRepository(db), f.acquirer, f.risk, defaultConfig(t), discardLogger)
+	return f
+}
+
+func (f *paymentFixture) create(t *testing.T, payment entity.Payment) *entity.Payment {
+	t.Helper()
+	payment.MerchantID = f.merchant.ID
+	created, err := f.useCase.Create(context.Background(), &payment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created
+}
+
+func (f *paymentFixture) process(t *testing.T, id uuid.UUID) (*entity.Payment, error) {
+	t.Helper()
+	card := &entity.Card{HolderID: 30111222, HolderName: "Jane Doe", Number: testCardNumber, Code: "123", Month: 12, Year: 2030}
+	return f.useCase.ProcessPayment(context.Background(), &entity.Payment{ID: id}, card, application.Customer{Country: "ar", Email: "Jane@Example.com"})
+}
+
+func (f *paymentFixture) balance(t *testing.T) float64 {
+	t.Helper()
+	merchant, err := f.payments.GetMerchantByID(context.Background(), f.merchant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return merchant.Balance
+}
+
+func TestPaymentCreate(t *testing.T) {
+	tests := []struct {
+		name           string
+		currency       string
+		payoutCurrency string
+		suspended      bool
+		merchantID     uint
+		wantErr        error
+		wantCurrency   string
+	}{
+		{name: "defaults the currency", wantCurrency: "USD"},
+		{name: "normalizes the currency", currency: "eur", payoutCurrency: "EUR", wantCurrency: "EUR"},
+		{name: "currency of the payout account", currency: "USD", payoutCurrency: "EUR",
+			wantErr: application.ErrCurrencyMismatch},
+		{name: "other than the default currency without a payout account", currency: "EUR",
+			wantErr: application.ErrCurrencyMismatch},
+		{name: "suspended merchant", suspended: true, wantErr: application.ErrMerchantSuspended},
+		{name: "unknown merchant", merchantID: 999, wantErr: errors.New("error creating payment")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPaymentFixture(t, 0)
+			if tt.suspended {
+				now := time.Now()
+				f.merchant.SuspendedAt = &now
+				if err := memory.NewMerchantRepository(f.db).UpdateSuspension(context.Background(), f.merchant); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.payoutCurrency != "" {
+				account := &entity.BankAccount{ID: uuid.New(), MerchantID: f.merchant.ID, Currency: tt.payoutCurrency,
+					Status: entity.BankAccountVerified, IsDefault: true}
+				if err := memory.NewBankAccountRepository(f.db).Create(context.Background(), account); err != nil {
+					t.Fatal(err)
+				}
+			}
+			merchantID := f.merchant.ID
+			if tt.merchantID != 0 {
+				merchantID = tt.merchantID
+			}
+
+			payment, err := f.useCase.Create(context.Background(), &entity.Payment{MerchantID: merchantID, Amount: 100, Currency: tt.currency})
+			if tt.wantErr != nil {
+				if err == nil || err.Error() != tt.wantErr.Error() {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if payment.Currency != tt.wantCurrency {
+				t.Errorf("currency = %q, want %q", payment.Currency, tt.wantCurrency)
+			}
+			if payment.CurrentState() != entity.Pending {
+				t.Errorf("state = %q, want %q", payment.CurrentState(), entity.Pending)
+			}
+			if !strings.HasPrefix(payment.ClientSecret, payment.ID.String()+"_secret_") {
+				t.Errorf("unexpected client secret %q", payment.ClientSecret)
+			}
+			if _, err = f.payments.GetByID(context.Background(), payment.ID); err != nil {
+				t.Errorf("payment not stored: %v", err)
+			}
+		})
+	}
+}
+
+func TestPaymentProcess(t *testing.T) {
+	tests := []struct {
+		name             string
+		amount           float64
+		risk             application.RiskAssessment
+		capture          repository.AcquirerResponse
+		threeDS          repository.ThreeDSResult
+		wantState        entity.StateEnum
+		wantDeclineCode  string
+		wantBalance      float64
+		wantTransactions int
+		wantCaptures     int
+		wantReviews      int
+	}{
+		{
+			name:             "captured",
+			capture:          repository.AcquirerResponse{Approved: true},
+			wantState:        entity.Succeeded,
+			wantBalance:      96.8,
+			wantTransactions: 2,
+			wantCaptures:     1,
+		},
+		{
+			name:             "fee capped at an amount under the fixed fee",
+			amount:           0.2,
+			capture:          repository.AcquirerResponse{Approved: true},
+			wantState:        entity.Succeeded,
+			wantTransactions: 2,
+			wantCaptures:     1,
+		},
+		{
+			name:            "declined by the acquirer",
+			capture:         repository.AcquirerResponse{DeclineCode: "insufficient_funds"},
+			wantState:       entity.Rejected,
+			wantDeclineCode: "insufficient_funds",
+			wantCaptures:    1,
+		},
+		{
+			name:            "blocked by the risk rules",
+			risk:            application.RiskAssessment{Outcome: entity.RiskBlock, Rules: []string{"blocklist"}, DeclineCode: "fraudulent"},
+			wantState:       entity.Rejected,
+			wantDeclineCode: "fraudulent",
+		},
+		{
+			name:        "flagged for review",
+			risk:        application.RiskAssessment{Outcome: entity.RiskReview, Rules: []string{"amount"}},
+			wantState:   entity.InReview,
+			wantReviews: 1,
+		},
+		{
+			name:      "3DS challenge",
+			threeDS:   repository.ThreeDSResult{Status: entity.ThreeDSChallengeRequired, TransactionID: "tx-1"},
+			wantState: entity.RequiresAction,
+		},
+		{
+			name:            "3DS failed",
+			threeDS:         repository.ThreeDSResult{Status: entity.ThreeDSFailed},
+			wantState:       entity.Rejected,
+			wantDeclineCode: "authentication_failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPaymentFixture(t, 0)
+			if tt.risk.Outcome != "" {
+				f.risk.assessment = tt.risk
+			}
+			f.acquirer.capture, f.acquirer.threeDS = tt.capture, tt.threeDS
+			if tt.amount == 0 {
+				tt.amount = 100
+			}
+			created := f.create(t, entity.Payment{Amount: tt.amount})
+
+			payment, err := f.process(t, created.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if payment.CurrentState() != tt.wantState {
+				t.Errorf("state = %q, want %q", payment.CurrentState(), tt.wantState)
+			}
+			if payment.DeclineCode != tt.wantDeclineCode {
+				t.Errorf("decline code = %q, want %q", payment.DeclineCode, tt.wantDeclineCode)
+			}
+			if payment.NetAmount < 0 || payment.FeeAmount > payment.Amount {
+				t.Errorf("fee %v over the amount %v, net %v", payment.FeeAmount, payment.Amount, payment.NetAmount)
+			}
+			if got := f.balance(t); !sameAmount(got, tt.wantBalance) {
+				t.Errorf("merchant balance = %v, want %v", got, tt.wantBalance)
+			}
+			if got := len(f.db.BalanceTransactions()); got != tt.wantTransactions {
+				t.Errorf("balance transactions = %d, want %d", got, tt.wantTransactions)
+			}
+			if f.acquirer.captures != tt.wantCaptures {
+				t.Errorf("captures = %d, want %d", f.acquirer.captures, tt.wantCaptures)
+			}
+			if len(f.reviews.created) != tt.wantReviews {
+				t.Errorf("reviews = %d, want %d", len(f.reviews.created), tt.wantReviews)
+			}
+			if payment.CardLast4 != "4242" || payment.CustomerCountry != "AR" || payment.CustomerEmail != "jane@example.com" {
+				t.Errorf("card and customer not recorded: %+v", payment)
+			}
+			if tt.wantState == entity.RequiresAction && payment.NextActionURL == "" {
+				t.Error("expected a next action url")
+			}
+		})
+	}
+}
+
+func TestPaymentProcessTwice(t *testing.T) {
+	tests := []struct {
+		name        string
+		risk        application.RiskAssessment
+		threeDS     repository.ThreeDSResult
+		wantState   entity.StateEnum
+		wantBalance float64
+	}{
+		{name: "succeeded", wantState: entity.Succeeded, wantBalance: 96.8},
+		{name: "in review", risk: application.RiskAssessment{Outcome: entity.RiskReview}, wantState: entity.InReview},
+		{name: "waiting for the 3DS challenge", wantState: entity.RequiresAction,
+			threeDS: repository.ThreeDSResult{Status: entity.ThreeDSChallengeRequired, TransactionID: "tx-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPaymentFixture(t, 0)
+			if tt.risk.Outcome != "" {
+				f.risk.assessment = tt.risk
+			}
+			f.acquirer.threeDS = tt.threeDS
+			created := f.create(t, entity.Payment{Amount: 100})
+			if _, err := f.process(t, created.ID); err != nil {
+				t.Fatal(err)
+			}
+			card, err := f.payments.GetCardByNumber(context.Background(), testCardNumber)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = f.process(t, created.ID); err == nil {
+				t.Fatal("expected the payment not to be processed again")
+			}
+			payment, err := f.payments.GetByID(context.Background(), created.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if payment.CurrentState() != tt.wantState {
+				t.Errorf("state = %q, want %q", payment.CurrentState(), tt.wantState)
+			}
+			if stored, err := f.payments.GetCardByNumber(context.Background(), testCardNumber); err != nil || stored.Balance != card.Balance {
+				t.Errorf("card stored again: %+v (%v)", stored, err)
+			}
+			if got := f.balance(t); !sameAmount(got, tt.wantBalance) {
+				t.Errorf("merchant balance = %v, want %v", got, tt.wantBalance)
+			}
+		})
+	}
+}
+
+func TestPaymentRefund(t *testing.T) {
+	tests := []struct {
+		name        string
+		otherOwner  bool
+		refundFirst bool
+		declined    bool
+		wantErr     bool
+		wantState   entity.StateEnum
+		wantBalance float64
+	}{
+		{name: "refunds a succeeded payment", wantState: entity.Refunded, wantBalance: 96.8},
+		{name: "another merchant", otherOwner: true, wantErr: true, wantState: entity.Succeeded, wantBalance: 196.8},
+		{name: "already refunded", refundFirst: true, wantErr: true, wantState: entity.Refunded, wantBalance: 96.8},
+		{name: "declined by the acquirer", declined: true, wantErr: true, wantState: entity.Succeeded, wantBalance: 196.8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPaymentFixture(t, 100)
+			created := f.create(t, entity.Payment{Amount: 100})
+			if _, err := f.process(t, created.ID); err != nil {
+				t.Fatal(err)
+			}
+			merchantID := f.merchant.ID
+			if tt.otherOwner {
+				merchantID++
+			}
+			if tt.refundFirst {
+				if err := f.useCase.ProcessRefund(context.Background(), created.ID, strconv.Itoa(int(merchantID))); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.declined {
+				f.acquirer.refund = repository.AcquirerResponse{DeclineCode: "do_not_honor"}
+			}
+
+			err := f.useCase.ProcessRefund(context.Background(), created.ID, strconv.Itoa(int(merchantID)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			payment, err := f.payments.GetByID(context.Background(), created.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if payment.CurrentState() != tt.wantState {
+				t.Errorf("state = %q, want %q", payment.CurrentState(), tt.wantState)
+			}
+			if got := f.balance(t); !sameAmount(got, tt.wantBalance) {
+				t.Errorf("merchant balance = %v, want %v", got, tt.wantBalance)
+			}
+		})
+	}
+}
+
+func TestPaymentGetByID(t *testing.T) {
+	f := newPaymentFixture(t, 0)
+	created := f.create(t, entity.Payment{Amount: 10})
+
+	tests := []struct {
+		name       string
+		id         uuid.UUID
+		merchantID string
+		wantErr    error
+	}{
+		{name: "owner", id: created.ID, merchantID: strconv.Itoa(int(f.merchant.ID))},
+		{name: "another merchant", id: created.ID, merchantID: strconv.Itoa(int(f.merchant.ID + 1)), wantErr: application.ErrPaymentNotFound},
+		{name: "unknown payment", id: uuid.New(), merchantID: strconv.Itoa(int(f.merchant.ID)), wantErr: application.ErrPaymentNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment, err := f.useCase.GetByID(context.Background(), tt.id, tt.merchantID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && payment.ID != created.ID {
+				t.Errorf("got payment %s, want %s", payment.ID, created.ID)
+			}
+		})
+	}
+}
+
+func TestPaymentGetByClientSecret(t *testing.T) {
+	f := newPaymentFixture(t, 0)
+	active := f.create(t, entity.Payment{Amount: 10})
+	expired := f.create(t, entity.Payment{Amount: 10})
+	stored, err := f.payments.GetByID(context.Background(), expired.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.ClientSecretExpiresAt = time.Now().Add(-time.Minute)
+	if _, err = f.payments.Update(context.Background(), stored); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		id      uuid.UUID
+		secret  string
+		wantErr error
+	}{
+		{name: "valid secret", id: active.ID, secret: active.ClientSecret},
+		{name: "wrong secret", id: active.ID, secret: expired.ClientSecret, wantErr: application.ErrPaymentNotFound},
+		{name: "expired secret", id: expired.ID, secret: expired.ClientSecret, wantErr: application.ErrClientSecretExpired},
+		{name: "unknown payment", id: uuid.New(), secret: active.ClientSecret, wantErr: application.ErrPaymentNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.useCase.GetByClientSecret(context.Background(), tt.id, tt.secret)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPaymentList(t *testing.T) {
+	f := newPaymentFixture(t, 0)
+	for _, amount := range []float64{30, 10, 50, 20, 40} {
+		f.create(t, entity.Payment{Amount: amount})
+	}
+	minAmount := 25.0
+
+	tests := []struct {
+		name      string
+		filter    repository.PaymentFilter
+		wantPages [][]float64
+	}{
+		{
+			name:      "by amount ascending",
+			filter:    repository.PaymentFilter{SortBy: repository.SortByAmount, Limit: 2},
+			wantPages: [][]float64{{10, 20}, {30, 40}, {50}},
+		},
+		{
+			name:      "by amount descending",
+			filter:    repository.PaymentFilter{SortBy: repository.SortByAmount, Descending: true, Limit: 3},
+			wantPages: [][]float64{{50, 40, 30}, {20, 10}},
+		},
+		{
+			name:      "by creation",
+			filter:    repository.PaymentFilter{Limit: 5},
+			wantPages: [][]float64{{30, 10, 50, 20, 40}},
+		},
+		{
+			name:      "minimum amount",
+			filter:    repository.PaymentFilter{SortBy: repository.SortByAmount, MinAmount: &minAmount, Limit: 10},
+			wantPages: [][]float64{{30, 40, 50}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			filter.MerchantID = f.merchant.ID
+			cursor := ""
+			for i, want := range tt.wantPages {
+				page, err := f.useCase.List(context.Background(), filter, cursor)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []float64
+				for _, payment := range page.Payments {
+					got = append(got, payment.Amount)
+				}
+				if !equalAmounts(got, want) {
+					t.Fatalf("page %d = %v, want %v", i, got, want)
+				}
+				if last := i == len(tt.wantPages)-1; page.HasMore == last {
+					t.Fatalf("page %d has more = %v", i, page.HasMore)
+				}
+				cursor = page.NextCursor
+			}
+		})
+	}
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		_, err := f.useCase.List(context.Background(), repository.PaymentFilter{MerchantID: f.merchant.ID}, "not a cursor")
+		if !errors.Is(err, application.ErrInvalidCursor) {
+			t.Fatalf("error = %v, want %v", err, application.ErrInvalidCursor)
+		}
+	})
+
+	t.Run("cursor of another sort", func(t *testing.T) {
+		byAmount := repository.PaymentFilter{MerchantID: f.merchant.ID, SortBy: repository.SortByAmount, Limit: 2}
+		page, err := f.useCase.List(context.Background(), byAmount, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		descending := byAmount
+		descending.Descending = true
+		byCreation := byAmount
+		byCreation.SortBy = ""
+		for _, filter := range []repository.PaymentFilter{descending, byCreation} {
+			if _, err = f.useCase.List(context.Background(), filter, page.NextCursor); !errors.Is(err, application.ErrInvalidCursor) {
+				t.Errorf("sort %q descending %v: error = %v, want %v", filter.SortBy, filter.Descending, err,
+					application.ErrInvalidCursor)
+			}
+		}
+	})
+}
+
+func TestPaymentSearch(t *testing.T) {
+	f := newPaymentFixture(t, 0)
+	f.create(t, entity.Payment{Amount: 10, Description: "Coffee beans", Reference: "INV-001", Metadata: entity.Metadata{"order": "A1"}})
+	f.create(t, entity.Payment{Amount: 20, Description: "Green tea", Reference: "INV-002", Metadata: entity.Metadata{"order": "B2", "channel": "web"}})
+
+	tests := []struct {
+		name     string
+		query    string
+		metadata map[string]string
+		want     []float64
+	}{
+		{name: "word", query: "coffee", want: []float64{10}},
+		{name: "prefix", query: "Cof", want: []float64{10}},
+		{name: "every term", query: "green inv", want: []float64{20}},
+		{name: "hyphenated reference", query: "INV-002", want: []float64{20}},
+		{name: "hyphenated prefix", query: "inv-00", want: []float64{20, 10}},
+		{name: "metadata value", query: "web", want: []float64{20}},
+		{name: "metadata pair", metadata: map[string]string{"order": "A1"}, want: []float64{10}},
+		{name: "no match", query: "milk"},
+		{name: "everything newest first", want: []float64{20, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payments, err := f.useCase.Search(context.Background(), f.merchant.ID, tt.query, tt.metadata, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []float64
+			for _, payment := range payments {
+				got = append(got, payment.Amount)
+			}
+			if !equalAmounts(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaymentForceTransition(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   entity.StateEnum
+		wantErr error
+	}{
+		{name: "known state", state: entity.Succeeded},
+		{name: "unknown state", state: "Lost", wantErr: application.ErrUnknownState},
+		{name: "same state", state: entity.Pending, wantErr: application.ErrSameState},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPaymentFixture(t, 0)
+			created := f.create(t, entity.Payment{Amount: 10})
+
+			payment, err := f.useCase.ForceTransition(context.Background(), created.ID, tt.state, "ops", "stuck")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && payment.CurrentState() != tt.state {
+				t.Errorf("state = %q, want %q", payment.CurrentState(), tt.state)
+			}
+		})
+	}
+}
+
+func equalAmounts(got, want []float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameAmount compares money amounts to the cent, the balances are computed in floating point
+func sameAmount(got, want float64) bool {
+	return math.Abs(got-want) < 0.005
+}
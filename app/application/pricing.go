@@ -0,0 +1,136 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/config"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+)
+
+var ErrInvalidPricingPlan = errors.New("invalid pricing plan")
+
+type pricingUseCase struct {
+	repository repository.PricingRepository
+	defaults   config.PricingConfig
+	logger     *slog.Logger
+}
+
+func NewPricingUseCase(repository repository.PricingRepository, defaults config.PricingConfig,
+	logger *slog.Logger) PricingUseCaseInterface {
+	return &pricingUseCase{repository: repository, defaults: defaults, logger: logger}
+}
+
+// GetPlan the plan applied to the merchant, the platform default when it has none of its own
+func (p *pricingUseCase) GetPlan(ctx context.Context, merchantID uint) (*entity.PricingPlan, error) {
+	return planFor(ctx, p.repository, p.defaults, merchantID), nil
+}
+
+// SetPlan creates or replaces the pricing plan of a merchant
+func (p *pricingUseCase) SetPlan(ctx context.Context, plan *entity.PricingPlan) (*entity.PricingPlan, error) {
+	if plan.PercentFee < 0 || plan.PercentFee >= 100 || plan.FixedFee < 0 || plan.RefundFixedFee < 0 {
+		return nil, ErrInvalidPricingPlan
+	}
+	if plan.RefundPolicy != entity.RefundFeeRetain && plan.RefundPolicy != entity.RefundFeeReturn {
+		return nil, ErrInvalidPricingPlan
+	}
+	for i, rule := range plan.Rules {
+		if rule.PercentFee < 0 || rule.PercentFee >= 100 || rule.FixedFee < 0 {
+			return nil, ErrInvalidPricingPlan
+		}
+		plan.Rules[i].CardBrand = strings.ToLower(rule.CardBrand)
+		plan.Rules[i].Currency = strings.ToUpper(rule.Currency)
+	}
+
+	plan.CreatedAt, plan.UpdatedAt = time.Now(), time.Now()
+	if err := p.repository.Save(ctx, plan); err != nil {
+		p.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error saving pricing plan")
+	}
+	p.logger.InfoContext(ctx, "pricing plan saved", "merchant_id", plan.MerchantID, "plan_id", plan.ID)
+	return plan, nil
+}
+
+// planFor loads the merchant plan falling back to the configured default one
+func planFor(ctx context.Context, repository repository.PricingRepository, defaults config.PricingConfig, merchantID uint) *entity.PricingPlan {
+	plan, err := repository.GetByMerchant(ctx, merchantID)
+	if err == nil {
+		return plan
+	}
+	return &entity.PricingPlan{
+		MerchantID:     merchantID,
+		Name:           "default",
+		PercentFee:     defaults.PercentFee,
+		FixedFee:       defaults.FixedFee,
+		RefundPolicy:   entity.RefundFeePolicyEnum(defaults.RefundPolicy),
+		RefundFixedFee: defaults.RefundFixedFee,
+	}
+}
+
+// processingFee computes the fee of a payment, the most specific rule matching the card brand and currency
+// wins over the plan base fees. The fee is capped at the payment amount so a fixed fee never makes the net negative
+func processingFee(plan *entity.PricingPlan, payment *entity.Payment) entity.FeeLine {
+	percent, fixed, description := plan.PercentFee, plan.FixedFee, "processing fee"
+	bestScore := 0
+	for _, rule := range plan.Rules {
+		if (rule.CardBrand != "" && rule.CardBrand != payment.CardBrand) ||
+			(rule.Currency != "" && rule.Currency != payment.Currency) {
+			continue
+		}
+		score := 1
+		if rule.CardBrand != "" {
+			score += 2
+		}
+		if rule.Currency != "" {
+			score++
+		}
+		if score > bestScore {
+			bestScore = score
+			percent, fixed = rule.PercentFee, rule.FixedFee
+			description = strings.TrimSpace(fmt.Sprintf("processing fee %s %s", rule.CardBrand, rule.Currency))
+		}
+	}
+
+	return entity.FeeLine{
+		PaymentID:   payment.ID,
+		Type:        entity.FeeProcessing,
+		Description: fmt.Sprintf("%s (%.2f%% + %.2f)", description, percent, fixed),
+		Amount:      math.Min(roundCents(payment.Amount*percent/100+fixed), payment.Amount),
+		CreatedAt:   time.Now(),
+	}
+}
+
+// refundFees the fee lines of refunding a payment: the processing fee given back when the plan returns it
+// and the fixed refund fee, capped at the payment amount
+func refundFees(plan *entity.PricingPlan, payment *entity.Payment) []entity.FeeLine {
+	var lines []entity.FeeLine
+	if plan.RefundPolicy == entity.RefundFeeReturn && payment.FeeAmount > 0 {
+		lines = append(lines, entity.FeeLine{
+			PaymentID:   payment.ID,
+			Type:        entity.FeeRefund,
+			Description: "processing fee returned",
+			Amount:      -payment.FeeAmount,
+			CreatedAt:   time.Now(),
+		})
+	}
+	if plan.RefundFixedFee > 0 {
+		lines = append(lines, entity.FeeLine{
+			PaymentID:   payment.ID,
+			Type:        entity.FeeRefund,
+			Description: "refund fee",
+			Amount:      math.Min(roundCents(plan.RefundFixedFee), payment.Amount),
+			CreatedAt:   time.Now(),
+		})
+	}
+	return lines
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
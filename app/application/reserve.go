@@ -0,0 +1,125 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidReservePolicy = errors.New("invalid reserve policy")
+	ErrHoldNotFound         = errors.New("hold not found or already released")
+)
+
+type reserveUseCase struct {
+	repository repository.ReserveRepository
+	logger     *slog.Logger
+}
+
+func NewReserveUseCase(repository repository.ReserveRepository, logger *slog.Logger) ReserveUseCaseInterface {
+	return &reserveUseCase{repository: repository, logger: logger}
+}
+
+// GetPolicy the rolling reserve of the merchant, an empty policy when it has none
+func (r *reserveUseCase) GetPolicy(ctx context.Context, merchantID uint) (*entity.ReservePolicy, error) {
+	policy, err := r.repository.GetPolicy(ctx, merchantID)
+	if err != nil {
+		return &entity.ReservePolicy{MerchantID: merchantID}, nil
+	}
+	return policy, nil
+}
+
+// SetPolicy configures the rolling reserve applied to the next captured payments of the merchant
+func (r *reserveUseCase) SetPolicy(ctx context.Context, policy *entity.ReservePolicy) (*entity.ReservePolicy, error) {
+	if policy.Percent < 0 || policy.Percent > 100 || policy.Days < 0 {
+		return nil, ErrInvalidReservePolicy
+	}
+	policy.UpdatedAt = time.Now()
+	if err := r.repository.SavePolicy(ctx, policy); err != nil {
+		r.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error saving reserve policy")
+	}
+	r.logger.InfoContext(ctx, "reserve policy saved", "merchant_id", policy.MerchantID, "percent", policy.Percent, "days", policy.Days)
+	return policy, nil
+}
+
+// PlaceHold holds funds of the merchant out of its available balance until released
+func (r *reserveUseCase) PlaceHold(ctx context.Context, merchantID uint, amount float64, reason string) (*entity.BalanceHold, error) {
+	now := time.Now()
+	hold := &entity.BalanceHold{
+		ID:         uuid.New(),
+		MerchantID: merchantID,
+		Amount:     roundCents(amount),
+		Reason:     reason,
+		CreatedAt:  now,
+	}
+	transaction := entity.BalanceTransaction{
+		MerchantID:  merchantID,
+		Type:        entity.TransactionHold,
+		Amount:      -hold.Amount,
+		AvailableOn: now,
+		CreatedAt:   now,
+	}
+	if err := r.repository.CreateHold(ctx, hold, transaction); err != nil {
+		r.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error placing hold")
+	}
+	r.logger.InfoContext(ctx, "balance hold placed", "merchant_id", merchantID, "hold_id", hold.ID, "amount", hold.Amount)
+	return hold, nil
+}
+
+// ReleaseHold gives the held funds back to the merchant available balance
+func (r *reserveUseCase) ReleaseHold(ctx context.Context, id uuid.UUID) (*entity.BalanceHold, error) {
+	hold, err := r.repository.GetHold(ctx, id)
+	if err != nil || hold.ReleasedAt != nil {
+		return nil, ErrHoldNotFound
+	}
+
+	now := time.Now()
+	hold.ReleasedAt = &now
+	transaction := entity.BalanceTransaction{
+		MerchantID:  hold.MerchantID,
+		Type:        entity.TransactionHoldRelease,
+		Amount:      hold.Amount,
+		AvailableOn: now,
+		CreatedAt:   now,
+	}
+	if err = r.repository.ReleaseHold(ctx, hold, transaction); err != nil {
+		r.logger.ErrorContext(ctx, err.Error())
+		return nil, ErrHoldNotFound
+	}
+	r.logger.InfoContext(ctx, "balance hold released", "merchant_id", hold.MerchantID, "hold_id", hold.ID)
+	return hold, nil
+}
+
+// ListHolds the holds of the merchant, released ones included
+func (r *reserveUseCase) ListHolds(ctx context.Context, merchantID uint) ([]entity.BalanceHold, error) {
+	holds, err := r.repository.ListHolds(ctx, merchantID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error fetching holds")
+	}
+	return holds, nil
+}
+
+// reserveTransactions the ledger lines holding the rolling reserve of a captured payment, it leaves the balance
+// together with the payment funds and comes back policy.Days later
+func reserveTransactions(policy *entity.ReservePolicy, payment entity.BalanceTransaction) []entity.BalanceTransaction {
+	if policy == nil || policy.Percent <= 0 {
+		return nil
+	}
+	amount := roundCents(payment.Amount * policy.Percent / 100)
+	if amount <= 0 {
+		return nil
+	}
+	hold, release := payment, payment
+	hold.Type, hold.Amount = entity.TransactionReserveHold, -amount
+	release.Type, release.Amount = entity.TransactionReserveRelease, amount
+	release.AvailableOn = payment.AvailableOn.AddDate(0, 0, policy.Days)
+	return []entity.BalanceTransaction{hold, release}
+}
@@ -0,0 +1,108 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+)
+
+// Decline codes of the payments rejected on review
+const (
+	declineReviewRejected = "review_rejected"
+	declineReviewExpired  = "review_expired"
+)
+
+var ErrReviewNotPending = errors.New("review not found or already decided")
+
+type reviewUseCase struct {
+	reviews  repository.ReviewRepository
+	payments PaymentUseCaseInterface
+	logger   *slog.Logger
+}
+
+func NewReviewUseCase(reviews repository.ReviewRepository, payments PaymentUseCaseInterface,
+	logger *slog.Logger) ReviewUseCaseInterface {
+	return &reviewUseCase{reviews: reviews, payments: payments, logger: logger}
+}
+
+// List the review queue, optionally filtered by status
+func (r *reviewUseCase) List(ctx context.Context, status entity.ReviewStatusEnum, limit int) ([]entity.Review, error) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	reviews, err := r.reviews.List(ctx, status, limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error fetching reviews")
+	}
+	return reviews, nil
+}
+
+// Approve sends the reviewed payment to the acquirer, it can still be declined there
+func (r *reviewUseCase) Approve(ctx context.Context, id uuid.UUID, reviewer, note string) (*entity.Review, *entity.Payment, error) {
+	return r.decide(ctx, id, entity.ReviewApproved, reviewer, note, "")
+}
+
+// Reject declines the reviewed payment
+func (r *reviewUseCase) Reject(ctx context.Context, id uuid.UUID, reviewer, note string) (*entity.Review, *entity.Payment, error) {
+	return r.decide(ctx, id, entity.ReviewRejected, reviewer, note, declineReviewRejected)
+}
+
+// ExpireOverdue declines the payments no reviewer decided on in time
+func (r *reviewUseCase) ExpireOverdue(ctx context.Context, now time.Time) error {
+	overdue, err := r.reviews.Overdue(ctx, now)
+	if err != nil {
+		return err
+	}
+	var failed int
+	for _, review := range overdue {
+		if _, _, err = r.decide(ctx, review.ID, entity.ReviewExpired, "", "review timed out", declineReviewExpired); err != nil {
+			r.logger.ErrorContext(ctx, err.Error(), "review_id", review.ID)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d reviews failed to expire", failed)
+	}
+	return nil
+}
+
+// decide claims the pending review with the decision and resumes the payment processing, the review is put
+// back in the queue when the payment can't be resumed
+func (r *reviewUseCase) decide(ctx context.Context, id uuid.UUID, status entity.ReviewStatusEnum, reviewer, note,
+	declineCode string) (*entity.Review, *entity.Payment, error) {
+	review, err := r.reviews.GetByID(ctx, id)
+	if err != nil || review.Status != entity.ReviewPending {
+		return nil, nil, ErrReviewNotPending
+	}
+
+	now := time.Now()
+	review.Status, review.Reviewer, review.Note, review.DecidedAt = status, reviewer, note, &now
+	if err = r.reviews.Decide(ctx, review); err != nil {
+		return nil, nil, ErrReviewNotPending
+	}
+
+	payment, err := r.payments.ResumeAfterReview(ctx, review.PaymentID, status == entity.ReviewApproved, declineCode)
+	if err != nil && !errors.Is(err, ErrPaymentNotInReview) {
+		review.Status, review.Reviewer, review.Note, review.DecidedAt = entity.ReviewPending, "", "", nil
+		if updateErr := r.reviews.Update(ctx, review); updateErr != nil {
+			r.logger.ErrorContext(ctx, updateErr.Error(), "review_id", review.ID)
+		}
+		return nil, nil, err
+	}
+	if err != nil {
+		r.logger.WarnContext(ctx, "review decided on a payment no longer in review", "review_id", review.ID, "payment_id", review.PaymentID)
+	}
+	r.logger.InfoContext(ctx, "payment review decided", "review_id", review.ID, "payment_id", review.PaymentID,
+		"status", review.Status, "reviewer", review.Reviewer)
+	return review, payment, nil
+}
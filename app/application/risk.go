@@ -0,0 +1,237 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/config"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/alvarezcarlos/payment/app/utils"
+)
+
+// Risk rules, a blocking rule is also the decline code of the payment
+const (
+	RuleAmountReview      = "amount_over_review_threshold"
+	RuleAmountBlock       = "amount_over_block_threshold"
+	RuleCountryMismatch   = "card_country_mismatch"
+	RuleBlockedCard       = "blocked_card"
+	RuleBlockedCustomer   = "blocked_customer"
+	RuleRepeatedFailures  = "repeated_failures"
+	RuleCardVelocity      = "card_velocity_exceeded"
+	RulePaymentAttempts   = "payment_attempts_exceeded"
+	RuleMerchantVolume    = "merchant_volume_exceeded"
+	RuleMerchantBlocklist = "merchant_blocklist"
+	RuleMerchantAllowlist = "merchant_allowlist"
+)
+
+const (
+	cardAttemptsWindow   = time.Hour
+	merchantVolumeWindow = 24 * time.Hour
+)
+
+// RiskAssessment result of the fraud screening of a payment, Outcome is the most severe of the triggered rules
+type RiskAssessment struct {
+	Outcome     entity.RiskOutcomeEnum
+	Rules       []string
+	DeclineCode string
+}
+
+func (a *RiskAssessment) trigger(rule string, outcome entity.RiskOutcomeEnum) {
+	a.Rules = append(a.Rules, rule)
+	if outcome == entity.RiskBlock && a.Outcome != entity.RiskBlock {
+		a.Outcome, a.DeclineCode = entity.RiskBlock, rule
+	}
+	if outcome == entity.RiskReview && a.Outcome == entity.RiskAllow {
+		a.Outcome = entity.RiskReview
+	}
+}
+
+type riskEngine struct {
+	repository         repository.RiskRepository
+	velocity           repository.VelocityStore
+	lists              repository.RiskListRepository
+	settings           config.RiskConfig
+	blockedCards       map[string]bool
+	blockedPersonalIDs map[uint]bool
+	logger             *slog.Logger
+}
+
+func NewRiskEngine(repository repository.RiskRepository, velocity repository.VelocityStore,
+	lists repository.RiskListRepository, settings config.RiskConfig, logger *slog.Logger) RiskEngineInterface {
+	r := &riskEngine{
+		repository:         repository,
+		velocity:           velocity,
+		lists:              lists,
+		settings:           settings,
+		blockedCards:       map[string]bool{},
+		blockedPersonalIDs: map[uint]bool{},
+		logger:             logger,
+	}
+	for _, fingerprint := range settings.BlockedCards {
+		r.blockedCards[fingerprint] = true
+	}
+	for _, id := range settings.BlockedPersonalIDs {
+		r.blockedPersonalIDs[id] = true
+	}
+	return r
+}
+
+// Evaluate screens the payment against the configured rules, it expects the card and customer fields set
+func (r *riskEngine) Evaluate(ctx context.Context, payment *entity.Payment) RiskAssessment {
+	assessment := RiskAssessment{Outcome: entity.RiskAllow}
+
+	if r.settings.BlockAmount > 0 && payment.Amount >= r.settings.BlockAmount {
+		assessment.trigger(RuleAmountBlock, entity.RiskBlock)
+	} else if r.settings.ReviewAmount > 0 && payment.Amount >= r.settings.ReviewAmount {
+		assessment.trigger(RuleAmountReview, entity.RiskReview)
+	}
+
+	cardCountry := utils.CardCountry(payment.CardNumber)
+	if payment.CustomerCountry != "" && cardCountry != "" && cardCountry != payment.CustomerCountry {
+		assessment.trigger(RuleCountryMismatch, entity.RiskOutcomeEnum(r.settings.CountryMismatchAction))
+	}
+
+	if r.blockedCards[payment.CardFingerprint] {
+		assessment.trigger(RuleBlockedCard, entity.RiskBlock)
+	}
+	if r.blockedPersonalIDs[payment.CustomerPersonalID] {
+		assessment.trigger(RuleBlockedCustomer, entity.RiskBlock)
+	}
+
+	if r.settings.MaxFailedAttempts > 0 {
+		failed, err := r.repository.CountFailedAttempts(ctx, payment.CardFingerprint,
+			time.Now().Add(-r.settings.FailedAttemptsWindow))
+		if err != nil {
+			r.logger.ErrorContext(ctx, err.Error())
+		} else if failed >= int64(r.settings.MaxFailedAttempts) {
+			assessment.trigger(RuleRepeatedFailures, entity.RiskBlock)
+		}
+	}
+
+	r.evaluateVelocity(ctx, payment, &assessment)
+	r.evaluateMerchantLists(ctx, payment, &assessment)
+
+	if assessment.Outcome != entity.RiskAllow {
+		r.logger.WarnContext(ctx, "payment flagged by risk rules", "payment_id", payment.ID,
+			"outcome", assessment.Outcome, "rules", assessment.Rules)
+	}
+	return assessment
+}
+
+// RecordAttempt stores the result of a processing attempt of the payment
+func (r *riskEngine) RecordAttempt(ctx context.Context, payment *entity.Payment) {
+	attempt := &entity.PaymentAttempt{
+		PaymentID:          payment.ID,
+		MerchantID:         payment.MerchantID,
+		CardFingerprint:    payment.CardFingerprint,
+		CustomerPersonalID: payment.CustomerPersonalID,
+		Amount:             payment.Amount,
+		Status:             payment.CurrentState(),
+		DeclineCode:        payment.DeclineCode,
+		RiskOutcome:        payment.RiskOutcome,
+		CreatedAt:          time.Now(),
+	}
+	if err := r.repository.CreateAttempt(ctx, attempt); err != nil {
+		r.logger.ErrorContext(ctx, err.Error())
+	}
+
+	limits := r.settings.Velocity
+	r.count(ctx, cardAttemptsKey(payment), 1, attempt.CreatedAt, cardAttemptsWindow)
+	switch attempt.Status {
+	case entity.Rejected:
+		r.count(ctx, paymentDeclinesKey(payment), 1, attempt.CreatedAt, limits.PaymentDeclinesWindow)
+	case entity.Succeeded:
+		r.count(ctx, merchantVolumeKey(payment), payment.Amount, attempt.CreatedAt, merchantVolumeWindow)
+	}
+}
+
+// PruneVelocity drops the velocity counters no window uses anymore
+func (r *riskEngine) PruneVelocity(ctx context.Context, now time.Time) error {
+	return r.velocity.Prune(ctx, now)
+}
+
+// evaluateVelocity blocks the payment when a sliding window limit is reached, the counters are not
+// enforced when the store is unavailable
+func (r *riskEngine) evaluateVelocity(ctx context.Context, payment *entity.Payment, assessment *RiskAssessment) {
+	limits, now := r.settings.Velocity, time.Now()
+
+	if limits.CardAttemptsPerHour > 0 {
+		if attempts, ok := r.sum(ctx, cardAttemptsKey(payment), now.Add(-cardAttemptsWindow)); ok &&
+			attempts >= float64(limits.CardAttemptsPerHour) {
+			assessment.trigger(RuleCardVelocity, entity.RiskBlock)
+		}
+	}
+	if limits.PaymentDeclines > 0 {
+		if declines, ok := r.sum(ctx, paymentDeclinesKey(payment), now.Add(-limits.PaymentDeclinesWindow)); ok &&
+			declines >= float64(limits.PaymentDeclines) {
+			assessment.trigger(RulePaymentAttempts, entity.RiskBlock)
+		}
+	}
+	if limits.MerchantDailyVolume > 0 {
+		if volume, ok := r.sum(ctx, merchantVolumeKey(payment), now.Add(-merchantVolumeWindow)); ok &&
+			volume+payment.Amount > limits.MerchantDailyVolume {
+			assessment.trigger(RuleMerchantVolume, entity.RiskBlock)
+		}
+	}
+}
+
+// evaluateMerchantLists blocks the payments matching a merchant block entry, a match on its allow list
+// skips the manual review but never lifts a block
+func (r *riskEngine) evaluateMerchantLists(ctx context.Context, payment *entity.Payment, assessment *RiskAssessment) {
+	entries, err := r.lists.ListByMerchant(ctx, payment.MerchantID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, err.Error())
+		return
+	}
+
+	var blocked, allowed bool
+	for _, entry := range entries {
+		if !matchesListEntry(entry, payment) {
+			continue
+		}
+		if entry.List == entity.RiskListBlock {
+			blocked = true
+		} else {
+			allowed = true
+		}
+	}
+
+	if blocked {
+		assessment.trigger(RuleMerchantBlocklist, entity.RiskBlock)
+	} else if allowed {
+		assessment.Rules = append(assessment.Rules, RuleMerchantAllowlist)
+		if assessment.Outcome == entity.RiskReview {
+			assessment.Outcome = entity.RiskAllow
+		}
+	}
+}
+
+func (r *riskEngine) sum(ctx context.Context, key string, since time.Time) (float64, bool) {
+	sum, err := r.velocity.Sum(ctx, key, since)
+	if err != nil {
+		r.logger.ErrorContext(ctx, err.Error(), "velocity_key", key)
+		return 0, false
+	}
+	return sum, true
+}
+
+func (r *riskEngine) count(ctx context.Context, key string, value float64, at time.Time, ttl time.Duration) {
+	if err := r.velocity.Add(ctx, key, value, at, ttl); err != nil {
+		r.logger.ErrorContext(ctx, err.Error(), "velocity_key", key)
+	}
+}
+
+func cardAttemptsKey(payment *entity.Payment) string {
+	return fmt.Sprintf("card:%s:attempts", payment.CardFingerprint)
+}
+
+func paymentDeclinesKey(payment *entity.Payment) string {
+	return fmt.Sprintf("payment:%s:declines", payment.ID)
+}
+
+func merchantVolumeKey(payment *entity.Payment) string {
+	return fmt.Sprintf("merchant:%d:volume", payment.MerchantID)
+}
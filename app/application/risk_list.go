@@ -0,0 +1,150 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/alvarezcarlos/payment/app/utils"
+	"github.com/google/uuid"
+)
+
+const maxRiskListEntries = 1000
+
+var (
+	ErrInvalidListEntry  = errors.New("invalid list entry")
+	ErrListEntryExists   = errors.New("the entry is already listed")
+	ErrListEntryNotFound = errors.New("list entry not found")
+	ErrRiskListFull      = errors.New("the maximum number of list entries was reached")
+)
+
+var (
+	fingerprintPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	cardNumberPattern  = regexp.MustCompile(`^[0-9]{12,19}$`)
+	binRangePattern    = regexp.MustCompile(`^([0-9]{6})(?:-([0-9]{6}))?$`)
+	domainPattern      = regexp.MustCompile(`^([a-z0-9-]+\.)+[a-z]{2,}$`)
+)
+
+type riskListUseCase struct {
+	repository  repository.RiskListRepository
+	fingerprint string
+	logger      *slog.Logger
+}
+
+func NewRiskListUseCase(repository repository.RiskListRepository, fingerprintKey string,
+	logger *slog.Logger) RiskListUseCaseInterface {
+	return &riskListUseCase{
+		repository:  repository,
+		fingerprint: fingerprintKey,
+		logger:      logger,
+	}
+}
+
+// Create lists a value for the merchant, a card can be given by number and only its fingerprint is stored
+func (r *riskListUseCase) Create(ctx context.Context, entry *entity.RiskListEntry, cardNumber string) (*entity.RiskListEntry, error) {
+	if entry.Type == entity.EntryCardFingerprint && cardNumber != "" {
+		if !cardNumberPattern.MatchString(cardNumber) {
+			return nil, ErrInvalidListEntry
+		}
+		entry.Value = utils.CardFingerprint(r.fingerprint, cardNumber)
+	}
+	value, ok := normalizeListValue(entry.Type, entry.Value)
+	if !ok || (entry.List != entity.RiskListAllow && entry.List != entity.RiskListBlock) {
+		return nil, ErrInvalidListEntry
+	}
+	entry.Value = value
+
+	count, err := r.repository.CountByMerchant(ctx, entry.MerchantID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error creating list entry")
+	}
+	if count >= maxRiskListEntries {
+		return nil, ErrRiskListFull
+	}
+
+	entry.ID, entry.CreatedAt = uuid.New(), time.Now()
+	if err = r.repository.Create(ctx, entry); err != nil {
+		if errors.Is(err, repository.ErrDuplicated) {
+			return nil, ErrListEntryExists
+		}
+		r.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error creating list entry")
+	}
+	r.logger.InfoContext(ctx, "risk list entry created", "merchant_id", entry.MerchantID, "list", entry.List, "type", entry.Type)
+	return entry, nil
+}
+
+// List the allow and block entries of the merchant
+func (r *riskListUseCase) List(ctx context.Context, merchantID uint) ([]entity.RiskListEntry, error) {
+	entries, err := r.repository.ListByMerchant(ctx, merchantID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error fetching list entries")
+	}
+	return entries, nil
+}
+
+// Delete removes an entry of the merchant lists
+func (r *riskListUseCase) Delete(ctx context.Context, id uuid.UUID, merchantID uint) error {
+	if err := r.repository.Delete(ctx, id, merchantID); err != nil {
+		return ErrListEntryNotFound
+	}
+	r.logger.InfoContext(ctx, "risk list entry deleted", "merchant_id", merchantID, "entry_id", id)
+	return nil
+}
+
+// normalizeListValue validates the value for the entry type and returns it in the form it is matched
+func normalizeListValue(entryType entity.RiskListEntryTypeEnum, value string) (string, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch entryType {
+	case entity.EntryCardFingerprint:
+		return value, fingerprintPattern.MatchString(value)
+	case entity.EntryPersonalID:
+		id, err := strconv.ParseUint(value, 10, 64)
+		return strconv.FormatUint(id, 10), err == nil && id > 0
+	case entity.EntryEmailDomain:
+		value = strings.TrimPrefix(value, "@")
+		return value, domainPattern.MatchString(value)
+	case entity.EntryBINRange:
+		match := binRangePattern.FindStringSubmatch(value)
+		if match == nil {
+			return "", false
+		}
+		if match[2] == "" {
+			return match[1], true
+		}
+		return value, match[1] <= match[2]
+	}
+	return "", false
+}
+
+// matchesListEntry reports whether the payment card or customer is the listed value
+func matchesListEntry(entry entity.RiskListEntry, payment *entity.Payment) bool {
+	switch entry.Type {
+	case entity.EntryCardFingerprint:
+		return payment.CardFingerprint != "" && entry.Value == payment.CardFingerprint
+	case entity.EntryPersonalID:
+		return entry.Value == strconv.FormatUint(uint64(payment.CustomerPersonalID), 10)
+	case entity.EntryEmailDomain:
+		at := strings.LastIndex(payment.CustomerEmail, "@")
+		return at >= 0 && entry.Value == strings.ToLower(payment.CustomerEmail[at+1:])
+	case entity.EntryBINRange:
+		if len(payment.CardNumber) < 6 {
+			return false
+		}
+		bin := payment.CardNumber[:6]
+		from, to, found := strings.Cut(entry.Value, "-")
+		if !found {
+			return bin == from
+		}
+		return bin >= from && bin <= to
+	}
+	return false
+}
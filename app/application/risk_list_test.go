@@ -0,0 +1,74 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/utils"
+	"github.com/google/uuid"
+)
+
+const testFingerprintKey = "test-fingerprint-key"
+
+// riskListStub stores nothing, the merchant lists are always empty
+type riskListStub struct{}
+
+func (riskListStub) Create(context.Context, *entity.RiskListEntry) error {
+	return nil
+}
+
+func (riskListStub) Delete(context.Context, uuid.UUID, uint) error {
+	return nil
+}
+
+func (riskListStub) ListByMerchant(context.Context, uint) ([]entity.RiskListEntry, error) {
+	return nil, nil
+}
+
+func (riskListStub) CountByMerchant(context.Context, uint) (int64, error) {
+	return 0, nil
+}
+
+func TestRiskListCreate(t *testing.T) {
+	fingerprint := utils.CardFingerprint(testFingerprintKey, testCardNumber)
+	tests := []struct {
+		name       string
+		entryType  entity.RiskListEntryTypeEnum
+		value      string
+		cardNumber string
+		want       string
+		wantErr    error
+	}{
+		{name: "fingerprint", entryType: entity.EntryCardFingerprint, value: " " + strings.ToUpper(fingerprint), want: fingerprint},
+		{name: "card number", entryType: entity.EntryCardFingerprint, cardNumber: testCardNumber, want: fingerprint},
+		{name: "malformed fingerprint", entryType: entity.EntryCardFingerprint, value: "abc", wantErr: application.ErrInvalidListEntry},
+		{name: "malformed card number", entryType: entity.EntryCardFingerprint, cardNumber: "4242-4242", wantErr: application.ErrInvalidListEntry},
+		{name: "personal id", entryType: entity.EntryPersonalID, value: "030111222", want: "30111222"},
+		{name: "zero personal id", entryType: entity.EntryPersonalID, value: "0", wantErr: application.ErrInvalidListEntry},
+		{name: "personal id not a number", entryType: entity.EntryPersonalID, value: "30-111-222", wantErr: application.ErrInvalidListEntry},
+		{name: "email domain", entryType: entity.EntryEmailDomain, value: "@Example.COM", want: "example.com"},
+		{name: "email domain without tld", entryType: entity.EntryEmailDomain, value: "localhost", wantErr: application.ErrInvalidListEntry},
+		{name: "bin", entryType: entity.EntryBINRange, value: "424242", want: "424242"},
+		{name: "bin range", entryType: entity.EntryBINRange, value: "400000-400099", want: "400000-400099"},
+		{name: "reversed bin range", entryType: entity.EntryBINRange, value: "400099-400000", wantErr: application.ErrInvalidListEntry},
+		{name: "short bin", entryType: entity.EntryBINRange, value: "4242", wantErr: application.ErrInvalidListEntry},
+		{name: "unknown type", entryType: "country", value: "AR", wantErr: application.ErrInvalidListEntry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lists := application.NewRiskListUseCase(riskListStub{}, testFingerprintKey, discardLogger)
+			entry, err := lists.Create(context.Background(), &entity.RiskListEntry{MerchantID: 1, List: entity.RiskListBlock, Type: tt.entryType,
+				Value: tt.value}, tt.cardNumber)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && entry.Value != tt.want {
+				t.Errorf("value = %q, want %q", entry.Value, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+)
+
+const maxPayoutsListed = 100
+
+type settlementUseCase struct {
+	repository   repository.SettlementRepository
+	bankAccounts repository.BankAccountRepository
+	logger       *slog.Logger
+}
+
+func NewSettlementUseCase(repository repository.SettlementRepository, bankAccounts repository.BankAccountRepository,
+	logger *slog.Logger) SettlementUseCaseInterface {
+	return &settlementUseCase{repository: repository, bankAccounts: bankAccounts, logger: logger}
+}
+
+// Run creates the payout batch of the day of asOf for every merchant with available funds, paid to its default
+// verified bank account. Merchants already paid out that day or without a bank account are skipped so the run
+// can be repeated, and negative totals are carried to the next batch
+func (s *settlementUseCase) Run(ctx context.Context, asOf time.Time) ([]entity.Payout, error) {
+	batchDate := startOfDay(asOf)
+	merchants, err := s.repository.MerchantsWithAvailableFunds(ctx, asOf)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error running settlement")
+	}
+
+	var payouts []entity.Payout
+	for _, merchantID := range merchants {
+		payout, err := s.settle(ctx, merchantID, batchDate, asOf)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "error settling merchant", "merchant_id", merchantID, "error", err.Error())
+			continue
+		}
+		if payout != nil {
+			payouts = append(payouts, *payout)
+		}
+	}
+	s.logger.InfoContext(ctx, "settlement finished", "batch_date", batchDate.Format(time.DateOnly), "payouts", len(payouts))
+	return payouts, nil
+}
+
+// ListPayouts the latest payouts of the merchant
+func (s *settlementUseCase) ListPayouts(ctx context.Context, merchantID uint) ([]entity.Payout, error) {
+	payouts, err := s.repository.ListPayouts(ctx, merchantID, maxPayoutsListed)
+	if err != nil {
+		s.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error fetching payouts")
+	}
+	return payouts, nil
+}
+
+// GetBalance the merchant funds split in available for the next payout and pending of the settlement delay
+func (s *settlementUseCase) GetBalance(ctx context.Context, merchantID uint) (*entity.MerchantBalance, error) {
+	balance, err := s.repository.Balance(ctx, merchantID, time.Now())
+	if err != nil {
+		s.logger.ErrorContext(ctx, err.Error())
+		return nil, errors.New("error fetching balance")
+	}
+	return balance, nil
+}
+
+func (s *settlementUseCase) settle(ctx context.Context, merchantID uint, batchDate, asOf time.Time) (*entity.Payout, error) {
+	exists, err := s.repository.PayoutExists(ctx, merchantID, batchDate)
+	if err != nil || exists {
+		return nil, err
+	}
+
+	account, err := s.bankAccounts.GetDefault(ctx, merchantID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "merchant without a verified default bank account, payout skipped", "merchant_id", merchantID)
+		return nil, nil
+	}
+
+	transactions, err := s.repository.AvailableTransactions(ctx, merchantID, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	payout := &entity.Payout{
+		ID:               uuid.New(),
+		MerchantID:       merchantID,
+		BankAccountID:    account.ID,
+		Currency:         account.Currency,
+		BatchDate:        batchDate,
+		Status:           entity.PayoutPaid,
+		TransactionCount: len(transactions),
+		CreatedAt:        asOf,
+	}
+	for _, transaction := range transactions {
+		payout.Amount += transaction.Amount
+		switch transaction.Type {
+		case entity.TransactionPayment:
+			payout.PaymentsTotal += transaction.Amount
+		case entity.TransactionRefund:
+			payout.RefundsTotal += transaction.Amount
+		case entity.TransactionFee:
+			payout.FeesTotal += transaction.Amount
+		}
+	}
+	if payout.Amount <= 0 {
+		return nil, nil
+	}
+
+	if err = s.repository.CreatePayout(ctx, payout, transactions); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "payout created", "merchant_id", merchantID, "payout_id", payout.ID, "amount", payout.Amount)
+	return payout, nil
+}
+
+// availableOn the day funds captured at t can be paid out after a settlement delay of days
+func availableOn(t time.Time, days int) time.Time {
+	return startOfDay(t).AddDate(0, 0, days)
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
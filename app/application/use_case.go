@@ -1,20 +1,105 @@
 package application
 
 import (
+	"context"
+	"io"
+	"time"
+
 	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
 	"github.com/google/uuid"
 )
 
 type MerchantUseCaseInterface interface {
-	Create(merchant *entity.Merchant) (*entity.Merchant, error)
-	GetByName(name string) (*entity.Merchant, error)
+	Create(ctx context.Context, merchant *entity.Merchant) (*entity.Merchant, error)
+	GetByName(ctx context.Context, name string) (*entity.Merchant, error)
+	Login(ctx context.Context, req LoginRequest) (*entity.Merchant, error)
+	// EnrollTOTP requires a valid current code when two-factor is already enabled
+	EnrollTOTP(ctx context.Context, name, code string) (secret string, url string, err error)
+	VerifyTOTP(ctx context.Context, name, code string) error
+	Suspend(ctx context.Context, name, reason string) (*entity.Merchant, error)
+	Reactivate(ctx context.Context, name string) (*entity.Merchant, error)
 }
 
 type PaymentUseCaseInterface interface {
-	Create(payment *entity.Payment) (*entity.Payment, error)
-	GetByID(uuid uuid.UUID) (*entity.Payment, error)
+	Create(ctx context.Context, payment *entity.Payment) (*entity.Payment, error)
+	GetByID(ctx context.Context, uuid uuid.UUID, merchantId string) (*entity.Payment, error)
+	GetByClientSecret(ctx context.Context, uuid uuid.UUID, clientSecret string) (*entity.Payment, error)
+	List(ctx context.Context, filter repository.PaymentFilter, cursor string) (*PaymentPage, error)
+	Search(ctx context.Context, merchantID uint, query string, metadata map[string]string, limit int) ([]entity.Payment, error)
 	ProcessPayment(
+		ctx context.Context,
 		payment *entity.Payment,
-		customer *entity.Card) (*entity.Payment, error)
-	ProcessRefund(uuid uuid.UUID, merchantName string) error
+		card *entity.Card,
+		customer Customer) (*entity.Payment, error)
+	ProcessRefund(ctx context.Context, uuid uuid.UUID, merchantName string) error
+	ResumeAfterReview(ctx context.Context, id uuid.UUID, approved bool, declineCode string) (*entity.Payment, error)
+	CompleteAuthentication(ctx context.Context, id uuid.UUID, transactionID, code string) (*entity.Payment, error)
+	Inspect(ctx context.Context, id uuid.UUID) (*entity.Payment, error)
+	ForceTransition(ctx context.Context, id uuid.UUID, state entity.StateEnum, operator, reason string) (*entity.Payment, error)
+}
+
+type ExportUseCaseInterface interface {
+	Create(ctx context.Context, export *entity.Export) (*entity.Export, error)
+	GetByID(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.Export, error)
+	Open(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.Export, io.ReadCloser, error)
+	FailAbandoned(ctx context.Context, now time.Time) error
+	Start(ctx context.Context)
+}
+
+type SettlementUseCaseInterface interface {
+	Run(ctx context.Context, asOf time.Time) ([]entity.Payout, error)
+	ListPayouts(ctx context.Context, merchantID uint) ([]entity.Payout, error)
+	GetBalance(ctx context.Context, merchantID uint) (*entity.MerchantBalance, error)
+}
+
+type BankAccountUseCaseInterface interface {
+	Create(ctx context.Context, account *entity.BankAccount, accountNumber string) (*entity.BankAccount, error)
+	List(ctx context.Context, merchantID uint) ([]entity.BankAccount, error)
+	Verify(ctx context.Context, id uuid.UUID, merchantID uint, amounts [2]float64) (*entity.BankAccount, error)
+	SetDefault(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.BankAccount, error)
+	Delete(ctx context.Context, id uuid.UUID, merchantID uint) error
+	RotateKeys(ctx context.Context) (int, error)
+}
+
+type PricingUseCaseInterface interface {
+	GetPlan(ctx context.Context, merchantID uint) (*entity.PricingPlan, error)
+	SetPlan(ctx context.Context, plan *entity.PricingPlan) (*entity.PricingPlan, error)
+}
+
+type ReserveUseCaseInterface interface {
+	GetPolicy(ctx context.Context, merchantID uint) (*entity.ReservePolicy, error)
+	SetPolicy(ctx context.Context, policy *entity.ReservePolicy) (*entity.ReservePolicy, error)
+	PlaceHold(ctx context.Context, merchantID uint, amount float64, reason string) (*entity.BalanceHold, error)
+	ReleaseHold(ctx context.Context, id uuid.UUID) (*entity.BalanceHold, error)
+	ListHolds(ctx context.Context, merchantID uint) ([]entity.BalanceHold, error)
+}
+
+type DisputeUseCaseInterface interface {
+	Sync(ctx context.Context, now time.Time) error
+	List(ctx context.Context, merchantID uint, status entity.DisputeStatusEnum) ([]entity.Dispute, error)
+	GetByID(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.Dispute, error)
+	AddEvidence(ctx context.Context, id uuid.UUID, merchantID uint, evidence *entity.DisputeEvidence, content io.Reader) (*entity.DisputeEvidence, error)
+	Submit(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.Dispute, error)
+	Accept(ctx context.Context, id uuid.UUID, merchantID uint) (*entity.Dispute, error)
+	Resolve(ctx context.Context, id uuid.UUID, won bool) (*entity.Dispute, error)
+}
+
+type RiskEngineInterface interface {
+	Evaluate(ctx context.Context, payment *entity.Payment) RiskAssessment
+	RecordAttempt(ctx context.Context, payment *entity.Payment)
+	PruneVelocity(ctx context.Context, now time.Time) error
+}
+
+type ReviewUseCaseInterface interface {
+	List(ctx context.Context, status entity.ReviewStatusEnum, limit int) ([]entity.Review, error)
+	Approve(ctx context.Context, id uuid.UUID, reviewer, note string) (*entity.Review, *entity.Payment, error)
+	Reject(ctx context.Context, id uuid.UUID, reviewer, note string) (*entity.Review, *entity.Payment, error)
+	ExpireOverdue(ctx context.Context, now time.Time) error
+}
+
+type RiskListUseCaseInterface interface {
+	Create(ctx context.Context, entry *entity.RiskListEntry, cardNumber string) (*entity.RiskListEntry, error)
+	List(ctx context.Context, merchantID uint) ([]entity.RiskListEntry, error)
+	Delete(ctx context.Context, id uuid.UUID, merchantID uint) error
 }
@@ -1,39 +1,159 @@
 package config
 
 import (
-	"fmt"
-
-	"github.com/kelseyhightower/envconfig"
+	"time"
 )
 
+// Configuration settings of the service, built by Load from the defaults of the struct tags, a YAML file and the
+// environment variables. The envconfig tags name the variables, nested structs are also read with the name of the
+// parent as prefix (DATABASE_DB_HOST for DB_HOST). Fields tagged secret can be read from a file with <VAR>_FILE
+// and must not keep their default value outside the local environment
 type Configuration struct {
-	Environment string   `envconfig:"ENV" default:"local"`
-	AppName     string   `envconfig:"APP_NAME" default:"payment"`
-	LogLevel    string   `envconfig:"LOG_LEVEL" default:"info"`
-	Port        string   `envconfig:"PORT" default:"8081"`
-	SecretKey   string   `envconfig:"SECRET_KEY" default:"someUltraSecretKey"`
-	Database    DBConfig `envconfig:"DATABASE"`
+	Environment string           `envconfig:"ENV" default:"local" yaml:"environment"`
+	AppName     string           `envconfig:"APP_NAME" default:"payment" yaml:"app_name"`
+	LogLevel    string           `envconfig:"LOG_LEVEL" default:"info" yaml:"log_level"`
+	Port        string           `envconfig:"PORT" default:"8081" yaml:"port"`
+	SecretKey   string           `envconfig:"SECRET_KEY" default:"someUltraSecretKey" yaml:"secret_key" secret:"true"`
+	AdminAPIKey string           `envconfig:"ADMIN_API_KEY" default:"someUltraSecretAdminKey" yaml:"admin_api_key" secret:"true"`
+	FileStore   string           `envconfig:"FILE_STORE_DIR" default:"data" yaml:"file_store"`
+	Database    DBConfig         `envconfig:"DATABASE" yaml:"database"`
+	Security    SecurityConfig   `envconfig:"SECURITY" yaml:"security"`
+	Payments    PaymentConfig    `envconfig:"PAYMENTS" yaml:"payments"`
+	Exports     ExportConfig     `envconfig:"EXPORTS" yaml:"exports"`
+	Settlement  SettlementConfig `envconfig:"SETTLEMENT" yaml:"settlement"`
+	Encryption  EncryptionConfig `envconfig:"ENCRYPTION" yaml:"encryption"`
+	Pricing     PricingConfig    `envconfig:"PRICING" yaml:"pricing"`
+	Disputes    DisputeConfig    `envconfig:"DISPUTES" yaml:"disputes"`
+	Risk        RiskConfig       `envconfig:"RISK" yaml:"risk"`
+	Reviews     ReviewConfig     `envconfig:"REVIEWS" yaml:"reviews"`
+	Tracing     TracingConfig    `envconfig:"TRACING" yaml:"tracing"`
 }
 
+// DBConfig connection to the primary database and its read replicas. Replicas are "host:port" addresses reached
+// with the same credentials, they only serve the reads that tolerate a replication lag. SSLMode is a libpq sslmode
+// and a zero StatementTimeout leaves the queries without time limit. With the sqlite Driver, Name is the path of
+// the database file and the server, credentials, TLS and replica settings don't apply
 type DBConfig struct {
-	Host     string `envconfig:"DB_HOST" default:"localhost"`
-	Port     int    `envconfig:"DB_PORT" default:"5432"`
-	Username string `envconfig:"DB_USERNAME" required:"true"`
-	Password string `envconfig:"DB_PASSWORD" required:"true"`
-	Name     string `envconfig:"DB_NAME" required:"true"`
+	Driver   string `envconfig:"DB_DRIVER" default:"postgres" yaml:"driver"`
+	Host     string `envconfig:"DB_HOST" default:"localhost" yaml:"host"`
+	Port     int    `envconfig:"DB_PORT" default:"5432" yaml:"port"`
+	Username string `envconfig:"DB_USERNAME" yaml:"username"`
+	Password string `envconfig:"DB_PASSWORD" yaml:"password" secret:"true"`
+	Name     string `envconfig:"DB_NAME" required:"true" yaml:"name"`
+	// AutoMigrate applies the pending migrations on startup, otherwise they are run with the migrate command
+	AutoMigrate      bool          `envconfig:"DB_AUTO_MIGRATE" default:"true" yaml:"auto_migrate"`
+	Replicas         []string      `envconfig:"DB_REPLICAS" yaml:"replicas"`
+	SSLMode          string        `envconfig:"DB_SSL_MODE" default:"prefer" yaml:"ssl_mode"`
+	SSLRootCert      string        `envconfig:"DB_SSL_ROOT_CERT" yaml:"ssl_root_cert"`
+	SSLCert          string        `envconfig:"DB_SSL_CERT" yaml:"ssl_cert"`
+	SSLKey           string        `envconfig:"DB_SSL_KEY" yaml:"ssl_key"`
+	ConnectTimeout   time.Duration `envconfig:"DB_CONNECT_TIMEOUT" default:"5s" yaml:"connect_timeout"`
+	StatementTimeout time.Duration `envconfig:"DB_STATEMENT_TIMEOUT" default:"30s" yaml:"statement_timeout"`
+	MaxOpenConns     int           `envconfig:"DB_MAX_OPEN_CONNS" default:"25" yaml:"max_open_conns"`
+	MaxIdleConns     int           `envconfig:"DB_MAX_IDLE_CONNS" default:"10" yaml:"max_idle_conns"`
+	ConnMaxLifetime  time.Duration `envconfig:"DB_CONN_MAX_LIFETIME" default:"30m" yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime  time.Duration `envconfig:"DB_CONN_MAX_IDLE_TIME" default:"5m" yaml:"conn_max_idle_time"`
+}
+
+// SecurityConfig holds the merchant login rules: password strength, lockout after failed attempts and TOTP settings
+type SecurityConfig struct {
+	PasswordMinLength     int           `envconfig:"PASSWORD_MIN_LENGTH" default:"10" yaml:"password_min_length"`
+	PasswordRequireUpper  bool          `envconfig:"PASSWORD_REQUIRE_UPPER" default:"true" yaml:"password_require_upper"`
+	PasswordRequireLower  bool          `envconfig:"PASSWORD_REQUIRE_LOWER" default:"true" yaml:"password_require_lower"`
+	PasswordRequireDigit  bool          `envconfig:"PASSWORD_REQUIRE_DIGIT" default:"true" yaml:"password_require_digit"`
+	PasswordRequireSymbol bool          `envconfig:"PASSWORD_REQUIRE_SYMBOL" default:"false" yaml:"password_require_symbol"`
+	MaxFailedLogins       int           `envconfig:"MAX_FAILED_LOGINS" default:"5" yaml:"max_failed_logins"`
+	LockoutDuration       time.Duration `envconfig:"LOCKOUT_DURATION" default:"1m" yaml:"lockout_duration"`
+	MaxLockoutDuration    time.Duration `envconfig:"MAX_LOCKOUT_DURATION" default:"24h" yaml:"max_lockout_duration"`
+	TOTPIssuer            string        `envconfig:"TOTP_ISSUER" default:"payments_platform" yaml:"totp_issuer"`
+}
+
+// PaymentConfig holds the settings of the payment flow
+type PaymentConfig struct {
+	ClientSecretTTL time.Duration `envconfig:"CLIENT_SECRET_TTL" default:"1h" yaml:"client_secret_ttl"`
+	DefaultCurrency string        `envconfig:"DEFAULT_CURRENCY" default:"USD" yaml:"default_currency"`
+}
+
+// PricingConfig default pricing plan applied to merchants without a plan of their own
+type PricingConfig struct {
+	PercentFee     float64 `envconfig:"PRICING_PERCENT_FEE" default:"2.9" yaml:"percent_fee"`
+	FixedFee       float64 `envconfig:"PRICING_FIXED_FEE" default:"0.30" yaml:"fixed_fee"`
+	RefundPolicy   string  `envconfig:"PRICING_REFUND_POLICY" default:"retain" yaml:"refund_policy"`
+	RefundFixedFee float64 `envconfig:"PRICING_REFUND_FIXED_FEE" default:"0" yaml:"refund_fixed_fee"`
+}
+
+// SettlementConfig holds the payout schedule, funds become available DelayDays after capture (T+N)
+type SettlementConfig struct {
+	DelayDays int           `envconfig:"SETTLEMENT_DELAY_DAYS" default:"2" yaml:"delay_days"`
+	Interval  time.Duration `envconfig:"SETTLEMENT_INTERVAL" default:"1h" yaml:"interval"`
 }
 
-var c Configuration
+// ExportConfig exports are generated by Workers goroutines from a queue of QueueSize jobs, new exports are refused
+// while it is full. Exports still pending or running after Timeout were abandoned by an instance that stopped while
+// generating them and are failed, abandoned exports are checked every Interval
+type ExportConfig struct {
+	Workers   int           `envconfig:"EXPORT_WORKERS" default:"2" yaml:"workers"`
+	QueueSize int           `envconfig:"EXPORT_QUEUE_SIZE" default:"100" yaml:"queue_size"`
+	Timeout   time.Duration `envconfig:"EXPORT_TIMEOUT" default:"1h" yaml:"timeout"`
+	Interval  time.Duration `envconfig:"EXPORT_INTERVAL" default:"15m" yaml:"interval"`
+}
+
+// DisputeConfig merchants must respond to a dispute within ResponseWindow or it is lost, the acquirer
+// notifications and the deadlines are checked every Interval
+type DisputeConfig struct {
+	ResponseWindow  time.Duration `envconfig:"DISPUTE_RESPONSE_WINDOW" default:"168h" yaml:"response_window"`
+	Interval        time.Duration `envconfig:"DISPUTE_INTERVAL" default:"1m" yaml:"interval"`
+	MaxEvidenceSize int64         `envconfig:"DISPUTE_MAX_EVIDENCE_SIZE" default:"5242880" yaml:"max_evidence_size"`
+}
+
+// RiskConfig rules of the fraud screening run before a payment is sent to the acquirer, a zero threshold
+// disables its rule. CountryMismatchAction is the outcome (review or block) when the card was issued in
+// a different country than the customer one
+type RiskConfig struct {
+	ReviewAmount          float64        `envconfig:"RISK_REVIEW_AMOUNT" default:"5000" yaml:"review_amount"`
+	BlockAmount           float64        `envconfig:"RISK_BLOCK_AMOUNT" default:"25000" yaml:"block_amount"`
+	CountryMismatchAction string         `envconfig:"RISK_COUNTRY_MISMATCH_ACTION" default:"review" yaml:"country_mismatch_action"`
+	BlockedCards          []string       `envconfig:"RISK_BLOCKED_CARD_FINGERPRINTS" yaml:"blocked_cards"`
+	BlockedPersonalIDs    []uint         `envconfig:"RISK_BLOCKED_PERSONAL_IDS" yaml:"blocked_personal_ids"`
+	MaxFailedAttempts     int            `envconfig:"RISK_MAX_FAILED_ATTEMPTS" default:"3" yaml:"max_failed_attempts"`
+	FailedAttemptsWindow  time.Duration  `envconfig:"RISK_FAILED_ATTEMPTS_WINDOW" default:"1h" yaml:"failed_attempts_window"`
+	Velocity              VelocityConfig `yaml:"velocity"`
+}
+
+// VelocityConfig sliding window limits enforced by the risk engine, a zero limit disables it. Store is
+// "postgres" to share the counters between instances or "memory" for a single instance
+type VelocityConfig struct {
+	Store                 string        `envconfig:"VELOCITY_STORE" default:"postgres" yaml:"store"`
+	CardAttemptsPerHour   int           `envconfig:"VELOCITY_CARD_ATTEMPTS_PER_HOUR" default:"10" yaml:"card_attempts_per_hour"`
+	PaymentDeclines       int           `envconfig:"VELOCITY_PAYMENT_DECLINES" default:"5" yaml:"payment_declines"`
+	PaymentDeclinesWindow time.Duration `envconfig:"VELOCITY_PAYMENT_DECLINES_WINDOW" default:"24h" yaml:"payment_declines_window"`
+	MerchantDailyVolume   float64       `envconfig:"VELOCITY_MERCHANT_DAILY_VOLUME" default:"1000000" yaml:"merchant_daily_volume"`
+	PruneInterval         time.Duration `envconfig:"VELOCITY_PRUNE_INTERVAL" default:"10m" yaml:"prune_interval"`
+}
+
+// ReviewConfig payments flagged for review are declined when no reviewer decides on them within Timeout,
+// expired reviews are checked every Interval
+type ReviewConfig struct {
+	Timeout  time.Duration `envconfig:"REVIEW_TIMEOUT" default:"24h" yaml:"timeout"`
+	Interval time.Duration `envconfig:"REVIEW_INTERVAL" default:"5m" yaml:"interval"`
+}
 
-func Config() Configuration {
-	return c
+// TracingConfig OpenTelemetry traces are exported over OTLP/HTTP to Endpoint (host:port of a collector) when
+// Enabled, SampleRatio is the fraction of the new traces recorded, incoming sampled traces are always followed
+type TracingConfig struct {
+	Enabled     bool    `envconfig:"TRACING_ENABLED" default:"false" yaml:"enabled"`
+	Endpoint    string  `envconfig:"TRACING_OTLP_ENDPOINT" default:"localhost:4318" yaml:"endpoint"`
+	Insecure    bool    `envconfig:"TRACING_OTLP_INSECURE" default:"true" yaml:"insecure"`
+	SampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1" yaml:"sample_ratio"`
 }
 
-func Environment() {
-	if err := envconfig.Process("", &c); err != nil {
-		if err != nil {
-			fmt.Printf("Error loading configuration: %s\n", err)
-		}
-		return
-	}
+// EncryptionConfig key ring used to encrypt sensitive data at rest, in the form "id1:base64key1,id2:base64key2"
+// with 32 bytes keys, it has no default and every environment generates its own. KeyID is the key used for new
+// encryptions, the others are kept to decrypt older values.
+// FingerprintKey is the HMAC key of the card fingerprints, it has no default either and changing it makes the
+// stored fingerprints unmatchable
+type EncryptionConfig struct {
+	Keys           string `envconfig:"ENCRYPTION_KEYS" required:"true" yaml:"keys" secret:"true"`
+	KeyID          string `envconfig:"ENCRYPTION_KEY_ID" default:"local" yaml:"key_id"`
+	FingerprintKey string `envconfig:"ENCRYPTION_FINGERPRINT_KEY" required:"true" yaml:"fingerprint_key" secret:"true"`
 }
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileVariable names the YAML configuration file, the environment variables take precedence over its values
+const FileVariable = "CONFIG_FILE"
+
+const fileSuffix = "_FILE"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting a leaf field of the configuration with the environment variables it is read from, the prefixed name
+// first
+type setting struct {
+	value    reflect.Value
+	names    []string
+	fallback string
+	hasValue bool
+	required bool
+	secret   bool
+}
+
+// Load builds the configuration in layers: the defaults of the struct tags, the YAML file named by CONFIG_FILE,
+// the environment variables and the files named by the <VAR>_FILE variables. The result is validated
+func Load() (Configuration, error) {
+	c, err := Defaults()
+	if err != nil {
+		return c, err
+	}
+	settings := walk(reflect.ValueOf(&c).Elem(), "")
+	if path := os.Getenv(FileVariable); path != "" {
+		if err := loadFile(path, &c); err != nil {
+			return c, err
+		}
+	}
+
+	for _, s := range settings {
+		if err := s.fromEnvironment(); err != nil {
+			return c, err
+		}
+	}
+	return c, c.Validate()
+}
+
+// Defaults the configuration with the default of every setting, the required ones are left empty
+func Defaults() (Configuration, error) {
+	var c Configuration
+	for _, s := range walk(reflect.ValueOf(&c).Elem(), "") {
+		if s.hasValue {
+			if err := decode(s.value, s.fallback); err != nil {
+				return c, fmt.Errorf("invalid default for %s: %w", s.names[0], err)
+			}
+		}
+	}
+	return c, nil
+}
+
+func loadFile(path string, c *Configuration) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading the configuration file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	// unknown keys are rejected so a typo doesn't silently leave a default in place
+	decoder.KnownFields(true)
+	if err = decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("error parsing the configuration file %s: %w", path, err)
+	}
+	return nil
+}
+
+// fromEnvironment sets the field from the first of its variables defined, or from the file its <VAR>_FILE
+// variable names
+func (s setting) fromEnvironment() error {
+	for _, name := range s.names {
+		value, inline := os.LookupEnv(name)
+		path, inFile := os.LookupEnv(name + fileSuffix)
+		switch {
+		case inline && inFile:
+			return fmt.Errorf("both %s and %s%s are set", name, name, fileSuffix)
+		case inFile:
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("error reading %s%s: %w", name, fileSuffix, err)
+			}
+			value = strings.TrimRight(string(content), "\r\n")
+		case !inline:
+			continue
+		}
+		if err := decode(s.value, value); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+		return nil
+	}
+	return nil
+}
+
+// walk lists the leaf fields of the struct, naming their variables the way envconfig does
+func walk(v reflect.Value, prefix string) []setting {
+	var settings []setting
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("envconfig")
+		name := tag
+		if name == "" {
+			name = strings.ToUpper(field.Name)
+		}
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+		if field.Type.Kind() == reflect.Struct {
+			settings = append(settings, walk(v.Field(i), name)...)
+			continue
+		}
+
+		s := setting{value: v.Field(i), names: []string{name}}
+		if tag != "" && tag != name {
+			s.names = append(s.names, tag)
+		}
+		s.fallback, s.hasValue = field.Tag.Lookup("default")
+		s.required = field.Tag.Get("required") == "true"
+		s.secret = field.Tag.Get("secret") == "true"
+		settings = append(settings, s)
+	}
+	return settings
+}
+
+// decode parses text into the field, lists are comma separated
+func decode(v reflect.Value, text string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if strings.TrimSpace(text) == "" {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+			return nil
+		}
+		items := strings.Split(text, ",")
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decode(slice.Index(i), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
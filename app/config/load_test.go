@@ -0,0 +1,221 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/config"
+)
+
+// validEnvironment the variables of a local configuration that passes the validation
+var validEnvironment = map[string]string{
+	"ENV":                        config.LocalEnvironment,
+	"DB_DRIVER":                  config.SQLiteDriver,
+	"DB_NAME":                    "payments.db",
+	"ENCRYPTION_KEYS":            "local:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+	"ENCRYPTION_FINGERPRINT_KEY": "test-fingerprint-key",
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		file    string
+		wantErr string
+		check   func(t *testing.T, c config.Configuration)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, c config.Configuration) {
+				if c.Port != "8081" || c.Database.Port != 5432 || c.Reviews.Timeout != 24*time.Hour {
+					t.Errorf("defaults not applied: %+v", c)
+				}
+			},
+		},
+		{
+			name: "environment over the defaults",
+			env: map[string]string{"DB_DRIVER": config.PostgresDriver, "DB_USERNAME": "payments", "DB_PASSWORD": "secret",
+				"DB_PORT": "6432", "DB_REPLICAS": "replica-1:5432, replica-2:5432"},
+			check: func(t *testing.T, c config.Configuration) {
+				if c.Database.Port != 6432 || len(c.Database.Replicas) != 2 || c.Database.Replicas[1] != "replica-2:5432" {
+					t.Errorf("environment not applied: %+v", c.Database)
+				}
+			},
+		},
+		{
+			name: "prefixed name",
+			env:  map[string]string{"DATABASE_DB_HOST": "primary"},
+			check: func(t *testing.T, c config.Configuration) {
+				if c.Database.Host != "primary" {
+					t.Errorf("host = %q, want primary", c.Database.Host)
+				}
+			},
+		},
+		{
+			name: "file overridden by the environment",
+			file: "port: \"9090\"\nreviews:\n  timeout: 2h\n",
+			env:  map[string]string{"PORT": "9191"},
+			check: func(t *testing.T, c config.Configuration) {
+				if c.Port != "9191" || c.Reviews.Timeout != 2*time.Hour {
+					t.Errorf("port = %q, review timeout = %v, want 9191 and 2h", c.Port, c.Reviews.Timeout)
+				}
+			},
+		},
+		{
+			name:    "unknown key in the file",
+			file:    "prot: \"9090\"\n",
+			wantErr: "field prot not found",
+		},
+		{
+			name:    "invalid value",
+			env:     map[string]string{"DB_PORT": "five"},
+			wantErr: "invalid value for DB_PORT",
+		},
+		{
+			name:    "required setting missing",
+			env:     map[string]string{"ENCRYPTION_KEYS": ""},
+			wantErr: "ENCRYPTION_KEYS is required",
+		},
+		{
+			name:    "default secret outside local",
+			env:     map[string]string{"ENV": "production"},
+			wantErr: "SECRET_KEY must be changed from its default",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range validEnvironment {
+				t.Setenv(name, value)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			if tt.file != "" {
+				t.Setenv(config.FileVariable, writeFile(t, "config.yaml", tt.file))
+			}
+
+			c, err := config.Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	for name, value := range validEnvironment {
+		t.Setenv(name, value)
+	}
+	t.Setenv("SECRET_KEY_FILE", writeFile(t, "secret_key", "from-file\n"))
+	c, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.SecretKey != "from-file" {
+		t.Errorf("secret key = %q, want from-file", c.SecretKey)
+	}
+
+	t.Setenv("SECRET_KEY", "inline")
+	if _, err = config.Load(); err == nil || !strings.Contains(err.Error(), "both SECRET_KEY and SECRET_KEY_FILE") {
+		t.Errorf("error = %v, want both variables rejected", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *config.Configuration)
+		wantErr []string
+	}{
+		{name: "valid", change: func(*config.Configuration) {}},
+		{
+			name:    "invalid port",
+			change:  func(c *config.Configuration) { c.Port = "0" },
+			wantErr: []string{`PORT "0" is not a valid port`},
+		},
+		{
+			name: "postgres without credentials",
+			change: func(c *config.Configuration) {
+				c.Database.Driver, c.Database.Username, c.Database.Password = config.PostgresDriver, "", ""
+			},
+			wantErr: []string{"DB_USERNAME is required", "DB_PASSWORD is required"},
+		},
+		{
+			name: "connect timeout under a second",
+			change: func(c *config.Configuration) {
+				c.Database.Driver, c.Database.Username, c.Database.Password = config.PostgresDriver, "payments", "secret"
+				c.Database.ConnectTimeout = 500 * time.Millisecond
+			},
+			wantErr: []string{"DB_CONNECT_TIMEOUT must be at least 1s"},
+		},
+		{
+			name:    "replicas with sqlite",
+			change:  func(c *config.Configuration) { c.Database.Replicas = []string{"replica:5432"} },
+			wantErr: []string{"DB_REPLICAS are only supported by the postgres driver"},
+		},
+		{
+			name:    "unknown refund policy",
+			change:  func(c *config.Configuration) { c.Pricing.RefundPolicy = "keep" },
+			wantErr: []string{`PRICING_REFUND_POLICY "keep" must be retain or return`},
+		},
+		{
+			name:    "sample ratio out of range",
+			change:  func(c *config.Configuration) { c.Tracing.SampleRatio = 1.5 },
+			wantErr: []string{"TRACING_SAMPLE_RATIO must be between 0 and 1"},
+		},
+		{
+			name: "every problem at once",
+			change: func(c *config.Configuration) {
+				c.Exports.Interval, c.Reviews.Interval, c.Payments.DefaultCurrency = 0, -time.Minute, "DOLLAR"
+			},
+			wantErr: []string{"EXPORT_INTERVAL must be positive", "REVIEW_INTERVAL must be positive",
+				`DEFAULT_CURRENCY "DOLLAR" is not an ISO 4217 code`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := config.Defaults()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Database.Driver, c.Database.Name = config.SQLiteDriver, "payments.db"
+			c.Encryption.Keys, c.Encryption.FingerprintKey = validEnvironment["ENCRYPTION_KEYS"], "test-fingerprint-key"
+			tt.change(&c)
+
+			err = c.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("error = nil, want %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
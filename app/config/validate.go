@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// LocalEnvironment the development environment, the only one allowed to run with the default secrets
+const LocalEnvironment = "local"
+
+// Database drivers, SQLite runs the service on a local file without a database server
+const (
+	PostgresDriver = "postgres"
+	SQLiteDriver   = "sqlite"
+)
+
+// Validate reports every invalid setting at once: required values missing, default secrets kept outside the
+// local environment and values out of their range
+func (c Configuration) Validate() error {
+	var problems []error
+	for _, s := range walk(reflect.ValueOf(&c).Elem(), "") {
+		name := s.names[len(s.names)-1]
+		switch {
+		case s.required && s.value.IsZero():
+			problems = append(problems, fmt.Errorf("%s is required", name))
+		case s.secret && s.hasValue && c.Environment != LocalEnvironment && s.value.String() == s.fallback:
+			problems = append(problems, fmt.Errorf("%s must be changed from its default outside the %s environment",
+				name, LocalEnvironment))
+		}
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		problems = append(problems, fmt.Errorf("PORT %q is not a valid port", c.Port))
+	}
+	switch c.Database.Driver {
+	case PostgresDriver:
+		if c.Database.Username == "" {
+			problems = append(problems, errors.New("DB_USERNAME is required"))
+		}
+		if c.Database.Password == "" {
+			problems = append(problems, errors.New("DB_PASSWORD is required"))
+		}
+	case SQLiteDriver:
+		if len(c.Database.Replicas) > 0 {
+			problems = append(problems, errors.New("DB_REPLICAS are only supported by the postgres driver"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("DB_DRIVER %q must be %s or %s", c.Database.Driver, PostgresDriver, SQLiteDriver))
+	}
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		problems = append(problems, fmt.Errorf("DB_PORT %d is not a valid port", c.Database.Port))
+	}
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		problems = append(problems, fmt.Errorf("DB_SSL_MODE %q is not a valid sslmode", c.Database.SSLMode))
+	}
+	for _, replica := range c.Database.Replicas {
+		if _, _, err := net.SplitHostPort(replica); err != nil {
+			problems = append(problems, fmt.Errorf("DB_REPLICAS %q is not a host:port address", replica))
+		}
+	}
+	if c.Database.MaxOpenConns < 1 || c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		problems = append(problems, errors.New("DB_MAX_OPEN_CONNS must be positive and DB_MAX_IDLE_CONNS between 0 and it"))
+	}
+	// libpq takes the connect timeout in seconds and 0 disables it
+	if c.Database.Driver == PostgresDriver && c.Database.ConnectTimeout < time.Second {
+		problems = append(problems, errors.New("DB_CONNECT_TIMEOUT must be at least 1s"))
+	}
+	if c.Database.StatementTimeout < 0 {
+		problems = append(problems, errors.New("DB_STATEMENT_TIMEOUT can't be negative"))
+	}
+	if c.Security.PasswordMinLength < 1 {
+		problems = append(problems, errors.New("PASSWORD_MIN_LENGTH must be positive"))
+	}
+	if c.Security.MaxFailedLogins < 1 {
+		problems = append(problems, errors.New("MAX_FAILED_LOGINS must be positive"))
+	}
+	if len(c.Payments.DefaultCurrency) != 3 {
+		problems = append(problems, fmt.Errorf("DEFAULT_CURRENCY %q is not an ISO 4217 code", c.Payments.DefaultCurrency))
+	}
+	if c.Pricing.RefundPolicy != "retain" && c.Pricing.RefundPolicy != "return" {
+		problems = append(problems, fmt.Errorf("PRICING_REFUND_POLICY %q must be retain or return", c.Pricing.RefundPolicy))
+	}
+	if c.Risk.CountryMismatchAction != "review" && c.Risk.CountryMismatchAction != "block" {
+		problems = append(problems, fmt.Errorf("RISK_COUNTRY_MISMATCH_ACTION %q must be review or block",
+			c.Risk.CountryMismatchAction))
+	}
+	if c.Risk.Velocity.Store != "postgres" && c.Risk.Velocity.Store != "memory" {
+		problems = append(problems, fmt.Errorf("VELOCITY_STORE %q must be postgres or memory", c.Risk.Velocity.Store))
+	}
+	if c.Exports.Workers < 1 || c.Exports.QueueSize < 1 {
+		problems = append(problems, errors.New("EXPORT_WORKERS and EXPORT_QUEUE_SIZE must be positive"))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, errors.New("TRACING_SAMPLE_RATIO must be between 0 and 1"))
+	}
+	// the workers tick at these intervals
+	intervals := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"SETTLEMENT_INTERVAL", c.Settlement.Interval},
+		{"EXPORT_INTERVAL", c.Exports.Interval},
+		{"EXPORT_TIMEOUT", c.Exports.Timeout},
+		{"DISPUTE_INTERVAL", c.Disputes.Interval},
+		{"VELOCITY_PRUNE_INTERVAL", c.Risk.Velocity.PruneInterval},
+		{"REVIEW_INTERVAL", c.Reviews.Interval},
+	}
+	for _, interval := range intervals {
+		if interval.value <= 0 {
+			problems = append(problems, fmt.Errorf("%s must be positive", interval.name))
+		}
+	}
+	return errors.Join(problems...)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io/fs"
+	"log/slog"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/config"
+	"github.com/alvarezcarlos/payment/app/infrastructure/acquirer"
+	"github.com/alvarezcarlos/payment/app/infrastructure/filestore"
+	"github.com/alvarezcarlos/payment/app/infrastructure/postgres/connection"
+	"github.com/alvarezcarlos/payment/app/infrastructure/postgres/migrations"
+	repo "github.com/alvarezcarlos/payment/app/infrastructure/postgres/repository"
+	"github.com/alvarezcarlos/payment/app/infrastructure/sqlite"
+	"github.com/alvarezcarlos/payment/app/infrastructure/velocity"
+	"github.com/alvarezcarlos/payment/app/metrics"
+	"github.com/alvarezcarlos/payment/app/tracing"
+	"github.com/alvarezcarlos/payment/app/utils"
+	"gorm.io/gorm"
+)
+
+// container the database and the use cases shared by the API server and the operations commands, the
+// database is not reached until a use case runs
+type container struct {
+	cfg      config.Configuration
+	db       connection.Connection
+	conn     *gorm.DB
+	migrator connection.MigrateInterface
+
+	riskEngine   application.RiskEngineInterface
+	merchants    application.MerchantUseCaseInterface
+	payments     application.PaymentUseCaseInterface
+	exports      application.ExportUseCaseInterface
+	settlement   application.SettlementUseCaseInterface
+	bankAccounts application.BankAccountUseCaseInterface
+	pricing      application.PricingUseCaseInterface
+	reserves     application.ReserveUseCaseInterface
+	riskLists    application.RiskListUseCaseInterface
+	reviews      application.ReviewUseCaseInterface
+	disputes     application.DisputeUseCaseInterface
+}
+
+func newContainer(cfg config.Configuration) (*container, error) {
+	//DBConnection
+	db, migrationFiles := openDatabase(cfg.Database)
+	conn := db.GetConnection()
+	if err := conn.Use(metrics.GormPlugin{DBName: cfg.Database.Name}); err != nil {
+		return nil, err
+	}
+	if err := conn.Use(tracing.GormPlugin{}); err != nil {
+		return nil, err
+	}
+	migrator, err := connection.NewMigrate(conn, migrationFiles, slog.Default())
+	if err != nil {
+		return nil, err
+	}
+	//Repositories
+	merchantRepo := repo.NewMerchantRepository(conn)
+	paymentRepo := repo.NewPaymentRepository(conn)
+	exportRepo := repo.NewExportRepository(conn)
+	settlementRepo := repo.NewSettlementRepository(conn)
+	bankAccountRepo := repo.NewBankAccountRepository(conn)
+	pricingRepo := repo.NewPricingRepository(conn)
+	reserveRepo := repo.NewReserveRepository(conn)
+	disputeRepo := repo.NewDisputeRepository(conn)
+	riskRepo := repo.NewRiskRepository(conn)
+	reviewRepo := repo.NewReviewRepository(conn)
+	riskListRepo := repo.NewRiskListRepository(conn)
+	velocityStore := repo.NewVelocityStore(conn)
+	if cfg.Risk.Velocity.Store == "memory" {
+		velocityStore = velocity.NewMemoryStore()
+	}
+	fileStore, err := filestore.NewLocalFileStore(cfg.FileStore)
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := utils.NewCipher(cfg.Encryption.Keys, cfg.Encryption.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	cardAcquirer := tracing.TraceAcquirer(metrics.InstrumentAcquirer(acquirer.NewSimulator(slog.Default())))
+	//UseCases
+	c := &container{cfg: cfg, db: db, conn: conn, migrator: migrator}
+	c.riskEngine = application.NewRiskEngine(riskRepo, velocityStore, riskListRepo, cfg.Risk, slog.Default())
+	c.merchants = application.NewMerchantUseCase(merchantRepo, cfg.Security, slog.Default())
+	c.payments = application.NewPaymentUseCase(paymentRepo, pricingRepo, reserveRepo, reviewRepo, bankAccountRepo,
+		cardAcquirer, c.riskEngine, cfg, slog.Default())
+	c.exports = application.NewExportUseCase(exportRepo, paymentRepo, fileStore, cfg.Exports, slog.Default())
+	c.settlement = application.NewSettlementUseCase(settlementRepo, bankAccountRepo, slog.Default())
+	c.bankAccounts = application.NewBankAccountUseCase(bankAccountRepo, settlementRepo, cipher,
+		cfg.Payments.DefaultCurrency, slog.Default())
+	c.pricing = application.NewPricingUseCase(pricingRepo, cfg.Pricing, slog.Default())
+	c.reserves = application.NewReserveUseCase(reserveRepo, slog.Default())
+	c.riskLists = application.NewRiskListUseCase(riskListRepo, cfg.Encryption.FingerprintKey, slog.Default())
+	c.reviews = application.NewReviewUseCase(reviewRepo, c.payments, slog.Default())
+	c.disputes = application.NewDisputeUseCase(disputeRepo, paymentRepo, cardAcquirer, fileStore, cfg.Disputes,
+		slog.Default())
+	return c, nil
+}
+
+// openDatabase the connection and the migrations of the configured driver
+func openDatabase(dbConfig config.DBConfig) (connection.Connection, fs.FS) {
+	conf := &gorm.Config{Logger: dbLogger()}
+	if dbConfig.Driver == config.SQLiteDriver {
+		return sqlite.NewConnection(dbConfig, conf, slog.Default()), sqlite.Migrations()
+	}
+	return connection.NewPostgresConnection(dbConfig, conf, slog.Default()), migrations.Postgres()
+}
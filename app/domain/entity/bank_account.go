@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BankAccount payout destination of a merchant, the account number is stored encrypted and only its
+// last digits are exposed
+type BankAccount struct {
+	ID                     uuid.UUID             `json:"id" gorm:"type:uuid;primaryKey"`
+	MerchantID             uint                  `json:"merchant_id" gorm:"index"`
+	Type                   BankAccountTypeEnum   `json:"type"`
+	HolderName             string                `json:"holder_name"`
+	Currency               string                `json:"currency"`
+	Country                string                `json:"country"`
+	AccountNumberEncrypted string                `json:"-"`
+	RoutingNumber          string                `json:"routing_number,omitempty"`
+	Last4                  string                `json:"last4"`
+	Status                 BankAccountStatusEnum `json:"status"`
+	MicroDeposit1          float64               `json:"-"`
+	MicroDeposit2          float64               `json:"-"`
+	VerificationAttempts   int                   `json:"-"`
+	IsDefault              bool                  `json:"is_default"`
+	CreatedAt              time.Time             `json:"created_at"`
+	UpdatedAt              time.Time             `json:"updated_at"`
+}
+
+type BankAccountTypeEnum string
+
+const (
+	BankAccountIBAN BankAccountTypeEnum = "iban"
+	BankAccountACH  BankAccountTypeEnum = "ach"
+)
+
+type BankAccountStatusEnum string
+
+const (
+	BankAccountPendingVerification BankAccountStatusEnum = "pending_verification"
+	BankAccountVerified            BankAccountStatusEnum = "verified"
+	BankAccountVerificationFailed  BankAccountStatusEnum = "verification_failed"
+)
+
+func (BankAccount) TableName() string {
+	return "bank_accounts"
+}
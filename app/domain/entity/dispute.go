@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Dispute chargeback raised by the cardholder against a captured payment. The disputed amount is withdrawn
+// from the merchant when it is opened and given back if the merchant wins it
+type Dispute struct {
+	ID            uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey"`
+	PaymentID     uuid.UUID         `json:"payment_id" gorm:"type:uuid;uniqueIndex"`
+	MerchantID    uint              `json:"merchant_id" gorm:"index"`
+	Amount        float64           `json:"amount"`
+	Currency      string            `json:"currency"`
+	Reason        string            `json:"reason"`
+	Status        DisputeStatusEnum `json:"status" gorm:"index"`
+	EvidenceDueBy time.Time         `json:"evidence_due_by"`
+	Evidence      []DisputeEvidence `json:"evidence,omitempty" gorm:"foreignKey:DisputeID"`
+	ResolvedAt    *time.Time        `json:"resolved_at,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// DisputeEvidence document uploaded by the merchant to contest a dispute
+type DisputeEvidence struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	DisputeID   uuid.UUID `json:"dispute_id" gorm:"type:uuid;index"`
+	Type        string    `json:"type"`
+	Note        string    `json:"note,omitempty"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Path        string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type DisputeStatusEnum string
+
+const (
+	DisputeNeedsResponse DisputeStatusEnum = "needs_response"
+	DisputeUnderReview   DisputeStatusEnum = "under_review"
+	DisputeWon           DisputeStatusEnum = "won"
+	DisputeLost          DisputeStatusEnum = "lost"
+)
+
+func (Dispute) TableName() string {
+	return "disputes"
+}
+
+func (DisputeEvidence) TableName() string {
+	return "dispute_evidence"
+}
@@ -18,23 +18,94 @@ type Card struct {
 }
 
 type Merchant struct {
-	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	Name      string    `json:"name" gorm:"unique"`
-	Balance   float64   `json:"balance"`
-	Payments  []Payment `json:"payments" gorm:"foreignKey:MerchantID"`
-	Password  string    `json:"-"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID                  uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name                string     `json:"name" gorm:"unique"`
+	Balance             float64    `json:"balance"`
+	Payments            []Payment  `json:"payments,omitempty" gorm:"foreignKey:MerchantID"`
+	Password            string     `json:"-"`
+	FailedLoginAttempts int        `json:"-"`
+	LockoutCount        int        `json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+	TOTPSecret          string     `json:"-"`
+	TOTPEnabled         bool       `json:"totp_enabled"`
+	TOTPLastStep        int64      `json:"-"`
+	SuspendedAt         *time.Time `json:"suspended_at,omitempty"`
+	SuspensionReason    string     `json:"suspension_reason,omitempty"`
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// IsLocked reports whether the merchant login is temporarily blocked after too many failed attempts
+func (m *Merchant) IsLocked(now time.Time) bool {
+	return m.LockedUntil != nil && now.Before(*m.LockedUntil)
+}
+
+// IsSuspended reports whether the merchant was suspended by an operator, suspended merchants can neither
+// log in nor use the API and are not paid out until reactivated
+func (m *Merchant) IsSuspended() bool {
+	return m.SuspendedAt != nil
+}
+
+// LoginEvent audit record of every login attempt made against a merchant account
+type LoginEvent struct {
+	ID           uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	MerchantID   uint           `json:"merchant_id" gorm:"index"`
+	MerchantName string         `json:"merchant_name"`
+	Event        LoginEventEnum `json:"event"`
+	IP           string         `json:"ip"`
+	UserAgent    string         `json:"user_agent"`
+	CreatedAt    time.Time      `json:"created_at"`
 }
 
 type Payment struct {
-	ID         uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
-	Amount     float64   `json:"amount"`
-	CardNumber string    `json:"-"`
-	MerchantID uint      `json:"merchant_id" gorm:"index"`
-	States     []State   `json:"states" gorm:"many2many:payment_states;"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID                    uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey"`
+	Amount                float64           `json:"amount" gorm:"index:idx_payments_merchant_amount,priority:2"`
+	Currency              string            `json:"currency"`
+	FeeAmount             float64           `json:"fee_amount"`
+	NetAmount             float64           `json:"net_amount"`
+	FeeLines              []FeeLine         `json:"fee_lines,omitempty" gorm:"foreignKey:PaymentID"`
+	CardNumber            string            `json:"-"`
+	CardBrand             string            `json:"card_brand,omitempty"`
+	CardLast4             string            `json:"card_last4,omitempty" gorm:"index"`
+	CardFingerprint       string            `json:"card_fingerprint,omitempty" gorm:"index"`
+	CustomerPersonalID    uint              `json:"customer_personal_id,omitempty" gorm:"index"`
+	CustomerName          string            `json:"customer_name,omitempty"`
+	CustomerCountry       string            `json:"customer_country,omitempty"`
+	CustomerEmail         string            `json:"customer_email,omitempty"`
+	RiskOutcome           RiskOutcomeEnum   `json:"risk_outcome,omitempty"`
+	RiskRules             StringList        `json:"risk_rules,omitempty"`
+	DeclineCode           string            `json:"decline_code,omitempty"`
+	ThreeDSStatus         ThreeDSStatusEnum `json:"three_ds_status,omitempty"`
+	ThreeDSTransactionID  string            `json:"-"`
+	LiabilityShift        bool              `json:"liability_shift"`
+	NextActionURL         string            `json:"next_action_url,omitempty"`
+	Description           string            `json:"description,omitempty"`
+	Reference             string            `json:"reference,omitempty" gorm:"index"`
+	Metadata              Metadata          `json:"metadata,omitempty"`
+	MerchantID            uint              `json:"merchant_id" gorm:"index;index:idx_payments_merchant_created,priority:1;index:idx_payments_merchant_amount,priority:1;index:idx_payments_merchant_status,priority:1"`
+	Status                StateEnum         `json:"status" gorm:"index:idx_payments_merchant_status,priority:2"`
+	States                []State           `json:"states" gorm:"many2many:payment_states;"`
+	ClientSecret          string            `json:"-"`
+	ClientSecretExpiresAt time.Time         `json:"-"`
+	CreatedAt             time.Time         `json:"created_at" gorm:"index:idx_payments_merchant_created,priority:2"`
+	UpdatedAt             time.Time         `json:"updated_at"`
+}
+
+// AddState appends a state to the payment history and keeps the denormalized current status in sync
+func (p *Payment) AddState(name StateEnum) {
+	p.States = append(p.States, SetState(name))
+	p.Status = name
+}
+
+// CurrentState the last state the payment went through
+func (p *Payment) CurrentState() StateEnum {
+	if p.Status != "" {
+		return p.Status
+	}
+	if len(p.States) == 0 {
+		return ""
+	}
+	return StateEnum(p.States[len(p.States)-1].Name)
 }
 
 type State struct {
@@ -53,6 +124,12 @@ func SetState(name StateEnum) State {
 		s.ID = 3
 	case Refunded:
 		s.ID = 4
+	case Disputed:
+		s.ID = 5
+	case InReview:
+		s.ID = 6
+	case RequiresAction:
+		s.ID = 7
 	}
 	return s
 }
@@ -64,6 +141,37 @@ const (
 	Succeeded StateEnum = "Succeeded"
 	Rejected  StateEnum = "Rejected"
 	Refunded  StateEnum = "Refunded"
+	Disputed  StateEnum = "Disputed"
+	InReview  StateEnum = "InReview"
+	// RequiresAction the customer must complete a 3-D Secure challenge at the payment NextActionURL
+	RequiresAction StateEnum = "RequiresAction"
+)
+
+// ThreeDSStatusEnum result of the 3-D Secure authentication of the cardholder, the issuer takes the fraud
+// chargebacks liability of authenticated payments
+type ThreeDSStatusEnum string
+
+const (
+	ThreeDSNotEnrolled       ThreeDSStatusEnum = "not_enrolled"
+	ThreeDSChallengeRequired ThreeDSStatusEnum = "challenge_required"
+	ThreeDSAuthenticated     ThreeDSStatusEnum = "authenticated"
+	ThreeDSFailed            ThreeDSStatusEnum = "failed"
+)
+
+type LoginEventEnum string
+
+const (
+	LoginSucceeded      LoginEventEnum = "login_succeeded"
+	LoginFailed         LoginEventEnum = "login_failed"
+	LoginLocked         LoginEventEnum = "login_locked"
+	LoginTOTPFailed     LoginEventEnum = "login_totp_failed"
+	LoginTOTPRequired   LoginEventEnum = "login_totp_required"
+	TOTPEnrolled        LoginEventEnum = "totp_enrolled"
+	TOTPEnabled         LoginEventEnum = "totp_enabled"
+	TOTPVerifyFailure   LoginEventEnum = "totp_verify_failed"
+	LoginSuspended      LoginEventEnum = "login_suspended"
+	MerchantSuspended   LoginEventEnum = "merchant_suspended"
+	MerchantReactivated LoginEventEnum = "merchant_reactivated"
 )
 
 func (Merchant) TableName() string {
@@ -73,3 +181,7 @@ func (Merchant) TableName() string {
 func (Payment) TableName() string {
 	return "payments"
 }
+
+func (LoginEvent) TableName() string {
+	return "login_events"
+}
@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Export asynchronous job producing a file with the merchant transactions of a date range
+type Export struct {
+	ID          uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey"`
+	MerchantID  uint             `json:"merchant_id" gorm:"index"`
+	Format      ExportFormatEnum `json:"format"`
+	From        time.Time        `json:"from"`
+	To          time.Time        `json:"to"`
+	Status      ExportStatusEnum `json:"status"`
+	FileName    string           `json:"-"`
+	RowCount    int              `json:"row_count"`
+	Error       string           `json:"error,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+}
+
+type ExportFormatEnum string
+
+const (
+	ExportCSV    ExportFormatEnum = "csv"
+	ExportNDJSON ExportFormatEnum = "ndjson"
+)
+
+type ExportStatusEnum string
+
+const (
+	ExportPending   ExportStatusEnum = "pending"
+	ExportRunning   ExportStatusEnum = "running"
+	ExportCompleted ExportStatusEnum = "completed"
+	ExportFailed    ExportStatusEnum = "failed"
+)
+
+func (Export) TableName() string {
+	return "exports"
+}
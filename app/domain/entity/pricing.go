@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PricingPlan fees charged by the platform to a merchant, a percentage of the amount plus a fixed amount.
+// Rules override the base fees for a card brand and/or currency
+type PricingPlan struct {
+	ID             uint                `json:"id" gorm:"primaryKey;autoIncrement"`
+	MerchantID     uint                `json:"merchant_id" gorm:"uniqueIndex"`
+	Name           string              `json:"name"`
+	PercentFee     float64             `json:"percent_fee"`
+	FixedFee       float64             `json:"fixed_fee"`
+	RefundPolicy   RefundFeePolicyEnum `json:"refund_policy"`
+	RefundFixedFee float64             `json:"refund_fixed_fee"`
+	Rules          []PricingRule       `json:"rules" gorm:"foreignKey:PlanID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// PricingRule fees of a plan for a card brand and/or currency, empty values match any
+type PricingRule struct {
+	ID         uint    `json:"id" gorm:"primaryKey;autoIncrement"`
+	PlanID     uint    `json:"-" gorm:"index"`
+	CardBrand  string  `json:"card_brand,omitempty"`
+	Currency   string  `json:"currency,omitempty"`
+	PercentFee float64 `json:"percent_fee"`
+	FixedFee   float64 `json:"fixed_fee"`
+}
+
+type RefundFeePolicyEnum string
+
+const (
+	// RefundFeeRetain the processing fee is kept by the platform when the payment is refunded
+	RefundFeeRetain RefundFeePolicyEnum = "retain"
+	// RefundFeeReturn the processing fee is given back to the merchant when the payment is refunded
+	RefundFeeReturn RefundFeePolicyEnum = "return"
+)
+
+// FeeLine fee charged (positive) or given back (negative) to the merchant for a payment
+type FeeLine struct {
+	ID          uint        `json:"id" gorm:"primaryKey;autoIncrement"`
+	PaymentID   uuid.UUID   `json:"-" gorm:"type:uuid;index"`
+	Type        FeeTypeEnum `json:"type"`
+	Description string      `json:"description"`
+	Amount      float64     `json:"amount"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+type FeeTypeEnum string
+
+const (
+	FeeProcessing FeeTypeEnum = "processing"
+	FeeRefund     FeeTypeEnum = "refund"
+)
+
+func (PricingPlan) TableName() string {
+	return "pricing_plans"
+}
+
+func (PricingRule) TableName() string {
+	return "pricing_rules"
+}
+
+func (FeeLine) TableName() string {
+	return "fee_lines"
+}
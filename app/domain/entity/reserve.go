@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReservePolicy rolling reserve of a merchant: Percent of every captured payment is held for Days after
+// it becomes available, to cover refunds and chargebacks
+type ReservePolicy struct {
+	MerchantID uint      `json:"merchant_id" gorm:"primaryKey;autoIncrement:false"`
+	Percent    float64   `json:"percent"`
+	Days       int       `json:"days"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// BalanceHold funds of a merchant manually held by an operator until released
+type BalanceHold struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	MerchantID uint       `json:"merchant_id" gorm:"index"`
+	Amount     float64    `json:"amount"`
+	Reason     string     `json:"reason"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (ReservePolicy) TableName() string {
+	return "reserve_policies"
+}
+
+func (BalanceHold) TableName() string {
+	return "balance_holds"
+}
@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Review manual review of a payment flagged by the risk engine, the payment stays InReview until a reviewer
+// decides on it or the review expires at DueAt
+type Review struct {
+	ID         uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey"`
+	PaymentID  uuid.UUID        `json:"payment_id" gorm:"type:uuid;index"`
+	MerchantID uint             `json:"merchant_id" gorm:"index"`
+	Amount     float64          `json:"amount"`
+	Rules      StringList       `json:"rules"`
+	Status     ReviewStatusEnum `json:"status" gorm:"index"`
+	Reviewer   string           `json:"reviewer,omitempty"`
+	Note       string           `json:"note,omitempty"`
+	DueAt      time.Time        `json:"due_at" gorm:"index"`
+	DecidedAt  *time.Time       `json:"decided_at,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+type ReviewStatusEnum string
+
+const (
+	ReviewPending  ReviewStatusEnum = "pending"
+	ReviewApproved ReviewStatusEnum = "approved"
+	ReviewRejected ReviewStatusEnum = "rejected"
+	ReviewExpired  ReviewStatusEnum = "expired"
+)
+
+func (Review) TableName() string {
+	return "reviews"
+}
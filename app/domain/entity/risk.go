@@ -0,0 +1,86 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// PaymentAttempt every processing attempt of a payment with its result, used by the risk engine to detect
+// repeated failures of a card
+type PaymentAttempt struct {
+	ID                 uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	PaymentID          uuid.UUID       `json:"payment_id" gorm:"type:uuid;index"`
+	MerchantID         uint            `json:"merchant_id" gorm:"index"`
+	CardFingerprint    string          `json:"-" gorm:"index:idx_payment_attempts_fingerprint_created,priority:1"`
+	CustomerPersonalID uint            `json:"customer_personal_id"`
+	Amount             float64         `json:"amount"`
+	Status             StateEnum       `json:"status"`
+	DeclineCode        string          `json:"decline_code,omitempty"`
+	RiskOutcome        RiskOutcomeEnum `json:"risk_outcome"`
+	CreatedAt          time.Time       `json:"created_at" gorm:"index:idx_payment_attempts_fingerprint_created,priority:2"`
+}
+
+// VelocityEvent value counted by a velocity window until ExpiresAt
+type VelocityEvent struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	Key       string `gorm:"index:idx_velocity_events_key_created,priority:1"`
+	Value     float64
+	CreatedAt time.Time `gorm:"index:idx_velocity_events_key_created,priority:2"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+type RiskOutcomeEnum string
+
+const (
+	RiskAllow  RiskOutcomeEnum = "allow"
+	RiskReview RiskOutcomeEnum = "review"
+	RiskBlock  RiskOutcomeEnum = "block"
+)
+
+// StringList list of strings stored as a JSON array
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal(l)
+	return string(raw), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return errors.New("unsupported string list value")
+	}
+	return json.Unmarshal(raw, l)
+}
+
+func (StringList) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "jsonb"
+	}
+	return "json"
+}
+
+func (PaymentAttempt) TableName() string {
+	return "payment_attempts"
+}
+
+func (VelocityEvent) TableName() string {
+	return "velocity_events"
+}
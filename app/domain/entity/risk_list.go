@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RiskListEntry value a merchant allows or blocks on its own payments. Cards are listed by fingerprint so
+// no card number is stored in the list
+type RiskListEntry struct {
+	ID         uuid.UUID             `json:"id" gorm:"type:uuid;primaryKey"`
+	MerchantID uint                  `json:"merchant_id" gorm:"uniqueIndex:idx_risk_list_entries_value,priority:1"`
+	List       RiskListEnum          `json:"list" gorm:"uniqueIndex:idx_risk_list_entries_value,priority:2"`
+	Type       RiskListEntryTypeEnum `json:"type" gorm:"uniqueIndex:idx_risk_list_entries_value,priority:3"`
+	Value      string                `json:"value" gorm:"uniqueIndex:idx_risk_list_entries_value,priority:4"`
+	Note       string                `json:"note,omitempty"`
+	CreatedAt  time.Time             `json:"created_at"`
+}
+
+type RiskListEnum string
+
+const (
+	RiskListAllow RiskListEnum = "allow"
+	RiskListBlock RiskListEnum = "block"
+)
+
+type RiskListEntryTypeEnum string
+
+const (
+	EntryCardFingerprint RiskListEntryTypeEnum = "card_fingerprint"
+	EntryPersonalID      RiskListEntryTypeEnum = "personal_id"
+	EntryEmailDomain     RiskListEntryTypeEnum = "email_domain"
+	// EntryBINRange first 6 digits of the card, a single BIN or an inclusive range as "400000-400099"
+	EntryBINRange RiskListEntryTypeEnum = "bin_range"
+)
+
+func (RiskListEntry) TableName() string {
+	return "risk_list_entries"
+}
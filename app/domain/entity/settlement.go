@@ -0,0 +1,77 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BalanceTransaction ledger line moving funds in or out of the merchant balance. It becomes available
+// for payout on AvailableOn and is settled once it is included in a payout
+type BalanceTransaction struct {
+	ID          uint                       `json:"id" gorm:"primaryKey;autoIncrement"`
+	MerchantID  uint                       `json:"merchant_id" gorm:"index:idx_balance_transactions_settlement,priority:1"`
+	PaymentID   *uuid.UUID                 `json:"payment_id,omitempty" gorm:"type:uuid;index"`
+	PayoutID    *uuid.UUID                 `json:"payout_id,omitempty" gorm:"type:uuid;index:idx_balance_transactions_settlement,priority:2"`
+	Type        BalanceTransactionTypeEnum `json:"type"`
+	Amount      float64                    `json:"amount"`
+	AvailableOn time.Time                  `json:"available_on" gorm:"index:idx_balance_transactions_settlement,priority:3"`
+	CreatedAt   time.Time                  `json:"created_at"`
+}
+
+type BalanceTransactionTypeEnum string
+
+const (
+	TransactionPayment BalanceTransactionTypeEnum = "payment"
+	TransactionRefund  BalanceTransactionTypeEnum = "refund"
+	TransactionFee     BalanceTransactionTypeEnum = "fee"
+	// TransactionReserveHold and TransactionReserveRelease move a rolling reserve out of and back into
+	// the available balance
+	TransactionReserveHold    BalanceTransactionTypeEnum = "reserve_hold"
+	TransactionReserveRelease BalanceTransactionTypeEnum = "reserve_release"
+	TransactionHold           BalanceTransactionTypeEnum = "hold"
+	TransactionHoldRelease    BalanceTransactionTypeEnum = "hold_release"
+	// TransactionDispute withdraws the disputed amount, TransactionDisputeReversal returns it when the dispute is won
+	TransactionDispute         BalanceTransactionTypeEnum = "dispute"
+	TransactionDisputeReversal BalanceTransactionTypeEnum = "dispute_reversal"
+	TransactionPayout          BalanceTransactionTypeEnum = "payout"
+)
+
+// Payout daily settlement batch transferring the available funds of a merchant out of the platform
+type Payout struct {
+	ID               uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey"`
+	MerchantID       uint             `json:"merchant_id" gorm:"uniqueIndex:idx_payouts_merchant_batch,priority:1"`
+	BatchDate        time.Time        `json:"batch_date" gorm:"type:date;uniqueIndex:idx_payouts_merchant_batch,priority:2"`
+	BankAccountID    uuid.UUID        `json:"bank_account_id" gorm:"type:uuid"`
+	Currency         string           `json:"currency"`
+	Amount           float64          `json:"amount"`
+	PaymentsTotal    float64          `json:"payments_total"`
+	RefundsTotal     float64          `json:"refunds_total"`
+	FeesTotal        float64          `json:"fees_total"`
+	TransactionCount int              `json:"transaction_count"`
+	Status           PayoutStatusEnum `json:"status"`
+	CreatedAt        time.Time        `json:"created_at"`
+}
+
+type PayoutStatusEnum string
+
+const (
+	PayoutPaid PayoutStatusEnum = "paid"
+)
+
+// MerchantBalance funds of the merchant split by availability, Reserved and Held are not part of Available
+type MerchantBalance struct {
+	Balance   float64 `json:"balance"`
+	Available float64 `json:"available"`
+	Pending   float64 `json:"pending"`
+	Reserved  float64 `json:"reserved"`
+	Held      float64 `json:"held"`
+}
+
+func (BalanceTransaction) TableName() string {
+	return "balance_transactions"
+}
+
+func (Payout) TableName() string {
+	return "payouts"
+}
@@ -1,21 +1,238 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
 	"github.com/alvarezcarlos/payment/app/domain/entity"
 	"github.com/google/uuid"
 )
 
+// ErrDuplicated returned by the repositories when a record violates a uniqueness constraint
+var ErrDuplicated = errors.New("duplicated record")
+
+// ErrInsufficientFunds returned when a debit would leave the merchant balance negative
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrAlreadySettled returned when a payout includes transactions another payout settled
+var ErrAlreadySettled = errors.New("transactions already settled")
+
+// ErrStatusChanged returned when a record is no longer in the status a transition starts from
+var ErrStatusChanged = errors.New("status changed concurrently")
+
+type readOnlyKey struct{}
+
+// ReadOnly marks the repository calls made with the returned context as tolerating a replication lag, so they can
+// be served by a read replica. Reads whose result is written back or checked for consistency must not use it
+func ReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// IsReadOnly reports whether the context was marked by ReadOnly
+func IsReadOnly(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyKey{}).(bool)
+	return readOnly
+}
+
 type MerchantRepository interface {
-	Create(merchant *entity.Merchant) (*entity.Merchant, error)
-	GetByName(name string) (*entity.Merchant, error)
+	Create(ctx context.Context, merchant *entity.Merchant) (*entity.Merchant, error)
+	GetByName(ctx context.Context, name string) (*entity.Merchant, error)
+	// Update stores only the login state and the two-factor settings of the merchant
+	Update(ctx context.Context, merchant *entity.Merchant) error
+	// RegisterFailedLogin adds a failed login to the merchant in a single statement and returns its failed attempts
+	// and lockouts after it
+	RegisterFailedLogin(ctx context.Context, merchantID uint) (attempts int, lockouts int, err error)
+	// Lock locks the merchant until lockedUntil and resets its failed attempts when they reached maxAttempts, it
+	// reports false when a concurrent failure already locked it
+	Lock(ctx context.Context, merchantID uint, maxAttempts int, lockedUntil time.Time) (bool, error)
+	// UseTOTPStep records step as the last two-factor time step accepted for the merchant, it reports false when
+	// the step or a later one was already accepted
+	UseTOTPStep(ctx context.Context, merchantID uint, step int64) (bool, error)
+	// UpdateSuspension stores only the suspension of the merchant, leaving its balance and login state as they are
+	UpdateSuspension(ctx context.Context, merchant *entity.Merchant) error
+	CreateLoginEvent(ctx context.Context, event *entity.LoginEvent) error
 }
 
 type PaymentRepository interface {
-	Create(payment *entity.Payment) error
-	Update(payment *entity.Payment) (*entity.Payment, error)
-	GetByID(id uuid.UUID) (*entity.Payment, error)
-	CreateCard(card *entity.Card) error
-	GetCardByNumber(number string) (*entity.Card, error)
-	GetMerchantByID(id uint) (*entity.Merchant, error)
-	UpdateCardAndMerchant(card *entity.Card, merchant *entity.Merchant) error
+	Create(ctx context.Context, payment *entity.Payment) error
+	Update(ctx context.Context, payment *entity.Payment) (*entity.Payment, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Payment, error)
+	CreateCard(ctx context.Context, card *entity.Card) error
+	GetCardByNumber(ctx context.Context, number string) (*entity.Card, error)
+	GetMerchantByID(ctx context.Context, id uint) (*entity.Merchant, error)
+	// UpdateCardAndBalance stores the card, adds delta to the merchant balance in a single statement and records
+	// the transactions. A negative delta larger than the balance fails with ErrInsufficientFunds
+	UpdateCardAndBalance(ctx context.Context, card *entity.Card, merchantID uint, delta float64, transactions ...entity.BalanceTransaction) error
+	List(ctx context.Context, filter PaymentFilter) ([]entity.Payment, error)
+	Search(ctx context.Context, search PaymentSearch) ([]entity.Payment, error)
+}
+
+type ExportRepository interface {
+	Create(ctx context.Context, export *entity.Export) error
+	Update(ctx context.Context, export *entity.Export) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Export, error)
+	// Refunds the refund transactions of the merchant created in [from, to) with an id greater than afterID,
+	// ordered by id
+	Refunds(ctx context.Context, merchantID uint, from, to time.Time, afterID uint, limit int) ([]entity.BalanceTransaction, error)
+	// FailAbandoned fails the exports still pending or running that were created before the given time
+	FailAbandoned(ctx context.Context, before time.Time, reason string) (int64, error)
+}
+
+type SettlementRepository interface {
+	MerchantsWithAvailableFunds(ctx context.Context, asOf time.Time) ([]uint, error)
+	AvailableTransactions(ctx context.Context, merchantID uint, asOf time.Time) ([]entity.BalanceTransaction, error)
+	PayoutExists(ctx context.Context, merchantID uint, batchDate time.Time) (bool, error)
+	CreatePayout(ctx context.Context, payout *entity.Payout, transactions []entity.BalanceTransaction) error
+	ListPayouts(ctx context.Context, merchantID uint, limit int) ([]entity.Payout, error)
+	Balance(ctx context.Context, merchantID uint, asOf time.Time) (*entity.MerchantBalance, error)
+}
+
+type BankAccountRepository interface {
+	Create(ctx context.Context, account *entity.BankAccount) error
+	Update(ctx context.Context, account *entity.BankAccount) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.BankAccount, error)
+	ListByMerchant(ctx context.Context, merchantID uint) ([]entity.BankAccount, error)
+	GetDefault(ctx context.Context, merchantID uint) (*entity.BankAccount, error)
+	SetDefault(ctx context.Context, account *entity.BankAccount) error
+	Delete(ctx context.Context, account *entity.BankAccount) error
+	// ListNotEncryptedWith the accounts whose number was encrypted with another key than keyID
+	ListNotEncryptedWith(ctx context.Context, keyID string) ([]entity.BankAccount, error)
+}
+
+type PricingRepository interface {
+	GetByMerchant(ctx context.Context, merchantID uint) (*entity.PricingPlan, error)
+	Save(ctx context.Context, plan *entity.PricingPlan) error
+}
+
+type ReserveRepository interface {
+	GetPolicy(ctx context.Context, merchantID uint) (*entity.ReservePolicy, error)
+	SavePolicy(ctx context.Context, policy *entity.ReservePolicy) error
+	CreateHold(ctx context.Context, hold *entity.BalanceHold, transaction entity.BalanceTransaction) error
+	GetHold(ctx context.Context, id uuid.UUID) (*entity.BalanceHold, error)
+	ReleaseHold(ctx context.Context, hold *entity.BalanceHold, transaction entity.BalanceTransaction) error
+	ListHolds(ctx context.Context, merchantID uint) ([]entity.BalanceHold, error)
+}
+
+type DisputeRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Dispute, error)
+	GetByPaymentID(ctx context.Context, paymentID uuid.UUID) (*entity.Dispute, error)
+	List(ctx context.Context, merchantID uint, status entity.DisputeStatusEnum) ([]entity.Dispute, error)
+	Open(ctx context.Context, dispute *entity.Dispute, payment *entity.Payment, transaction entity.BalanceTransaction) error
+	// Transition stores the new status of a dispute still in the from status, with the new state of the payment when
+	// given and the ledger lines, atomically. It fails with ErrStatusChanged when the dispute left the from status
+	Transition(ctx context.Context, dispute *entity.Dispute, from entity.DisputeStatusEnum, payment *entity.Payment,
+		transactions ...entity.BalanceTransaction) error
+	AddEvidence(ctx context.Context, evidence *entity.DisputeEvidence) error
+	Overdue(ctx context.Context, asOf time.Time) ([]entity.Dispute, error)
+}
+
+type RiskRepository interface {
+	CreateAttempt(ctx context.Context, attempt *entity.PaymentAttempt) error
+	CountFailedAttempts(ctx context.Context, cardFingerprint string, since time.Time) (int64, error)
+}
+
+type RiskListRepository interface {
+	Create(ctx context.Context, entry *entity.RiskListEntry) error
+	Delete(ctx context.Context, id uuid.UUID, merchantID uint) error
+	ListByMerchant(ctx context.Context, merchantID uint) ([]entity.RiskListEntry, error)
+	CountByMerchant(ctx context.Context, merchantID uint) (int64, error)
+}
+
+type ReviewRepository interface {
+	Create(ctx context.Context, review *entity.Review) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.Review, error)
+	List(ctx context.Context, status entity.ReviewStatusEnum, limit int) ([]entity.Review, error)
+	Decide(ctx context.Context, review *entity.Review) error
+	Update(ctx context.Context, review *entity.Review) error
+	Overdue(ctx context.Context, asOf time.Time) ([]entity.Review, error)
+}
+
+// VelocityStore sliding window counters, every Add records a value under the key that is summed by the
+// windows starting before it until it expires
+type VelocityStore interface {
+	Add(ctx context.Context, key string, value float64, at time.Time, ttl time.Duration) error
+	Sum(ctx context.Context, key string, since time.Time) (float64, error)
+	Prune(ctx context.Context, asOf time.Time) error
+}
+
+// Acquirer the card network payments are sent to, it moves the funds of the card and reports the
+// chargebacks raised later by cardholders
+type Acquirer interface {
+	Capture(ctx context.Context, payment *entity.Payment, card *entity.Card) AcquirerResponse
+	Refund(ctx context.Context, payment *entity.Payment, card *entity.Card) AcquirerResponse
+	// Authenticate starts the 3-D Secure authentication of the cardholder, a challenge must be completed
+	// with VerifyChallenge before the payment is captured
+	Authenticate(ctx context.Context, payment *entity.Payment, card *entity.Card) ThreeDSResult
+	VerifyChallenge(ctx context.Context, transactionID, code string) bool
+	// Disputes the chargebacks notified and not acknowledged yet, they are returned again until AckDispute
+	Disputes() []DisputeNotice
+	// AckDispute acknowledges the chargeback of the payment once it is recorded
+	AckDispute(paymentID uuid.UUID)
+}
+
+// AcquirerResponse result of an operation sent to the acquirer, DeclineCode explains a declined one
+type AcquirerResponse struct {
+	Approved    bool
+	DeclineCode string
+}
+
+// ThreeDSResult outcome of the 3-D Secure authentication, TransactionID identifies a pending challenge
+type ThreeDSResult struct {
+	Status        entity.ThreeDSStatusEnum
+	TransactionID string
+}
+
+// DisputeNotice chargeback notified by the acquirer
+type DisputeNotice struct {
+	PaymentID  uuid.UUID
+	Reason     string
+	NotifiedAt time.Time
+}
+
+// FileStore keeps generated files and uploads, names are relative to the store root
+type FileStore interface {
+	Create(name string) (io.WriteCloser, error)
+	Open(name string) (io.ReadCloser, error)
+}
+
+const (
+	SortByCreatedAt = "created_at"
+	SortByAmount    = "amount"
+)
+
+// PaymentFilter criteria of a merchant payment listing, results are sorted by SortBy and the payment id
+// and paginated with a keyset cursor pointing to the last row of the previous page
+type PaymentFilter struct {
+	MerchantID uint
+	State      entity.StateEnum
+	MinAmount  *float64
+	MaxAmount  *float64
+	From       *time.Time
+	To         *time.Time
+	CardLast4  string
+	Customer   string
+	SortBy     string
+	Descending bool
+	After      *PaymentCursor
+	Limit      int
+}
+
+// PaymentCursor position of the last payment returned in the previous page, with the sort it was returned in
+type PaymentCursor struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Amount     float64   `json:"amount"`
+	SortBy     string    `json:"sort_by"`
+	Descending bool      `json:"descending"`
+}
+
+// PaymentSearch full-text terms matched against the customer, description, reference and metadata values,
+// Metadata keeps only the payments containing every given key/value pair
+type PaymentSearch struct {
+	MerchantID uint
+	Terms      []string
+	Metadata   map[string]string
+	Limit      int
 }
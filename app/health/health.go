@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/worker"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDegraded = "degraded"
+	StatusPending  = "pending"
+)
+
+const pingTimeout = 2 * time.Second
+
+// Check result of a single dependency check of the readiness report
+type Check struct {
+	Status string          `json:"status"`
+	Error  string          `json:"error,omitempty"`
+	Jobs   []worker.Status `json:"jobs,omitempty"`
+}
+
+// Report readiness of the service and of each dependency
+type Report struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks"`
+}
+
+// Monitor tracks the startup of the service and checks its dependencies. The service is ready once the
+// migrations completed and while the database answers, failing workers degrade it without taking it out
+// of the traffic
+type Monitor struct {
+	db      *gorm.DB
+	workers *worker.Runner
+
+	mu           sync.RWMutex
+	migrated     bool
+	migrationErr error
+}
+
+func NewMonitor(db *gorm.DB, workers *worker.Runner) *Monitor {
+	return &Monitor{db: db, workers: workers}
+}
+
+// MigrationsFinished records the result of the startup migrations
+func (m *Monitor) MigrationsFinished(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.migrated, m.migrationErr = err == nil, err
+}
+
+func (m *Monitor) migrations() Check {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	switch {
+	case m.migrationErr != nil:
+		return Check{Status: StatusDown, Error: m.migrationErr.Error()}
+	case !m.migrated:
+		return Check{Status: StatusPending}
+	default:
+		return Check{Status: StatusUp}
+	}
+}
+
+func (m *Monitor) database(ctx context.Context) Check {
+	sqlDB, err := m.db.DB()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+		defer cancel()
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		return Check{Status: StatusDown, Error: err.Error()}
+	}
+	return Check{Status: StatusUp}
+}
+
+func (m *Monitor) jobs(now time.Time) Check {
+	check := Check{Status: StatusUp, Jobs: m.workers.Statuses()}
+	for _, job := range check.Jobs {
+		if job.LastError != "" || job.Stalled(now) {
+			check.Status = StatusDegraded
+		}
+	}
+	return check
+}
+
+// Ready reports the readiness of the service with the detail of every check
+func (m *Monitor) Ready(ctx context.Context) (bool, Report) {
+	report := Report{Status: StatusUp, Checks: map[string]Check{
+		"migrations": m.migrations(),
+		"database":   m.database(ctx),
+		"workers":    m.jobs(time.Now()),
+	}}
+	ready := report.Checks["migrations"].Status == StatusUp && report.Checks["database"].Status == StatusUp
+	switch {
+	case !ready:
+		report.Status = StatusDown
+	case report.Checks["workers"].Status != StatusUp:
+		report.Status = StatusDegraded
+	}
+	return ready, report
+}
+
+// Gate rejects the requests with 503 until the migrations completed, the excluded paths are always served
+func (m *Monitor) Gate(excluded ...string) echo.MiddlewareFunc {
+	skip := map[string]bool{}
+	for _, path := range excluded {
+		skip[path] = true
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if skip[c.Path()] || m.migrations().Status == StatusUp {
+				return next(c)
+			}
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{"message": "service is starting"})
+		}
+	}
+}
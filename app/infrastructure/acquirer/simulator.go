@@ -0,0 +1,124 @@
+package acquirer
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+)
+
+// Test cards with a fixed behaviour on the simulator, any other card is charged against its balance
+const (
+	// DisputeCard is captured and then disputed by the cardholder as fraudulent
+	DisputeCard = "4000000000000259"
+	// DisputeProductCard is captured and then disputed as a product not received
+	DisputeProductCard = "4000000000002685"
+	// ChallengeCard requires the cardholder to complete a 3-D Secure challenge
+	ChallengeCard = "4000000000003220"
+	// FrictionlessCard is authenticated by the issuer without a challenge
+	FrictionlessCard = "4000000000003063"
+	// AuthenticationFailedCard fails the 3-D Secure authentication
+	AuthenticationFailedCard = "4000000000003097"
+	// ChallengeCode the one-time code accepted by the simulated ACS challenge page
+	ChallengeCode = "1234"
+)
+
+const declineInsufficientFunds = "insufficient_funds"
+
+var disputeReasons = map[string]string{
+	DisputeCard:        "fraudulent",
+	DisputeProductCard: "product_not_received",
+}
+
+type simulator struct {
+	logger     *slog.Logger
+	mu         sync.Mutex
+	disputes   []repository.DisputeNotice
+	challenges map[string]bool
+}
+
+// NewSimulator acquirer keeping the funds on the card balance, used on every environment until
+// a real acquirer is integrated
+func NewSimulator(logger *slog.Logger) repository.Acquirer {
+	return &simulator{logger: logger, challenges: map[string]bool{}}
+}
+
+// Capture charges the card, it is declined when the card balance doesn't cover the amount. Dispute test
+// cards are always captured and their chargeback is notified by Disputes until it is acknowledged
+func (s *simulator) Capture(_ context.Context, payment *entity.Payment, card *entity.Card) repository.AcquirerResponse {
+	reason, disputed := disputeReasons[card.Number]
+	if card.Balance < payment.Amount && !disputed {
+		return repository.AcquirerResponse{DeclineCode: declineInsufficientFunds}
+	}
+	card.Balance = card.Balance - payment.Amount
+
+	if disputed {
+		s.mu.Lock()
+		s.disputes = append(s.disputes, repository.DisputeNotice{
+			PaymentID:  payment.ID,
+			Reason:     reason,
+			NotifiedAt: time.Now(),
+		})
+		s.mu.Unlock()
+		s.logger.Info("simulated dispute raised", "payment_id", payment.ID, "reason", reason)
+	}
+	return repository.AcquirerResponse{Approved: true}
+}
+
+// Refund gives the amount back to the card
+func (s *simulator) Refund(_ context.Context, payment *entity.Payment, card *entity.Card) repository.AcquirerResponse {
+	card.Balance = card.Balance + payment.Amount
+	return repository.AcquirerResponse{Approved: true}
+}
+
+// Authenticate the 3-D Secure outcome is fixed by the test cards, any other card is not enrolled
+func (s *simulator) Authenticate(_ context.Context, payment *entity.Payment, card *entity.Card) repository.ThreeDSResult {
+	switch card.Number {
+	case FrictionlessCard:
+		return repository.ThreeDSResult{Status: entity.ThreeDSAuthenticated}
+	case AuthenticationFailedCard:
+		return repository.ThreeDSResult{Status: entity.ThreeDSFailed}
+	case ChallengeCard:
+		transactionID := uuid.NewString()
+		s.mu.Lock()
+		s.challenges[transactionID] = true
+		s.mu.Unlock()
+		s.logger.Info("simulated 3DS challenge started", "payment_id", payment.ID, "transaction_id", transactionID)
+		return repository.ThreeDSResult{Status: entity.ThreeDSChallengeRequired, TransactionID: transactionID}
+	default:
+		return repository.ThreeDSResult{Status: entity.ThreeDSNotEnrolled}
+	}
+}
+
+// VerifyChallenge a challenge can be answered once, it succeeds with ChallengeCode
+func (s *simulator) VerifyChallenge(_ context.Context, transactionID, code string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.challenges[transactionID] {
+		return false
+	}
+	delete(s.challenges, transactionID)
+	return subtle.ConstantTimeCompare([]byte(code), []byte(ChallengeCode)) == 1
+}
+
+func (s *simulator) Disputes() []repository.DisputeNotice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]repository.DisputeNotice(nil), s.disputes...)
+}
+
+func (s *simulator) AckDispute(paymentID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, notice := range s.disputes {
+		if notice.PaymentID == paymentID {
+			s.disputes = append(s.disputes[:i], s.disputes[i+1:]...)
+			return
+		}
+	}
+}
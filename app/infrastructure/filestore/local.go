@@ -0,0 +1,51 @@
+package filestore
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+)
+
+type localFileStore struct {
+	root string
+}
+
+// NewLocalFileStore stores files under the root directory of the local disk, creating it when missing
+func NewLocalFileStore(root string) (repository.FileStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, err
+	}
+	return &localFileStore{root: root}, nil
+}
+
+func (l *localFileStore) Create(name string) (io.WriteCloser, error) {
+	path, err := l.path(name)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o640)
+}
+
+func (l *localFileStore) Open(name string) (io.ReadCloser, error) {
+	path, err := l.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// path resolves the name inside the root, rejecting names escaping it
+func (l *localFileStore) path(name string) (string, error) {
+	path := filepath.Join(l.root, filepath.Clean("/"+name))
+	if !strings.HasPrefix(path, filepath.Clean(l.root)+string(os.PathSeparator)) {
+		return "", errors.New("invalid file name")
+	}
+	return path, nil
+}
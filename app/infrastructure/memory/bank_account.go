@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type bankAccountRepo struct {
+	db *Database
+}
+
+func NewBankAccountRepository(db *Database) repository.BankAccountRepository {
+	return &bankAccountRepo{db: db}
+}
+
+func (b *bankAccountRepo) Create(_ context.Context, account *entity.BankAccount) error {
+	b.db.mu.Lock()
+	defer b.db.mu.Unlock()
+	b.db.bankAccounts[account.ID] = *account
+	return nil
+}
+
+func (b *bankAccountRepo) Update(_ context.Context, account *entity.BankAccount) error {
+	b.db.mu.Lock()
+	defer b.db.mu.Unlock()
+	b.db.bankAccounts[account.ID] = *account
+	return nil
+}
+
+func (b *bankAccountRepo) GetByID(_ context.Context, id uuid.UUID) (*entity.BankAccount, error) {
+	b.db.mu.Lock()
+	defer b.db.mu.Unlock()
+	account, ok := b.db.bankAccounts[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &account, nil
+}
+
+func (b *bankAccountRepo) ListByMerchant(_ context.Context, merchantID uint) ([]entity.BankAccount, error) {
+	return b.list(func(account entity.BankAccount) bool { return account.MerchantID == merchantID }), nil
+}
+
+func (b *bankAccountRepo) GetDefault(_ context.Context, merchantID uint) (*entity.BankAccount, error) {
+	accounts := b.list(func(account entity.BankAccount) bool {
+		return account.MerchantID == merchantID && account.IsDefault && account.Status == entity.BankAccountVerified
+	})
+	if len(accounts) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &accounts[0], nil
+}
+
+// SetDefault marks the account as the merchant default unsetting the previous one
+func (b *bankAccountRepo) SetDefault(_ context.Context, account *entity.BankAccount) error {
+	b.db.mu.Lock()
+	defer b.db.mu.Unlock()
+	for id, stored := range b.db.bankAccounts {
+		if stored.MerchantID == account.MerchantID && id != account.ID && stored.IsDefault {
+			stored.IsDefault = false
+			b.db.bankAccounts[id] = stored
+		}
+	}
+	account.IsDefault = true
+	b.db.bankAccounts[account.ID] = *account
+	return nil
+}
+
+func (b *bankAccountRepo) Delete(_ context.Context, account *entity.BankAccount) error {
+	b.db.mu.Lock()
+	defer b.db.mu.Unlock()
+	delete(b.db.bankAccounts, account.ID)
+	return nil
+}
+
+func (b *bankAccountRepo) ListNotEncryptedWith(_ context.Context, keyID string) ([]entity.BankAccount, error) {
+	return b.list(func(account entity.BankAccount) bool {
+		return !strings.HasPrefix(account.AccountNumberEncrypted, keyID+":")
+	}), nil
+}
+
+// list the accounts matching the filter, oldest first like the postgres repository
+func (b *bankAccountRepo) list(match func(entity.BankAccount) bool) []entity.BankAccount {
+	b.db.mu.Lock()
+	defer b.db.mu.Unlock()
+	var accounts []entity.BankAccount
+	for _, account := range b.db.bankAccounts {
+		if match(account) {
+			accounts = append(accounts, account)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].CreatedAt.Before(accounts[j].CreatedAt) })
+	return accounts
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/google/uuid"
+)
+
+// Database the records shared by the in-memory repositories, the counterpart of the gorm connection the
+// postgres repositories are built with. Records are copied in and out so callers never share them
+type Database struct {
+	mu           sync.Mutex
+	sequence     uint
+	merchants    map[uint]entity.Merchant
+	loginEvents  []entity.LoginEvent
+	payments     map[uuid.UUID]entity.Payment
+	cards        map[string]entity.Card
+	transactions []entity.BalanceTransaction
+	bankAccounts map[uuid.UUID]entity.BankAccount
+}
+
+// NewDatabase an empty database, it lives as long as the process and isn't shared between instances
+func NewDatabase() *Database {
+	return &Database{
+		merchants:    map[uint]entity.Merchant{},
+		payments:     map[uuid.UUID]entity.Payment{},
+		cards:        map[string]entity.Card{},
+		bankAccounts: map[uuid.UUID]entity.BankAccount{},
+	}
+}
+
+// LoginEvents the login events recorded so far, oldest first
+func (d *Database) LoginEvents() []entity.LoginEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]entity.LoginEvent(nil), d.loginEvents...)
+}
+
+// BalanceTransactions the balance transactions recorded so far, oldest first
+func (d *Database) BalanceTransactions() []entity.BalanceTransaction {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]entity.BalanceTransaction(nil), d.transactions...)
+}
+
+// nextID the auto increment shared by every table, ids are unique across the database
+func (d *Database) nextID() uint {
+	d.sequence++
+	return d.sequence
+}
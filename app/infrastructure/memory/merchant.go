@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type merchantRepo struct {
+	db *Database
+}
+
+func NewMerchantRepository(db *Database) repository.MerchantRepository {
+	return &merchantRepo{db: db}
+}
+
+func (m *merchantRepo) Create(_ context.Context, merchant *entity.Merchant) (*entity.Merchant, error) {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+	for _, existing := range m.db.merchants {
+		if existing.Name == merchant.Name {
+			return nil, repository.ErrDuplicated
+		}
+	}
+	m.db.saveMerchant(merchant)
+	return merchant, nil
+}
+
+func (m *merchantRepo) GetByName(_ context.Context, name string) (*entity.Merchant, error) {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+	for _, merchant := range m.db.merchants {
+		if merchant.Name == name {
+			merchant = cloneMerchant(merchant)
+			return &merchant, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *merchantRepo) Update(_ context.Context, merchant *entity.Merchant) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+	stored, ok := m.db.merchants[merchant.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	login := cloneMerchant(*merchant)
+	stored.FailedLoginAttempts, stored.LockoutCount, stored.LockedUntil = login.FailedLoginAttempts, login.LockoutCount,
+		login.LockedUntil
+	stored.TOTPSecret, stored.TOTPEnabled, stored.UpdatedAt = login.TOTPSecret, login.TOTPEnabled, time.Now()
+	m.db.merchants[merchant.ID] = stored
+	return nil
+}
+
+func (m *merchantRepo) RegisterFailedLogin(_ context.Context, merchantID uint) (int, int, error) {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+	stored, ok := m.db.merchants[merchantID]
+	if !ok {
+		return 0, 0, gorm.ErrRecordNotFound
+	}
+	stored.FailedLoginAttempts++
+	stored.UpdatedAt = time.Now()
+	m.db.merchants[merchantID] = stored
+	return stored.FailedLoginAttempts, stored.LockoutCount, nil
+}
+
+func (m *merchantRepo) Lock(_ context.Context, merchantID uint, maxAttempts int, lockedUntil time.Time) (bool, error) {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+	stored, ok := m.db.merchants[merchantID]
+	if !ok || stored.FailedLoginAttempts < maxAttempts {
+		return false, nil
+	}
+	stored.FailedLoginAttempts, stored.LockedUntil, stored.UpdatedAt = 0, &lockedUntil, time.Now()
+	stored.LockoutCount++
+	m.db.merchants[merchantID] = stored
+	return true, nil
+}
+
+func (m *merchantRepo) UseTOTPStep(_ context.Context, merchantID uint, step int64) (bool, error) {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+	stored, ok := m.db.merchants[merchantID]
+	if !ok || stored.TOTPLastStep >= step {
+		return false, nil
+	}
+	stored.TOTPLastStep = step
+	m.db.merchants[merchantID] = stored
+	return true, nil
+}
+
+func (m *merchantRepo) UpdateSuspension(_ context.Context, merchant *entity.Merchant) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+	stored, ok := m.db.merchants[merchant.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	suspended := cloneMerchant(*merchant)
+	stored.SuspendedAt, stored.SuspensionReason, stored.UpdatedAt = suspended.SuspendedAt, merchant.SuspensionReason,
+		merchant.UpdatedAt
+	m.db.merchants[merchant.ID] = stored
+	return nil
+}
+
+func (m *merchantRepo) CreateLoginEvent(_ context.Context, event *entity.LoginEvent) error {
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+	event.ID = m.db.nextID()
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	m.db.loginEvents = append(m.db.loginEvents, *event)
+	return nil
+}
+
+// saveMerchant inserts or replaces the merchant like gorm Save, the payments association is not stored
+func (d *Database) saveMerchant(merchant *entity.Merchant) {
+	if merchant.ID == 0 {
+		merchant.ID = d.nextID()
+	}
+	now := time.Now()
+	if merchant.CreatedAt.IsZero() {
+		merchant.CreatedAt = now
+	}
+	merchant.UpdatedAt = now
+	d.merchants[merchant.ID] = cloneMerchant(*merchant)
+}
+
+func cloneMerchant(merchant entity.Merchant) entity.Merchant {
+	merchant.Payments = nil
+	if merchant.LockedUntil != nil {
+		lockedUntil := *merchant.LockedUntil
+		merchant.LockedUntil = &lockedUntil
+	}
+	if merchant.SuspendedAt != nil {
+		suspendedAt := *merchant.SuspendedAt
+		merchant.SuspendedAt = &suspendedAt
+	}
+	return merchant
+}
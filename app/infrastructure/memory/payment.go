@@ -0,0 +1,301 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type paymentRepo struct {
+	db *Database
+}
+
+func NewPaymentRepository(db *Database) repository.PaymentRepository {
+	return &paymentRepo{db: db}
+}
+
+func (p *paymentRepo) Create(_ context.Context, payment *entity.Payment) error {
+	p.db.mu.Lock()
+	defer p.db.mu.Unlock()
+	if _, ok := p.db.payments[payment.ID]; ok {
+		return repository.ErrDuplicated
+	}
+	now := time.Now()
+	if payment.CreatedAt.IsZero() {
+		payment.CreatedAt = now
+	}
+	if payment.UpdatedAt.IsZero() {
+		payment.UpdatedAt = now
+	}
+	p.db.saveFeeLines(payment)
+	p.db.payments[payment.ID] = clonePayment(*payment)
+	return nil
+}
+
+// Update saves the payment like gorm Save, the states are added to the stored history and the new fee lines created
+func (p *paymentRepo) Update(_ context.Context, payment *entity.Payment) (*entity.Payment, error) {
+	p.db.mu.Lock()
+	defer p.db.mu.Unlock()
+	stored := p.db.payments[payment.ID]
+	states := stored.States
+	for _, state := range payment.States {
+		if !hasState(states, state) {
+			states = append(states, state)
+		}
+	}
+	payment.UpdatedAt = time.Now()
+	p.db.saveFeeLines(payment)
+	saved := clonePayment(*payment)
+	saved.States = append([]entity.State(nil), states...)
+	p.db.payments[payment.ID] = saved
+
+	updatedPayment := clonePayment(saved)
+	return &updatedPayment, nil
+}
+
+func (p *paymentRepo) GetByID(_ context.Context, id uuid.UUID) (*entity.Payment, error) {
+	p.db.mu.Lock()
+	defer p.db.mu.Unlock()
+	payment, ok := p.db.payments[id]
+	if !ok {
+		return &entity.Payment{}, gorm.ErrRecordNotFound
+	}
+	payment = clonePayment(payment)
+	return &payment, nil
+}
+
+// CreateCard keeps the stored card when one with the same number exists, like the postgres repository
+func (p *paymentRepo) CreateCard(_ context.Context, card *entity.Card) error {
+	p.db.mu.Lock()
+	defer p.db.mu.Unlock()
+	if _, ok := p.db.cards[card.Number]; ok {
+		return nil
+	}
+	card.ID = p.db.nextID()
+	p.db.cards[card.Number] = *card
+	return nil
+}
+
+func (p *paymentRepo) GetCardByNumber(_ context.Context, number string) (*entity.Card, error) {
+	p.db.mu.Lock()
+	defer p.db.mu.Unlock()
+	card, ok := p.db.cards[number]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &card, nil
+}
+
+func (p *paymentRepo) GetMerchantByID(_ context.Context, id uint) (*entity.Merchant, error) {
+	p.db.mu.Lock()
+	defer p.db.mu.Unlock()
+	merchant, ok := p.db.merchants[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	merchant = cloneMerchant(merchant)
+	return &merchant, nil
+}
+
+func (p *paymentRepo) UpdateCardAndBalance(_ context.Context, card *entity.Card, merchantID uint, delta float64, transactions ...entity.BalanceTransaction) error {
+	p.db.mu.Lock()
+	defer p.db.mu.Unlock()
+	merchant, ok := p.db.merchants[merchantID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if delta < 0 && merchant.Balance < -delta {
+		return repository.ErrInsufficientFunds
+	}
+	merchant.Balance += delta
+	p.db.merchants[merchantID] = merchant
+	if card.ID == 0 {
+		card.ID = p.db.nextID()
+	}
+	p.db.cards[card.Number] = *card
+	for _, transaction := range transactions {
+		transaction.ID = p.db.nextID()
+		if transaction.CreatedAt.IsZero() {
+			transaction.CreatedAt = time.Now()
+		}
+		p.db.transactions = append(p.db.transactions, transaction)
+	}
+	return nil
+}
+
+func (p *paymentRepo) List(_ context.Context, filter repository.PaymentFilter) ([]entity.Payment, error) {
+	p.db.mu.Lock()
+	defer p.db.mu.Unlock()
+
+	column := repository.SortByCreatedAt
+	if filter.SortBy == repository.SortByAmount {
+		column = repository.SortByAmount
+	}
+	// compare orders two payments by the sort column and then by id, the same keyset the postgres query uses
+	compare := func(a, b entity.Payment) int {
+		switch {
+		case column == repository.SortByAmount && a.Amount != b.Amount:
+			if a.Amount < b.Amount {
+				return -1
+			}
+			return 1
+		case column == repository.SortByCreatedAt && !a.CreatedAt.Equal(b.CreatedAt):
+			return a.CreatedAt.Compare(b.CreatedAt)
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	}
+	if filter.Descending {
+		ascending := compare
+		compare = func(a, b entity.Payment) int { return ascending(b, a) }
+	}
+
+	var after *entity.Payment
+	if filter.After != nil {
+		after = &entity.Payment{ID: filter.After.ID, CreatedAt: filter.After.CreatedAt, Amount: filter.After.Amount}
+	}
+
+	var payments []entity.Payment
+	for _, payment := range p.db.payments {
+		if payment.MerchantID != filter.MerchantID || !matchesFilter(payment, filter) {
+			continue
+		}
+		if after != nil && compare(payment, *after) <= 0 {
+			continue
+		}
+		payments = append(payments, clonePayment(payment))
+	}
+	sort.Slice(payments, func(i, j int) bool { return compare(payments[i], payments[j]) < 0 })
+	if filter.Limit > 0 && len(payments) > filter.Limit {
+		payments = payments[:filter.Limit]
+	}
+	return payments, nil
+}
+
+// Search matches every term as a prefix of a word of the customer, description, reference or metadata values.
+// The payments are returned newest first, the text rank of the postgres search isn't reproduced
+func (p *paymentRepo) Search(_ context.Context, search repository.PaymentSearch) ([]entity.Payment, error) {
+	p.db.mu.Lock()
+	defer p.db.mu.Unlock()
+
+	var payments []entity.Payment
+	for _, payment := range p.db.payments {
+		if payment.MerchantID != search.MerchantID || !containsMetadata(payment.Metadata, search.Metadata) ||
+			!matchesTerms(payment, search.Terms) {
+			continue
+		}
+		payment = clonePayment(payment)
+		payment.FeeLines = nil
+		payments = append(payments, payment)
+	}
+	sort.Slice(payments, func(i, j int) bool { return payments[i].CreatedAt.After(payments[j].CreatedAt) })
+	if search.Limit > 0 && len(payments) > search.Limit {
+		payments = payments[:search.Limit]
+	}
+	return payments, nil
+}
+
+// saveFeeLines assigns an id to the new fee lines of the payment, as creating the association does
+func (d *Database) saveFeeLines(payment *entity.Payment) {
+	for i := range payment.FeeLines {
+		line := &payment.FeeLines[i]
+		if line.ID == 0 {
+			line.ID = d.nextID()
+		}
+		line.PaymentID = payment.ID
+		if line.CreatedAt.IsZero() {
+			line.CreatedAt = time.Now()
+		}
+	}
+}
+
+func matchesFilter(payment entity.Payment, filter repository.PaymentFilter) bool {
+	switch {
+	case filter.State != "" && payment.Status != filter.State:
+		return false
+	case filter.MinAmount != nil && payment.Amount < *filter.MinAmount:
+		return false
+	case filter.MaxAmount != nil && payment.Amount > *filter.MaxAmount:
+		return false
+	case filter.From != nil && payment.CreatedAt.Before(*filter.From):
+		return false
+	case filter.To != nil && !payment.CreatedAt.Before(*filter.To):
+		return false
+	case filter.CardLast4 != "" && payment.CardLast4 != filter.CardLast4:
+		return false
+	}
+	if filter.Customer != "" {
+		if personalID, err := strconv.ParseUint(filter.Customer, 10, 64); err == nil {
+			return uint64(payment.CustomerPersonalID) == personalID
+		}
+		return strings.Contains(strings.ToLower(payment.CustomerName), strings.ToLower(filter.Customer))
+	}
+	return true
+}
+
+func containsMetadata(metadata entity.Metadata, pairs map[string]string) bool {
+	for key, value := range pairs {
+		if stored, ok := metadata[key]; !ok || stored != value {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesTerms(payment entity.Payment, terms []string) bool {
+	text := []string{payment.Description, payment.Reference, payment.CustomerName}
+	if payment.CustomerPersonalID != 0 {
+		text = append(text, strconv.FormatUint(uint64(payment.CustomerPersonalID), 10))
+	}
+	for _, value := range payment.Metadata {
+		text = append(text, value)
+	}
+	words := strings.FieldsFunc(strings.ToLower(strings.Join(text, " ")), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, term := range terms {
+		matched := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func hasState(states []entity.State, state entity.State) bool {
+	for _, s := range states {
+		if s.ID == state.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// clonePayment copies the slices and maps of the payment so the stored record isn't changed through it
+func clonePayment(payment entity.Payment) entity.Payment {
+	payment.States = append([]entity.State(nil), payment.States...)
+	payment.FeeLines = append([]entity.FeeLine(nil), payment.FeeLines...)
+	payment.RiskRules = append(entity.StringList(nil), payment.RiskRules...)
+	if payment.Metadata != nil {
+		metadata := make(entity.Metadata, len(payment.Metadata))
+		for key, value := range payment.Metadata {
+			metadata[key] = value
+		}
+		payment.Metadata = metadata
+	}
+	return payment
+}
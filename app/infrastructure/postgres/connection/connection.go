@@ -1,8 +1,12 @@
 package connection
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alvarezcarlos/payment/app/config"
@@ -11,43 +15,117 @@ import (
 )
 
 const (
-	maxRetries = 5
+	pingTimeout  = 2 * time.Second
+	maxRetryWait = 30 * time.Second
 )
 
-type PostgresRepository interface {
+// Connection the database of the configured driver, GetConnection opens it lazily
+type Connection interface {
 	GetConnection() *gorm.DB
+	WaitUntilAvailable(ctx context.Context) error
 }
 
 type PostgresConnection struct {
 	url        string
+	settings   config.DBConfig
 	config     *gorm.Config
 	connection *gorm.DB
 	logger     *slog.Logger
 }
 
-func NewPostgresConnection(conf *gorm.Config, logger *slog.Logger) *PostgresConnection {
-	var dbConfig = config.Config().Database
-	url := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d", dbConfig.Host, dbConfig.Username, dbConfig.Password, dbConfig.Name, dbConfig.Port)
+func NewPostgresConnection(dbConfig config.DBConfig, conf *gorm.Config, logger *slog.Logger) *PostgresConnection {
 	return &PostgresConnection{
-		url:        url,
+		url:        dsn(dbConfig, dbConfig.Host, strconv.Itoa(dbConfig.Port)),
+		settings:   dbConfig,
 		connection: nil,
 		config:     conf,
 		logger:     logger,
 	}
 }
-func (pg *PostgresConnection) GetConnection() *gorm.DB {
-	var db *gorm.DB
-	var err error
-
-	for i := 1; i <= maxRetries; i++ {
-		db, err = gorm.Open(postgres.Open(pg.url), pg.config)
-		if err != nil {
-			pg.logger.Error(fmt.Errorf("%w, attempt %d of %d", err, i, maxRetries).Error())
-			time.Sleep(3 * time.Second)
+
+// dsn the libpq connection string of a server, values are quoted so passwords can hold any character. The
+// statement timeout is sent as a session parameter of every connection. libpq counts the connect timeout in whole
+// seconds and waits forever on 0, so it is rounded up
+func dsn(dbConfig config.DBConfig, host, port string) string {
+	params := [][2]string{
+		{"host", host},
+		{"port", port},
+		{"user", dbConfig.Username},
+		{"password", dbConfig.Password},
+		{"dbname", dbConfig.Name},
+		{"sslmode", dbConfig.SSLMode},
+		{"sslrootcert", dbConfig.SSLRootCert},
+		{"sslcert", dbConfig.SSLCert},
+		{"sslkey", dbConfig.SSLKey},
+		{"connect_timeout", strconv.Itoa(int(math.Ceil(dbConfig.ConnectTimeout.Seconds())))},
+		{"statement_timeout", strconv.FormatInt(dbConfig.StatementTimeout.Milliseconds(), 10)},
+	}
+	var b strings.Builder
+	for _, param := range params {
+		if param[1] == "" {
 			continue
 		}
-		pg.logger.Debug(" =======> db connected")
-		return db
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(param[1])
+		fmt.Fprintf(&b, "%s='%s'", param[0], value)
+	}
+	return b.String()
+}
+
+// GetConnection opens the connection pool without reaching the database, so the service can start serving its
+// health endpoints while the database comes up. WaitUntilAvailable blocks until it is reachable
+func (pg *PostgresConnection) GetConnection() *gorm.DB {
+	if pg.connection != nil {
+		return pg.connection
+	}
+	pg.config.DisableAutomaticPing = true
+	// the repositories detect the uniqueness violations with gorm.ErrDuplicatedKey, whatever the driver
+	pg.config.TranslateError = true
+	db, err := gorm.Open(postgres.Open(pg.url), pg.config)
+	if err != nil {
+		panic(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic(err)
+	}
+	sqlDB.SetMaxOpenConns(pg.settings.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(pg.settings.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(pg.settings.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(pg.settings.ConnMaxIdleTime)
+	if len(pg.settings.Replicas) > 0 {
+		if err = useReplicas(db, pg.settings); err != nil {
+			panic(err)
+		}
+	}
+	pg.connection = db
+	return db
+}
+
+// WaitUntilAvailable pings the database with an exponential backoff until it answers or ctx is done
+func (pg *PostgresConnection) WaitUntilAvailable(ctx context.Context) error {
+	sqlDB, err := pg.GetConnection().DB()
+	if err != nil {
+		return err
+	}
+	wait := time.Second
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err = sqlDB.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			pg.logger.Debug(" =======> db connected")
+			return nil
+		}
+		pg.logger.Error(fmt.Errorf("%w, attempt %d, retrying in %s", err, attempt, wait).Error())
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, maxRetryWait)
 	}
-	panic(err)
 }
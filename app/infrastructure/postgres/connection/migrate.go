@@ -1,28 +1,282 @@
 package connection
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+const (
+	migrationsTable = "schema_migrations"
+	// migrationLockID key of the advisory lock serializing the instances migrating the same database
+	migrationLockID = 7261700145
+)
+
+var (
+	ErrPendingMigrations = errors.New("the database has pending migrations")
+	migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+)
+
+// Migration versioned change of the schema, Up applies it and Down reverts it
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus a known migration and when it was applied, nil for a pending one
+type MigrationStatus struct {
+	Version   uint       `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
 type MigrateInterface interface {
-	AutoMigrateAll(tables ...interface{})
+	// Up applies the pending migrations in version order, each one in its own transaction
+	Up() (int, error)
+	// Down reverts the last steps applied migrations
+	Down(steps int) (int, error)
+	Status() ([]MigrationStatus, error)
+	Pending() ([]MigrationStatus, error)
 }
+
 type migrate struct {
 	connection *gorm.DB
+	migrations []Migration
 	logger     *slog.Logger
 }
 
-func NewMigrate(connection *gorm.DB, logger *slog.Logger) MigrateInterface {
+type appliedMigration struct {
+	Version   uint
+	Name      string
+	AppliedAt time.Time
+}
+
+func NewMigrate(connection *gorm.DB, files fs.FS, logger *slog.Logger) (MigrateInterface, error) {
+	migrations, err := loadMigrations(files)
+	if err != nil {
+		return nil, err
+	}
 	return &migrate{
 		connection: connection,
-		logger:     logger}
+		migrations: migrations,
+		logger:     logger}, nil
+}
+
+func (m *migrate) Up() (int, error) {
+	if err := m.ensureTable(); err != nil {
+		return 0, err
+	}
+	applied := 0
+	for _, migration := range m.migrations {
+		ran, err := m.run(migration, true)
+		if err != nil {
+			return applied, err
+		}
+		if ran {
+			applied++
+			m.logger.Info("migration applied", "version", migration.Version, "name", migration.Name)
+		}
+	}
+	return applied, nil
+}
+
+func (m *migrate) Down(steps int) (int, error) {
+	if err := m.ensureTable(); err != nil {
+		return 0, err
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	known := map[uint]Migration{}
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	reverted := 0
+	for i := len(applied) - 1; i >= 0 && reverted < steps; i-- {
+		migration, ok := known[applied[i].Version]
+		if !ok {
+			return reverted, fmt.Errorf("migration %d is not known by this build", applied[i].Version)
+		}
+		ran, err := m.run(migration, false)
+		if err != nil {
+			return reverted, err
+		}
+		if ran {
+			reverted++
+			m.logger.Info("migration reverted", "version", migration.Version, "name", migration.Name)
+		}
+	}
+	return reverted, nil
+}
+
+// Status lists the known migrations and the applied ones this build doesn't know, by version
+func (m *migrate) Status() ([]MigrationStatus, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*MigrationStatus{}
+	for _, migration := range m.migrations {
+		byVersion[migration.Version] = &MigrationStatus{Version: migration.Version, Name: migration.Name}
+	}
+	for _, record := range applied {
+		appliedAt := record.AppliedAt
+		if status, ok := byVersion[record.Version]; ok {
+			status.AppliedAt = &appliedAt
+		} else {
+			byVersion[record.Version] = &MigrationStatus{Version: record.Version, Name: record.Name, AppliedAt: &appliedAt}
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(byVersion))
+	for _, status := range byVersion {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+func (m *migrate) Pending() ([]MigrationStatus, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+	var pending []MigrationStatus
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status)
+		}
+	}
+	return pending, nil
+}
+
+// run applies or reverts the migration with its bookkeeping in a single transaction, it is skipped when
+// another instance already did it
+func (m *migrate) run(migration Migration, up bool) (bool, error) {
+	ran := false
+	err := m.connection.Transaction(func(tx *gorm.DB) error {
+		// the SQLite transactions already hold the write lock of the whole database file
+		if isPostgres(tx) {
+			// the statement timeout of the service doesn't apply to schema changes nor to waiting for the lock
+			if err := tx.Exec("SET LOCAL statement_timeout = 0").Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockID).Error; err != nil {
+				return err
+			}
+		}
+		var count int64
+		if err := tx.Table(migrationsTable).Where("version = ?", migration.Version).Count(&count).Error; err != nil {
+			return err
+		}
+		if (count > 0) == up {
+			return nil
+		}
+
+		script := migration.Down
+		if up {
+			script = migration.Up
+		}
+		if err := tx.Exec(script).Error; err != nil {
+			return err
+		}
+		var err error
+		if up {
+			err = tx.Exec("INSERT INTO "+migrationsTable+" (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now()).Error
+		} else {
+			err = tx.Exec("DELETE FROM "+migrationsTable+" WHERE version = ?", migration.Version).Error
+		}
+		ran = err == nil
+		return err
+	})
+	if err != nil {
+		m.logger.Error("Error running migration", "version", migration.Version, "name", migration.Name, "error", err.Error())
+		return false, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return ran, nil
+}
+
+func (m *migrate) ensureTable() error {
+	timestampType := "datetime"
+	if isPostgres(m.connection) {
+		timestampType = "timestamptz"
+	}
+	return m.connection.Exec(`CREATE TABLE IF NOT EXISTS ` + migrationsTable + ` (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at ` + timestampType + ` NOT NULL
+	)`).Error
+}
+
+func isPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+func (m *migrate) applied() ([]appliedMigration, error) {
+	var applied []appliedMigration
+	err := m.connection.Table(migrationsTable).Order("version").Find(&applied).Error
+	return applied, err
 }
 
-func (m *migrate) AutoMigrateAll(tables ...interface{}) {
-	err := m.connection.AutoMigrate(tables...)
+// loadMigrations reads the migrations of files sorted by version, every version needs both scripts
+func loadMigrations(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
 	if err != nil {
-		m.logger.Error("Error migrating tables")
+		return nil, err
+	}
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(files, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs an up and a down script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
 	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
 }
@@ -0,0 +1,51 @@
+package connection
+
+import (
+	"net"
+
+	"github.com/alvarezcarlos/payment/app/config"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const primaryCallback = "replicas:primary_by_default"
+
+// useReplicas spreads the reads marked with repository.ReadOnly between the replicas. Every other statement keeps
+// going to the primary, dbresolver alone would send all the reads made outside a transaction to the replicas,
+// including the ones checking for consistency before a write
+func useReplicas(db *gorm.DB, settings config.DBConfig) error {
+	replicas := make([]gorm.Dialector, 0, len(settings.Replicas))
+	for _, replica := range settings.Replicas {
+		host, port, err := net.SplitHostPort(replica)
+		if err != nil {
+			return err
+		}
+		replicas = append(replicas, postgres.Open(dsn(settings, host, port)))
+	}
+	resolver := dbresolver.Register(dbresolver.Config{Replicas: replicas, Policy: dbresolver.RandomPolicy{}}).
+		SetMaxOpenConns(settings.MaxOpenConns).
+		SetMaxIdleConns(settings.MaxIdleConns).
+		SetConnMaxLifetime(settings.ConnMaxLifetime).
+		SetConnMaxIdleTime(settings.ConnMaxIdleTime)
+	if err := db.Use(resolver); err != nil {
+		return err
+	}
+
+	// the resolver callbacks run first of all, registered after them these ones are placed before
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("*").Register(primaryCallback, primaryByDefault); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("*").Register(primaryCallback, primaryByDefault); err != nil {
+		return err
+	}
+	return callbacks.Raw().Before("*").Register(primaryCallback, primaryByDefault)
+}
+
+func primaryByDefault(db *gorm.DB) {
+	if !repository.IsReadOnly(db.Statement.Context) {
+		dbresolver.Write.ModifyStatement(db.Statement)
+	}
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed postgres/*.sql
+var files embed.FS
+
+// Postgres versioned migrations of the postgres schema, named <version>_<name>.up.sql and
+// <version>_<name>.down.sql
+func Postgres() fs.FS {
+	sub, err := fs.Sub(files, "postgres")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,21 @@
+DROP TABLE IF EXISTS "risk_list_entries";
+DROP TABLE IF EXISTS "reviews";
+DROP TABLE IF EXISTS "velocity_events";
+DROP TABLE IF EXISTS "payment_attempts";
+DROP TABLE IF EXISTS "dispute_evidence";
+DROP TABLE IF EXISTS "disputes";
+DROP TABLE IF EXISTS "balance_holds";
+DROP TABLE IF EXISTS "reserve_policies";
+DROP TABLE IF EXISTS "fee_lines";
+DROP TABLE IF EXISTS "pricing_rules";
+DROP TABLE IF EXISTS "pricing_plans";
+DROP TABLE IF EXISTS "bank_accounts";
+DROP TABLE IF EXISTS "payouts";
+DROP TABLE IF EXISTS "balance_transactions";
+DROP TABLE IF EXISTS "exports";
+DROP TABLE IF EXISTS "login_events";
+DROP TABLE IF EXISTS "cards";
+DROP TABLE IF EXISTS "payment_states";
+DROP TABLE IF EXISTS "payments";
+DROP TABLE IF EXISTS "states";
+DROP TABLE IF EXISTS "merchants";
@@ -0,0 +1,317 @@
+-- Schema of the platform as created by the former gorm auto migration, the statements are idempotent so
+-- databases migrated by it are baselined to this version
+
+CREATE TABLE IF NOT EXISTS "merchants" (
+    "id" bigserial,
+    "name" text,
+    "balance" decimal,
+    "password" text,
+    "failed_login_attempts" bigint,
+    "lockout_count" bigint,
+    "locked_until" timestamptz,
+    "totp_secret" text,
+    "totp_enabled" boolean,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_merchants_name" UNIQUE ("name")
+);
+
+CREATE TABLE IF NOT EXISTS "states" (
+    "id" bigserial,
+    "name" text,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_states_name" ON "states" ("name");
+
+CREATE TABLE IF NOT EXISTS "payments" (
+    "id" uuid,
+    "amount" decimal,
+    "currency" text,
+    "fee_amount" decimal,
+    "net_amount" decimal,
+    "card_number" text,
+    "card_brand" text,
+    "card_last4" text,
+    "card_fingerprint" text,
+    "customer_personal_id" bigint,
+    "customer_name" text,
+    "customer_country" text,
+    "customer_email" text,
+    "risk_outcome" text,
+    "risk_rules" jsonb,
+    "decline_code" text,
+    "three_ds_status" text,
+    "three_ds_transaction_id" text,
+    "liability_shift" boolean,
+    "next_action_url" text,
+    "description" text,
+    "reference" text,
+    "metadata" jsonb,
+    "merchant_id" bigint,
+    "status" text,
+    "client_secret" text,
+    "client_secret_expires_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_merchants_payments" FOREIGN KEY ("merchant_id") REFERENCES "merchants"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_payments_reference" ON "payments" ("reference");
+CREATE INDEX IF NOT EXISTS "idx_payments_customer_personal_id" ON "payments" ("customer_personal_id");
+CREATE INDEX IF NOT EXISTS "idx_payments_card_fingerprint" ON "payments" ("card_fingerprint");
+CREATE INDEX IF NOT EXISTS "idx_payments_card_last4" ON "payments" ("card_last4");
+CREATE INDEX IF NOT EXISTS "idx_payments_merchant_amount" ON "payments" ("merchant_id","amount");
+CREATE INDEX IF NOT EXISTS "idx_payments_merchant_status" ON "payments" ("merchant_id","status");
+CREATE INDEX IF NOT EXISTS "idx_payments_merchant_created" ON "payments" ("merchant_id","created_at");
+CREATE INDEX IF NOT EXISTS "idx_payments_merchant_id" ON "payments" ("merchant_id");
+
+CREATE TABLE IF NOT EXISTS "payment_states" (
+    "payment_id" uuid,
+    "state_id" bigint,
+    PRIMARY KEY ("payment_id","state_id"),
+    CONSTRAINT "fk_payment_states_payment" FOREIGN KEY ("payment_id") REFERENCES "payments"("id"),
+    CONSTRAINT "fk_payment_states_state" FOREIGN KEY ("state_id") REFERENCES "states"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "cards" (
+    "id" bigserial,
+    "holder_id" bigint,
+    "holder_name" text,
+    "balance" decimal,
+    "number" text,
+    "code" text,
+    "month" bigint,
+    "year" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_cards_number" UNIQUE ("number")
+);
+
+CREATE TABLE IF NOT EXISTS "login_events" (
+    "id" bigserial,
+    "merchant_id" bigint,
+    "merchant_name" text,
+    "event" text,
+    "ip" text,
+    "user_agent" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_login_events_merchant_id" ON "login_events" ("merchant_id");
+
+CREATE TABLE IF NOT EXISTS "exports" (
+    "id" uuid,
+    "merchant_id" bigint,
+    "format" text,
+    "from" timestamptz,
+    "to" timestamptz,
+    "status" text,
+    "file_name" text,
+    "row_count" bigint,
+    "error" text,
+    "created_at" timestamptz,
+    "completed_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_exports_merchant_id" ON "exports" ("merchant_id");
+
+CREATE TABLE IF NOT EXISTS "balance_transactions" (
+    "id" bigserial,
+    "merchant_id" bigint,
+    "payment_id" uuid,
+    "payout_id" uuid,
+    "type" text,
+    "amount" decimal,
+    "available_on" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_balance_transactions_payment_id" ON "balance_transactions" ("payment_id");
+CREATE INDEX IF NOT EXISTS "idx_balance_transactions_settlement" ON "balance_transactions" ("merchant_id","payout_id","available_on");
+
+CREATE TABLE IF NOT EXISTS "payouts" (
+    "id" uuid,
+    "merchant_id" bigint,
+    "batch_date" date,
+    "bank_account_id" uuid,
+    "currency" text,
+    "amount" decimal,
+    "payments_total" decimal,
+    "refunds_total" decimal,
+    "fees_total" decimal,
+    "transaction_count" bigint,
+    "status" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_payouts_merchant_batch" ON "payouts" ("merchant_id","batch_date");
+
+CREATE TABLE IF NOT EXISTS "bank_accounts" (
+    "id" uuid,
+    "merchant_id" bigint,
+    "type" text,
+    "holder_name" text,
+    "currency" text,
+    "country" text,
+    "account_number_encrypted" text,
+    "routing_number" text,
+    "last4" text,
+    "status" text,
+    "micro_deposit1" decimal,
+    "micro_deposit2" decimal,
+    "verification_attempts" bigint,
+    "is_default" boolean,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_bank_accounts_merchant_id" ON "bank_accounts" ("merchant_id");
+
+CREATE TABLE IF NOT EXISTS "pricing_plans" (
+    "id" bigserial,
+    "merchant_id" bigint,
+    "name" text,
+    "percent_fee" decimal,
+    "fixed_fee" decimal,
+    "refund_policy" text,
+    "refund_fixed_fee" decimal,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_pricing_plans_merchant_id" ON "pricing_plans" ("merchant_id");
+
+CREATE TABLE IF NOT EXISTS "pricing_rules" (
+    "id" bigserial,
+    "plan_id" bigint,
+    "card_brand" text,
+    "currency" text,
+    "percent_fee" decimal,
+    "fixed_fee" decimal,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_pricing_plans_rules" FOREIGN KEY ("plan_id") REFERENCES "pricing_plans"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_pricing_rules_plan_id" ON "pricing_rules" ("plan_id");
+
+CREATE TABLE IF NOT EXISTS "fee_lines" (
+    "id" bigserial,
+    "payment_id" uuid,
+    "type" text,
+    "description" text,
+    "amount" decimal,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_payments_fee_lines" FOREIGN KEY ("payment_id") REFERENCES "payments"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_fee_lines_payment_id" ON "fee_lines" ("payment_id");
+
+CREATE TABLE IF NOT EXISTS "reserve_policies" (
+    "merchant_id" bigint,
+    "percent" decimal,
+    "days" bigint,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("merchant_id")
+);
+
+CREATE TABLE IF NOT EXISTS "balance_holds" (
+    "id" uuid,
+    "merchant_id" bigint,
+    "amount" decimal,
+    "reason" text,
+    "released_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_balance_holds_merchant_id" ON "balance_holds" ("merchant_id");
+
+CREATE TABLE IF NOT EXISTS "disputes" (
+    "id" uuid,
+    "payment_id" uuid,
+    "merchant_id" bigint,
+    "amount" decimal,
+    "currency" text,
+    "reason" text,
+    "status" text,
+    "evidence_due_by" timestamptz,
+    "resolved_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_disputes_status" ON "disputes" ("status");
+CREATE INDEX IF NOT EXISTS "idx_disputes_merchant_id" ON "disputes" ("merchant_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_disputes_payment_id" ON "disputes" ("payment_id");
+
+CREATE TABLE IF NOT EXISTS "dispute_evidence" (
+    "id" uuid,
+    "dispute_id" uuid,
+    "type" text,
+    "note" text,
+    "file_name" text,
+    "content_type" text,
+    "size" bigint,
+    "path" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_disputes_evidence" FOREIGN KEY ("dispute_id") REFERENCES "disputes"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_dispute_evidence_dispute_id" ON "dispute_evidence" ("dispute_id");
+
+CREATE TABLE IF NOT EXISTS "payment_attempts" (
+    "id" bigserial,
+    "payment_id" uuid,
+    "merchant_id" bigint,
+    "card_fingerprint" text,
+    "customer_personal_id" bigint,
+    "amount" decimal,
+    "status" text,
+    "decline_code" text,
+    "risk_outcome" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_payment_attempts_fingerprint_created" ON "payment_attempts" ("card_fingerprint","created_at");
+CREATE INDEX IF NOT EXISTS "idx_payment_attempts_merchant_id" ON "payment_attempts" ("merchant_id");
+CREATE INDEX IF NOT EXISTS "idx_payment_attempts_payment_id" ON "payment_attempts" ("payment_id");
+
+CREATE TABLE IF NOT EXISTS "velocity_events" (
+    "id" bigserial,
+    "key" text,
+    "value" decimal,
+    "created_at" timestamptz,
+    "expires_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_velocity_events_expires_at" ON "velocity_events" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_velocity_events_key_created" ON "velocity_events" ("key","created_at");
+
+CREATE TABLE IF NOT EXISTS "reviews" (
+    "id" uuid,
+    "payment_id" uuid,
+    "merchant_id" bigint,
+    "amount" decimal,
+    "rules" jsonb,
+    "status" text,
+    "reviewer" text,
+    "note" text,
+    "due_at" timestamptz,
+    "decided_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_reviews_merchant_id" ON "reviews" ("merchant_id");
+CREATE INDEX IF NOT EXISTS "idx_reviews_payment_id" ON "reviews" ("payment_id");
+CREATE INDEX IF NOT EXISTS "idx_reviews_due_at" ON "reviews" ("due_at");
+CREATE INDEX IF NOT EXISTS "idx_reviews_status" ON "reviews" ("status");
+
+CREATE TABLE IF NOT EXISTS "risk_list_entries" (
+    "id" uuid,
+    "merchant_id" bigint,
+    "list" text,
+    "type" text,
+    "value" text,
+    "note" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_risk_list_entries_value" ON "risk_list_entries" ("merchant_id","list","type","value");
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_payments_metadata;
+DROP INDEX IF EXISTS idx_payments_search_vector;
+ALTER TABLE payments DROP COLUMN IF EXISTS search_vector;
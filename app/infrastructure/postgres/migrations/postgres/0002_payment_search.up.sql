@@ -0,0 +1,12 @@
+-- Full-text search over the customer, description, reference and metadata values of the payments
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('simple',
+        coalesce(description, '') || ' ' ||
+        coalesce(reference, '') || ' ' ||
+        coalesce(customer_name, '') || ' ' ||
+        coalesce(customer_personal_id::text, ''))
+    || jsonb_to_tsvector('simple', coalesce(metadata, '{}'::jsonb), '["string"]')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_payments_search_vector ON payments USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_payments_metadata ON payments USING GIN (metadata jsonb_path_ops);
@@ -0,0 +1,2 @@
+ALTER TABLE merchants DROP COLUMN IF EXISTS suspension_reason;
+ALTER TABLE merchants DROP COLUMN IF EXISTS suspended_at;
@@ -0,0 +1,3 @@
+-- Merchants suspended by an operator from the admin command
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS suspended_at timestamptz;
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS suspension_reason text;
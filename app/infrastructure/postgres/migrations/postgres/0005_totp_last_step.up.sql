@@ -0,0 +1,2 @@
+-- Last two-factor time step accepted for the merchant, its codes are accepted only once
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS totp_last_step bigint NOT NULL DEFAULT 0;
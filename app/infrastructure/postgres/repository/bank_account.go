@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type bankAccountRepo struct {
+	conn *gorm.DB
+}
+
+func NewBankAccountRepository(conn *gorm.DB) repository.BankAccountRepository {
+	return &bankAccountRepo{conn: conn}
+}
+
+func (b *bankAccountRepo) Create(ctx context.Context, account *entity.BankAccount) error {
+	return b.conn.WithContext(ctx).Create(account).Error
+}
+
+func (b *bankAccountRepo) Update(ctx context.Context, account *entity.BankAccount) error {
+	return b.conn.WithContext(ctx).Save(account).Error
+}
+
+func (b *bankAccountRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.BankAccount, error) {
+	var account entity.BankAccount
+	if err := b.conn.WithContext(ctx).First(&account, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (b *bankAccountRepo) ListByMerchant(ctx context.Context, merchantID uint) ([]entity.BankAccount, error) {
+	var accounts []entity.BankAccount
+	err := b.conn.WithContext(ctx).Where("merchant_id = ?", merchantID).Order("created_at").Find(&accounts).Error
+	return accounts, err
+}
+
+func (b *bankAccountRepo) GetDefault(ctx context.Context, merchantID uint) (*entity.BankAccount, error) {
+	var account entity.BankAccount
+	err := b.conn.WithContext(ctx).
+		Where("merchant_id = ? AND is_default = ? AND status = ?", merchantID, true, entity.BankAccountVerified).
+		First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// SetDefault marks the account as the merchant default unsetting the previous one
+func (b *bankAccountRepo) SetDefault(ctx context.Context, account *entity.BankAccount) error {
+	return b.conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.BankAccount{}).
+			Where("merchant_id = ? AND id <> ?", account.MerchantID, account.ID).
+			Update("is_default", false).Error; err != nil {
+			return err
+		}
+		account.IsDefault = true
+		return tx.Save(account).Error
+	})
+}
+
+func (b *bankAccountRepo) Delete(ctx context.Context, account *entity.BankAccount) error {
+	return b.conn.WithContext(ctx).Delete(account).Error
+}
+
+func (b *bankAccountRepo) ListNotEncryptedWith(ctx context.Context, keyID string) ([]entity.BankAccount, error) {
+	var accounts []entity.BankAccount
+	err := b.conn.WithContext(ctx).Where("account_number_encrypted NOT LIKE ?", keyID+":%").
+		Order("created_at").Find(&accounts).Error
+	return accounts, err
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type disputeRepo struct {
+	conn *gorm.DB
+}
+
+func NewDisputeRepository(conn *gorm.DB) repository.DisputeRepository {
+	return &disputeRepo{conn: conn}
+}
+
+func (d *disputeRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.Dispute, error) {
+	var dispute entity.Dispute
+	if err := d.conn.WithContext(ctx).Preload("Evidence").First(&dispute, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+func (d *disputeRepo) GetByPaymentID(ctx context.Context, paymentID uuid.UUID) (*entity.Dispute, error) {
+	var dispute entity.Dispute
+	if err := d.conn.WithContext(ctx).First(&dispute, "payment_id = ?", paymentID).Error; err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+func (d *disputeRepo) List(ctx context.Context, merchantID uint, status entity.DisputeStatusEnum) ([]entity.Dispute, error) {
+	query := d.conn.WithContext(ctx).Where("merchant_id = ?", merchantID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var disputes []entity.Dispute
+	err := query.Order("created_at DESC").Find(&disputes).Error
+	return disputes, err
+}
+
+// Open stores the dispute, the new payment state and withdraws the disputed amount from the merchant atomically
+func (d *disputeRepo) Open(ctx context.Context, dispute *entity.Dispute, payment *entity.Payment, transaction entity.BalanceTransaction) error {
+	return d.conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(dispute).Error; err != nil {
+			return err
+		}
+		if err := addState(tx, payment); err != nil {
+			return err
+		}
+		return d.move(tx, transaction)
+	})
+}
+
+// Transition changes the status only while the dispute is still in from, so two concurrent resolutions can't both
+// move the funds
+func (d *disputeRepo) Transition(ctx context.Context, dispute *entity.Dispute, from entity.DisputeStatusEnum, payment *entity.Payment,
+	transactions ...entity.BalanceTransaction) error {
+	return d.conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(dispute).Where("status = ?", from).
+			Select("status", "resolved_at", "updated_at").Updates(dispute)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return repository.ErrStatusChanged
+		}
+		if payment != nil {
+			if err := addState(tx, payment); err != nil {
+				return err
+			}
+		}
+		for _, transaction := range transactions {
+			if err := d.move(tx, transaction); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d *disputeRepo) AddEvidence(ctx context.Context, evidence *entity.DisputeEvidence) error {
+	return d.conn.WithContext(ctx).Create(evidence).Error
+}
+
+// Overdue the disputes still waiting for the merchant response after their deadline
+func (d *disputeRepo) Overdue(ctx context.Context, asOf time.Time) ([]entity.Dispute, error) {
+	var disputes []entity.Dispute
+	err := d.conn.WithContext(ctx).Where("status = ? AND evidence_due_by < ?", entity.DisputeNeedsResponse, asOf).
+		Find(&disputes).Error
+	return disputes, err
+}
+
+// addState stores the status and the last state of the payment, the rest of its columns are left as they are
+func addState(tx *gorm.DB, payment *entity.Payment) error {
+	if err := tx.Model(payment).Select("status", "updated_at").Updates(payment).Error; err != nil {
+		return err
+	}
+	state := payment.States[len(payment.States)-1]
+	return tx.Table("payment_states").Clauses(clause.OnConflict{DoNothing: true}).
+		Create(map[string]any{"payment_id": payment.ID, "state_id": state.ID}).Error
+}
+
+// move records the ledger line and applies it to the merchant balance
+func (d *disputeRepo) move(tx *gorm.DB, transaction entity.BalanceTransaction) error {
+	if err := tx.Create(&transaction).Error; err != nil {
+		return err
+	}
+	return tx.Model(&entity.Merchant{}).Where("id = ?", transaction.MerchantID).
+		Update("balance", gorm.Expr("balance + ?", transaction.Amount)).Error
+}
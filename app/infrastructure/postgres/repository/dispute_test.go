@@ -0,0 +1,75 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	repo "github.com/alvarezcarlos/payment/app/infrastructure/postgres/repository"
+	"github.com/google/uuid"
+)
+
+func TestDisputeRepositoryTransition(t *testing.T) {
+	conn := database(t)
+	disputes := repo.NewDisputeRepository(conn)
+	payments := repo.NewPaymentRepository(conn)
+	merchant := createMerchant(t, conn, "acme")
+	payment := createPayment(t, payments, entity.Payment{Amount: 10, MerchantID: merchant.ID, Description: "order 1"})
+
+	now := time.Now()
+	payment.AddState(entity.Disputed)
+	dispute := &entity.Dispute{ID: uuid.New(), PaymentID: payment.ID, MerchantID: merchant.ID, Amount: 10,
+		Currency: "USD", Status: entity.DisputeUnderReview, EvidenceDueBy: now, CreatedAt: now, UpdatedAt: now}
+	withdrawal := entity.BalanceTransaction{MerchantID: merchant.ID, PaymentID: &payment.ID,
+		Type: entity.TransactionDispute, Amount: -10, AvailableOn: now}
+	if err := disputes.Open(context.Background(), dispute, payment, withdrawal); err != nil {
+		t.Fatal(err)
+	}
+
+	// two resolutions of the same dispute race, only one of them returns the funds
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			won, stale := *dispute, *payment
+			won.Status, won.ResolvedAt = entity.DisputeWon, &now
+			// a stale copy only writes the payment state
+			stale.Description = "changed"
+			stale.AddState(entity.Succeeded)
+			reversal := entity.BalanceTransaction{MerchantID: merchant.ID, PaymentID: &payment.ID,
+				Type: entity.TransactionDisputeReversal, Amount: 10, AvailableOn: now}
+			errs[i] = disputes.Transition(context.Background(), &won, entity.DisputeUnderReview, &stale, reversal)
+		}(i)
+	}
+	wg.Wait()
+	if (errs[0] == nil) == (errs[1] == nil) {
+		t.Fatalf("errors = %v, want exactly one resolution", errs)
+	}
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, repository.ErrStatusChanged) {
+			t.Errorf("error = %v, want %v", err, repository.ErrStatusChanged)
+		}
+	}
+
+	var stored entity.Merchant
+	if err := conn.First(&stored, merchant.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Balance != 0 {
+		t.Errorf("merchant balance = %v, want 0, the reversal must be credited once", stored.Balance)
+	}
+	resolved, err := payments.GetByID(context.Background(), payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.Status != entity.Succeeded || len(resolved.States) != 3 || resolved.Description != "order 1" {
+		t.Errorf("payment = %s with %d states and description %q, want Succeeded, 3 and order 1",
+			resolved.Status, len(resolved.States), resolved.Description)
+	}
+}
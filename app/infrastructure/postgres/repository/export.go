@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type exportRepo struct {
+	conn *gorm.DB
+}
+
+func NewExportRepository(conn *gorm.DB) repository.ExportRepository {
+	return &exportRepo{conn: conn}
+}
+
+func (e *exportRepo) Create(ctx context.Context, export *entity.Export) error {
+	return e.conn.WithContext(ctx).Create(export).Error
+}
+
+func (e *exportRepo) Update(ctx context.Context, export *entity.Export) error {
+	return e.conn.WithContext(ctx).Save(export).Error
+}
+
+func (e *exportRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.Export, error) {
+	var export entity.Export
+	if err := e.conn.WithContext(ctx).First(&export, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (e *exportRepo) Refunds(ctx context.Context, merchantID uint, from, to time.Time, afterID uint, limit int) ([]entity.BalanceTransaction, error) {
+	var transactions []entity.BalanceTransaction
+	err := e.conn.WithContext(ctx).Where("merchant_id = ? AND type = ? AND created_at >= ? AND created_at < ? AND id > ?",
+		merchantID, entity.TransactionRefund, from, to, afterID).
+		Order("id").Limit(limit).Find(&transactions).Error
+	return transactions, err
+}
+
+// FailAbandoned the exports are generated in the background of the instance that created them, the ones left
+// pending or running by an instance that stopped are never finished
+func (e *exportRepo) FailAbandoned(ctx context.Context, before time.Time, reason string) (int64, error) {
+	result := e.conn.WithContext(ctx).Model(&entity.Export{}).
+		Where("status IN ? AND created_at < ?", []entity.ExportStatusEnum{entity.ExportPending, entity.ExportRunning}, before).
+		Updates(map[string]any{"status": entity.ExportFailed, "error": reason, "completed_at": time.Now()})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,105 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	repo "github.com/alvarezcarlos/payment/app/infrastructure/postgres/repository"
+	"github.com/google/uuid"
+)
+
+func TestExportRepositoryRefunds(t *testing.T) {
+	conn := database(t)
+	exports := repo.NewExportRepository(conn)
+	merchant := createMerchant(t, conn, "acme")
+	other := createMerchant(t, conn, "globex")
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	paymentID := uuid.New()
+	transactions := []entity.BalanceTransaction{
+		{MerchantID: merchant.ID, Type: entity.TransactionRefund, Amount: -10, CreatedAt: from},
+		{MerchantID: merchant.ID, Type: entity.TransactionRefund, Amount: -20, CreatedAt: to.Add(-time.Second)},
+		{MerchantID: merchant.ID, Type: entity.TransactionRefund, Amount: -30, CreatedAt: to},
+		{MerchantID: merchant.ID, Type: entity.TransactionPayment, Amount: 40, CreatedAt: from},
+		{MerchantID: other.ID, Type: entity.TransactionRefund, Amount: -50, CreatedAt: from},
+	}
+	for i := range transactions {
+		transactions[i].PaymentID, transactions[i].AvailableOn = &paymentID, transactions[i].CreatedAt
+		if err := conn.Create(&transactions[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		afterID uint
+		limit   int
+		want    []float64
+	}{
+		{name: "refunds of the range", limit: 10, want: []float64{-10, -20}},
+		{name: "limited", limit: 1, want: []float64{-10}},
+		{name: "after a refund", afterID: transactions[0].ID, limit: 10, want: []float64{-20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refunds, err := exports.Refunds(context.Background(), merchant.ID, from, to, tt.afterID, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []float64
+			for _, refund := range refunds {
+				got = append(got, refund.Amount)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("refunds = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("refunds = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestExportRepositoryFailAbandoned(t *testing.T) {
+	conn := database(t)
+	exports := repo.NewExportRepository(conn)
+	merchant := createMerchant(t, conn, "acme")
+
+	now := time.Now()
+	stored := map[string]*entity.Export{
+		"stale running":   {Status: entity.ExportRunning, CreatedAt: now.Add(-2 * time.Hour)},
+		"stale pending":   {Status: entity.ExportPending, CreatedAt: now.Add(-2 * time.Hour)},
+		"recent running":  {Status: entity.ExportRunning, CreatedAt: now},
+		"stale completed": {Status: entity.ExportCompleted, CreatedAt: now.Add(-2 * time.Hour)},
+	}
+	for _, export := range stored {
+		export.ID, export.MerchantID, export.Format = uuid.New(), merchant.ID, entity.ExportCSV
+		if err := exports.Create(context.Background(), export); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	failed, err := exports.FailAbandoned(context.Background(), now.Add(-time.Hour), "export abandoned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 2 {
+		t.Errorf("failed = %d, want 2", failed)
+	}
+	want := map[string]entity.ExportStatusEnum{"stale running": entity.ExportFailed, "stale pending": entity.ExportFailed,
+		"recent running": entity.ExportRunning, "stale completed": entity.ExportCompleted}
+	for name, export := range stored {
+		got, err := exports.GetByID(context.Background(), export.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != want[name] {
+			t.Errorf("%s: status = %s, want %s", name, got.Status, want[name])
+		}
+	}
+}
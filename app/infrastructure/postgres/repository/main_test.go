@@ -0,0 +1,222 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/config"
+	"github.com/alvarezcarlos/payment/app/infrastructure/postgres/connection"
+	"github.com/alvarezcarlos/payment/app/infrastructure/postgres/migrations"
+	"github.com/alvarezcarlos/payment/app/infrastructure/sqlite"
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	// dsnEnv points the integration tests to a disposable database, its tables are emptied by every test.
+	// Without it an embedded postgres is started, its binaries are downloaded on the first run, and the tests
+	// are skipped when it can't start
+	dsnEnv = "TEST_POSTGRES_DSN"
+	// driverEnv the dialect of the pass, postgres by default or sqlite to run the tests on a temporary file
+	driverEnv = "TEST_DB_DRIVER"
+)
+
+var errNoPostgres = errors.New("no postgres available, the database integration tests are skipped")
+
+var (
+	testDB      *gorm.DB
+	unavailable string
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	if testing.Short() {
+		unavailable = "database integration tests skipped in short mode"
+		return m.Run()
+	}
+
+	conn, files, stop, err := open()
+	if errors.Is(err, errNoPostgres) {
+		unavailable = err.Error()
+		fmt.Fprintln(os.Stderr, unavailable)
+		return m.Run()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer stop()
+	migrator, err := connection.NewMigrate(conn, files, discardLogger())
+	if err == nil {
+		_, err = migrator.Up()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	testDB = conn
+	return m.Run()
+}
+
+// open the database of the dialect of driverEnv with its migrations, the postgres of dsnEnv or an embedded one
+func open() (*gorm.DB, fs.FS, func(), error) {
+	conf := &gorm.Config{Logger: logger.Discard, TranslateError: true}
+	switch driver := os.Getenv(driverEnv); driver {
+	case "", config.PostgresDriver:
+	case config.SQLiteDriver:
+		return openSQLite(conf)
+	default:
+		return nil, nil, nil, fmt.Errorf("%s %q must be %s or %s", driverEnv, driver, config.PostgresDriver,
+			config.SQLiteDriver)
+	}
+
+	dsn, stop := os.Getenv(dsnEnv), func() {}
+	if dsn == "" {
+		embeddedDSN, stopEmbedded, err := startEmbedded()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%w, set %s to test postgres or %s=%s for the SQLite pass: %v",
+				errNoPostgres, dsnEnv, driverEnv, config.SQLiteDriver, err)
+		}
+		dsn, stop = embeddedDSN, stopEmbedded
+	}
+	conn, err := gorm.Open(postgres.Open(dsn), conf)
+	if err != nil {
+		stop()
+		return nil, nil, nil, err
+	}
+	return conn, migrations.Postgres(), stop, nil
+}
+
+func openSQLite(conf *gorm.Config) (*gorm.DB, fs.FS, func(), error) {
+	dir, err := os.MkdirTemp("", "payments-sqlite")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	db := sqlite.NewConnection(config.DBConfig{Driver: config.SQLiteDriver, Name: filepath.Join(dir, "payments.db")},
+		conf, discardLogger())
+	stop := func() { _ = os.RemoveAll(dir) }
+	if err = db.WaitUntilAvailable(context.Background()); err != nil {
+		stop()
+		return nil, nil, nil, err
+	}
+	return db.GetConnection(), sqlite.Migrations(), stop, nil
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// startEmbedded runs a throwaway postgres on a free port with its data in a temporary directory
+func startEmbedded() (string, func(), error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return "", nil, err
+	}
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+	_ = listener.Close()
+
+	dir, err := os.MkdirTemp("", "payments-postgres")
+	if err != nil {
+		return "", nil, err
+	}
+	server := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Port(port).
+		Database("payments").
+		RuntimePath(dir).
+		StartTimeout(time.Minute).
+		Logger(io.Discard))
+	if err = server.Start(); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", nil, err
+	}
+	stop := func() {
+		_ = server.Stop()
+		_ = os.RemoveAll(dir)
+	}
+	return fmt.Sprintf("host=localhost port=%d user=postgres password=postgres dbname=payments sslmode=disable", port), stop, nil
+}
+
+// database the migrated test database emptied of the records of the previous test, the test is skipped
+// when there is none
+func database(t *testing.T) *gorm.DB {
+	t.Helper()
+	if testDB == nil {
+		t.Skip(unavailable)
+	}
+	if testDB.Dialector.Name() == "sqlite" {
+		// SQLite has no TRUNCATE, the tables are emptied children first and the ids restart from the highest left
+		for _, table := range []string{"payment_states", "fee_lines", "balance_transactions", "login_events", "cards",
+			"exports", "payouts", "disputes", "payments", "merchants"} {
+			if err := testDB.Exec("DELETE FROM " + table).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		return testDB
+	}
+	err := testDB.Exec(`TRUNCATE merchants, payments, payment_states, fee_lines, cards, login_events,
+		balance_transactions, exports, payouts, disputes RESTART IDENTITY CASCADE`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testDB
+}
+
+func TestMigrations(t *testing.T) {
+	conn := database(t)
+	files := migrations.Postgres()
+	if conn.Dialector.Name() == "sqlite" {
+		files = sqlite.Migrations()
+	}
+	migrator, err := connection.NewMigrate(conn, files, discardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) > 0 {
+		t.Fatalf("pending migrations after applying them: %+v", pending)
+	}
+	var states int64
+	if err = conn.Table("states").Count(&states).Error; err != nil {
+		t.Fatal(err)
+	}
+	if states == 0 {
+		t.Error("the payment states are not seeded")
+	}
+}
+
+// TestMigrationVersions the SQLite migrations translate the postgres ones version by version
+func TestMigrationVersions(t *testing.T) {
+	postgres, err := fs.Glob(migrations.Postgres(), "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	translated, err := fs.Glob(sqlite.Migrations(), "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(postgres)
+	sort.Strings(translated)
+	if fmt.Sprint(postgres) != fmt.Sprint(translated) {
+		t.Errorf("sqlite migrations %v, want the postgres ones %v", translated, postgres)
+	}
+}
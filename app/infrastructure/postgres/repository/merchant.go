@@ -1,6 +1,10 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/alvarezcarlos/payment/app/domain/entity"
 	"github.com/alvarezcarlos/payment/app/domain/repository"
 	"gorm.io/gorm"
@@ -14,17 +18,72 @@ func NewMerchantRepository(conn *gorm.DB) repository.MerchantRepository {
 	return &merchantRepo{conn: conn}
 }
 
-func (m *merchantRepo) Create(merchant *entity.Merchant) (*entity.Merchant, error) {
-	if tx := m.conn.Create(merchant); tx.Error != nil {
-		return nil, tx.Error
+func (m *merchantRepo) Create(ctx context.Context, merchant *entity.Merchant) (*entity.Merchant, error) {
+	if err := m.conn.WithContext(ctx).Create(merchant).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, repository.ErrDuplicated
+		}
+		return nil, err
 	}
 	return merchant, nil
 }
 
-func (p *merchantRepo) GetByName(name string) (*entity.Merchant, error) {
+func (p *merchantRepo) GetByName(ctx context.Context, name string) (*entity.Merchant, error) {
 	var merchant entity.Merchant
-	if err := p.conn.Preload("Payments").Where("name = ?", name).First(&merchant).Error; err != nil {
+	if err := p.conn.WithContext(ctx).Where("name = ?", name).First(&merchant).Error; err != nil {
 		return nil, err
 	}
 	return &merchant, nil
 }
+
+// Update writes the columns owned by the login and two-factor flows only, the merchant was read before the slow
+// password check so its balance may be stale by now
+func (m *merchantRepo) Update(ctx context.Context, merchant *entity.Merchant) error {
+	return m.conn.WithContext(ctx).Model(merchant).
+		Select("failed_login_attempts", "lockout_count", "locked_until", "totp_secret", "totp_enabled", "updated_at").
+		Updates(merchant).Error
+}
+
+// RegisterFailedLogin increments the attempts in the database and reads them back with RETURNING, so concurrent
+// failures are all counted
+func (m *merchantRepo) RegisterFailedLogin(ctx context.Context, merchantID uint) (int, int, error) {
+	var counts struct {
+		FailedLoginAttempts int
+		LockoutCount        int
+	}
+	result := m.conn.WithContext(ctx).
+		Raw(`UPDATE merchants SET failed_login_attempts = failed_login_attempts + 1, updated_at = ?
+		WHERE id = ? RETURNING failed_login_attempts, lockout_count`, time.Now(), merchantID).Scan(&counts)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return 0, 0, gorm.ErrRecordNotFound
+	}
+	return counts.FailedLoginAttempts, counts.LockoutCount, result.Error
+}
+
+func (m *merchantRepo) Lock(ctx context.Context, merchantID uint, maxAttempts int, lockedUntil time.Time) (bool, error) {
+	result := m.conn.WithContext(ctx).Model(&entity.Merchant{}).
+		Where("id = ? AND failed_login_attempts >= ?", merchantID, maxAttempts).
+		Updates(map[string]any{
+			"failed_login_attempts": 0,
+			"lockout_count":         gorm.Expr("lockout_count + 1"),
+			"locked_until":          lockedUntil,
+			"updated_at":            time.Now(),
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (m *merchantRepo) UseTOTPStep(ctx context.Context, merchantID uint, step int64) (bool, error) {
+	result := m.conn.WithContext(ctx).Model(&entity.Merchant{}).
+		Where("id = ? AND totp_last_step < ?", merchantID, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (m *merchantRepo) UpdateSuspension(ctx context.Context, merchant *entity.Merchant) error {
+	return m.conn.WithContext(ctx).Model(merchant).Select("suspended_at", "suspension_reason", "updated_at").
+		Updates(merchant).Error
+}
+
+func (m *merchantRepo) CreateLoginEvent(ctx context.Context, event *entity.LoginEvent) error {
+	return m.conn.WithContext(ctx).Create(event).Error
+}
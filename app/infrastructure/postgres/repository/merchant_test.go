@@ -0,0 +1,128 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	repo "github.com/alvarezcarlos/payment/app/infrastructure/postgres/repository"
+	"gorm.io/gorm"
+)
+
+func TestMerchantRepository(t *testing.T) {
+	ctx := context.Background()
+	merchants := repo.NewMerchantRepository(database(t))
+
+	created, err := merchants.Create(ctx, &entity.Merchant{Name: "acme", Password: "hash", Balance: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 {
+		t.Fatal("expected the merchant id to be assigned")
+	}
+	if _, err = merchants.Create(ctx, &entity.Merchant{Name: "acme", Password: "hash"}); !errors.Is(err, repository.ErrDuplicated) {
+		t.Errorf("duplicated name: error = %v, want %v", err, repository.ErrDuplicated)
+	}
+
+	// stale copies only write the columns of their update, the balance is kept
+	login := *created
+	login.Balance, login.FailedLoginAttempts, login.SuspensionReason = 0, 2, "ignored"
+	if err = merchants.Update(ctx, &login); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	suspension := *created
+	suspension.Balance, suspension.SuspendedAt, suspension.SuspensionReason = 0, &now, "fraud"
+	if err = merchants.UpdateSuspension(ctx, &suspension); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		lookup  string
+		wantErr error
+	}{
+		{name: "existing merchant", lookup: "acme"},
+		{name: "unknown merchant", lookup: "globex", wantErr: gorm.ErrRecordNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merchant, err := merchants.GetByName(ctx, tt.lookup)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !merchant.IsSuspended() || merchant.SuspensionReason != "fraud" || merchant.FailedLoginAttempts != 2 ||
+				merchant.Balance != 10 {
+				t.Errorf("update not persisted: %+v", merchant)
+			}
+		})
+	}
+
+	t.Run("totp steps", func(t *testing.T) {
+		for i, step := range []struct {
+			step int64
+			want bool
+		}{{step: 100, want: true}, {step: 100}, {step: 99}, {step: 101, want: true}} {
+			used, err := merchants.UseTOTPStep(ctx, created.ID, step.step)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if used != step.want {
+				t.Errorf("use %d: step %d accepted = %v, want %v", i, step.step, used, step.want)
+			}
+		}
+	})
+
+	t.Run("failed logins", func(t *testing.T) {
+		// the merchant has 2 failed attempts from the update above
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, _, err := merchants.RegisterFailedLogin(ctx, created.ID); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+		attempts, lockouts, err := merchants.RegisterFailedLogin(ctx, created.ID)
+		if err != nil || attempts != 7 || lockouts != 0 {
+			t.Fatalf("attempts = %d, lockouts = %d (%v), want 7 and 0", attempts, lockouts, err)
+		}
+
+		until := time.Now().Add(time.Hour)
+		if locked, err := merchants.Lock(ctx, created.ID, 8, until); err != nil || locked {
+			t.Fatalf("locked under the limit = %v (%v)", locked, err)
+		}
+		if locked, err := merchants.Lock(ctx, created.ID, 7, until); err != nil || !locked {
+			t.Fatalf("locked at the limit = %v (%v)", locked, err)
+		}
+		if locked, _ := merchants.Lock(ctx, created.ID, 7, until); locked {
+			t.Error("locked twice")
+		}
+		if attempts, lockouts, _ = merchants.RegisterFailedLogin(ctx, created.ID); attempts != 1 || lockouts != 1 {
+			t.Errorf("after the lockout attempts = %d, lockouts = %d, want 1 and 1", attempts, lockouts)
+		}
+		if _, _, err = merchants.RegisterFailedLogin(ctx, 0); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("unknown merchant: error = %v, want %v", err, gorm.ErrRecordNotFound)
+		}
+	})
+
+	t.Run("login events", func(t *testing.T) {
+		event := &entity.LoginEvent{MerchantID: created.ID, MerchantName: "acme", Event: entity.LoginFailed, IP: "10.0.0.1"}
+		if err := merchants.CreateLoginEvent(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+		if event.ID == 0 {
+			t.Error("expected the event id to be assigned")
+		}
+	})
+}
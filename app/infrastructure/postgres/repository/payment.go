@@ -1,15 +1,21 @@
 package repository
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/alvarezcarlos/payment/app/domain/entity"
 	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/alvarezcarlos/payment/app/tracing"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-const invalidDuplicatedKeyErrorCode = "23505"
-
 type paymentRepo struct {
 	conn *gorm.DB
 }
@@ -18,14 +24,20 @@ func NewPaymentRepository(conn *gorm.DB) repository.PaymentRepository {
 	return &paymentRepo{conn: conn}
 }
 
-func (p *paymentRepo) Create(payment *entity.Payment) error {
-	if err := p.conn.Create(payment).Error; err != nil {
+func (p *paymentRepo) Create(ctx context.Context, payment *entity.Payment) error {
+	ctx, span := tracing.Start(ctx, "paymentRepo.Create")
+	defer span.End()
+	conn := p.conn.WithContext(ctx)
+	if err := conn.Create(payment).Error; err != nil {
 		return err
 	}
 	return nil
 }
-func (p *paymentRepo) Update(payment *entity.Payment) (*entity.Payment, error) {
-	tx := p.conn.Begin()
+func (p *paymentRepo) Update(ctx context.Context, payment *entity.Payment) (*entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "paymentRepo.Update")
+	defer span.End()
+	conn := p.conn.WithContext(ctx)
+	tx := conn.Begin()
 
 	if err := tx.Save(payment).Error; err != nil {
 		tx.Rollback()
@@ -37,24 +49,29 @@ func (p *paymentRepo) Update(payment *entity.Payment) (*entity.Payment, error) {
 	}
 
 	updatedPayment := &entity.Payment{}
-	if err := p.conn.Preload("States").First(updatedPayment, payment.ID).Error; err != nil {
+	if err := conn.Preload("States").Preload("FeeLines").First(updatedPayment, "id = ?", payment.ID).Error; err != nil {
 		return nil, err
 	}
 
 	return updatedPayment, nil
 }
-func (p *paymentRepo) GetByID(id uuid.UUID) (*entity.Payment, error) {
+func (p *paymentRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "paymentRepo.GetByID")
+	defer span.End()
+	conn := p.conn.WithContext(ctx)
 	var payment entity.Payment
-	if err := p.conn.Preload("States").First(&payment, "id = ?", id).Error; err != nil {
+	if err := conn.Preload("States").Preload("FeeLines").First(&payment, "id = ?", id).Error; err != nil {
 		return &entity.Payment{}, err
 	}
 	return &payment, nil
 }
 
-func (p *paymentRepo) CreateCard(card *entity.Card) error {
-	if err := p.conn.Create(card).Error; err != nil {
-		pgErr, _ := err.(*pgconn.PgError)
-		if pgErr.Code == invalidDuplicatedKeyErrorCode {
+func (p *paymentRepo) CreateCard(ctx context.Context, card *entity.Card) error {
+	ctx, span := tracing.Start(ctx, "paymentRepo.CreateCard")
+	defer span.End()
+	conn := p.conn.WithContext(ctx)
+	if err := conn.Create(card).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
 			return nil
 		}
 		return err
@@ -62,33 +79,59 @@ func (p *paymentRepo) CreateCard(card *entity.Card) error {
 	return nil
 }
 
-func (p *paymentRepo) GetCardByNumber(number string) (*entity.Card, error) {
+func (p *paymentRepo) GetCardByNumber(ctx context.Context, number string) (*entity.Card, error) {
+	ctx, span := tracing.Start(ctx, "paymentRepo.GetCardByNumber")
+	defer span.End()
+	conn := p.conn.WithContext(ctx)
 	var retrievedCard entity.Card
-	if err := p.conn.Where("number = ?", number).First(&retrievedCard).Error; err != nil {
+	if err := conn.Where("number = ?", number).First(&retrievedCard).Error; err != nil {
 		return nil, err
 	}
 	return &retrievedCard, nil
 }
 
-func (p *paymentRepo) GetMerchantByID(id uint) (*entity.Merchant, error) {
+func (p *paymentRepo) GetMerchantByID(ctx context.Context, id uint) (*entity.Merchant, error) {
+	ctx, span := tracing.Start(ctx, "paymentRepo.GetMerchantByID")
+	defer span.End()
+	conn := p.conn.WithContext(ctx)
 	var retrievedMerchant entity.Merchant
-	if err := p.conn.First(&retrievedMerchant, id).Error; err != nil {
+	if err := conn.First(&retrievedMerchant, id).Error; err != nil {
 		return nil, err
 	}
 	return &retrievedMerchant, nil
 }
 
-func (p *paymentRepo) UpdateCardAndMerchant(card *entity.Card, merchant *entity.Merchant) error {
-	tx := p.conn.Begin()
+func (p *paymentRepo) UpdateCardAndBalance(ctx context.Context, card *entity.Card, merchantID uint, delta float64, transactions ...entity.BalanceTransaction) error {
+	ctx, span := tracing.Start(ctx, "paymentRepo.UpdateCardAndBalance")
+	defer span.End()
+	conn := p.conn.WithContext(ctx)
+	tx := conn.Begin()
 
 	if err := tx.Save(card).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	if err := tx.Save(merchant).Error; err != nil {
+	// the balance is changed in place so concurrent payments and refunds of the merchant don't overwrite each other
+	query := tx.Model(&entity.Merchant{}).Where("id = ?", merchantID)
+	if delta < 0 {
+		query = query.Where("balance >= ?", -delta)
+	}
+	result := query.UpdateColumn("balance", gorm.Expr("balance + ?", delta))
+	if result.Error != nil {
 		tx.Rollback()
-		return err
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return repository.ErrInsufficientFunds
+	}
+
+	if len(transactions) > 0 {
+		if err := tx.Create(&transactions).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
 
 	if err := tx.Commit().Error; err != nil {
@@ -97,3 +140,132 @@ func (p *paymentRepo) UpdateCardAndMerchant(card *entity.Card, merchant *entity.
 
 	return nil
 }
+
+func (p *paymentRepo) List(ctx context.Context, filter repository.PaymentFilter) ([]entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "paymentRepo.List")
+	defer span.End()
+	conn := p.conn.WithContext(ctx)
+	query := conn.Model(&entity.Payment{}).Where("merchant_id = ?", filter.MerchantID)
+
+	if filter.State != "" {
+		query = query.Where("status = ?", filter.State)
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("amount <= ?", *filter.MaxAmount)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.CardLast4 != "" {
+		query = query.Where("card_last4 = ?", filter.CardLast4)
+	}
+	if filter.Customer != "" {
+		if personalID, err := strconv.ParseUint(filter.Customer, 10, 64); err == nil {
+			query = query.Where("customer_personal_id = ?", personalID)
+		} else {
+			query = query.Where("LOWER(customer_name) LIKE ?", "%"+strings.ToLower(filter.Customer)+"%")
+		}
+	}
+
+	column := repository.SortByCreatedAt
+	if filter.SortBy == repository.SortByAmount {
+		column = repository.SortByAmount
+	}
+	direction, comparator := "ASC", ">"
+	if filter.Descending {
+		direction, comparator = "DESC", "<"
+	}
+
+	if filter.After != nil {
+		var value interface{} = filter.After.CreatedAt
+		if column == repository.SortByAmount {
+			value = filter.After.Amount
+		}
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, comparator), value, filter.After.ID)
+	}
+
+	var payments []entity.Payment
+	err := query.Preload("States").Preload("FeeLines").
+		Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).
+		Limit(filter.Limit).
+		Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (p *paymentRepo) Search(ctx context.Context, search repository.PaymentSearch) ([]entity.Payment, error) {
+	ctx, span := tracing.Start(ctx, "paymentRepo.Search")
+	defer span.End()
+	conn := p.conn.WithContext(ctx)
+	query := conn.Model(&entity.Payment{}).Where("merchant_id = ?", search.MerchantID)
+
+	var order interface{} = "created_at DESC"
+	if p.conn.Dialector.Name() == "sqlite" {
+		query = sqliteSearch(query, search)
+	} else {
+		var err error
+		if query, order, err = postgresSearch(query, search); err != nil {
+			return nil, err
+		}
+	}
+
+	var payments []entity.Payment
+	if err := query.Preload("States").Order(order).Limit(search.Limit).Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// postgresSearch matches the metadata with the jsonb containment and every term as a word prefix of the search
+// vector, ranking the best matches first
+func postgresSearch(query *gorm.DB, search repository.PaymentSearch) (*gorm.DB, interface{}, error) {
+	if len(search.Metadata) > 0 {
+		raw, err := json.Marshal(search.Metadata)
+		if err != nil {
+			return nil, nil, err
+		}
+		query = query.Where("metadata @> ?::jsonb", string(raw))
+	}
+
+	var order interface{} = "created_at DESC"
+	if len(search.Terms) > 0 {
+		prefixes := make([]string, len(search.Terms))
+		for i, term := range search.Terms {
+			prefixes[i] = term + ":*"
+		}
+		tsQuery := strings.Join(prefixes, " & ")
+		query = query.Where("search_vector @@ to_tsquery('simple', ?)", tsQuery)
+		order = clause.OrderBy{Expression: clause.Expr{
+			SQL:                "ts_rank(search_vector, to_tsquery('simple', ?)) DESC, created_at DESC",
+			Vars:               []interface{}{tsQuery},
+			WithoutParentheses: true,
+		}}
+	}
+	return query, order, nil
+}
+
+// sqliteSearchText the searched columns of a payment, its metadata values are matched apart
+const sqliteSearchText = `lower(coalesce(description, '') || ' ' || coalesce(reference, '') || ' ' ||
+	coalesce(customer_name, '') || ' ' || coalesce(customer_personal_id, ''))`
+
+// sqliteSearch approximates the full-text search, which SQLite lacks, with every term contained in the searched
+// columns or in a metadata value
+func sqliteSearch(query *gorm.DB, search repository.PaymentSearch) *gorm.DB {
+	for key, value := range search.Metadata {
+		query = query.Where("EXISTS (SELECT 1 FROM json_each(payments.metadata) WHERE key = ? AND value = ?)", key, value)
+	}
+	for _, term := range search.Terms {
+		pattern := "%" + strings.ToLower(term) + "%"
+		query = query.Where("("+sqliteSearchText+" LIKE ? OR "+
+			"EXISTS (SELECT 1 FROM json_each(payments.metadata) WHERE lower(value) LIKE ?))", pattern, pattern)
+	}
+	return query
+}
@@ -0,0 +1,264 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	repo "github.com/alvarezcarlos/payment/app/infrastructure/postgres/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func createMerchant(t *testing.T, conn *gorm.DB, name string) *entity.Merchant {
+	t.Helper()
+	merchant, err := repo.NewMerchantRepository(conn).Create(context.Background(), &entity.Merchant{Name: name, Password: "hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return merchant
+}
+
+// createPayment stores a pending payment, created one millisecond after the previous one so the creation
+// order is stable at the database precision
+func createPayment(t *testing.T, payments repository.PaymentRepository, payment entity.Payment) *entity.Payment {
+	t.Helper()
+	time.Sleep(time.Millisecond)
+	payment.ID = uuid.New()
+	payment.Currency = "USD"
+	payment.CreatedAt, payment.UpdatedAt = time.Now(), time.Now()
+	payment.AddState(entity.Pending)
+	if err := payments.Create(context.Background(), &payment); err != nil {
+		t.Fatal(err)
+	}
+	return &payment
+}
+
+func TestPaymentRepositoryCreateAndUpdate(t *testing.T) {
+	conn := database(t)
+	payments := repo.NewPaymentRepository(conn)
+	merchant := createMerchant(t, conn, "acme")
+	created := createPayment(t, payments, entity.Payment{MerchantID: merchant.ID, Amount: 100,
+		Metadata: entity.Metadata{"order": "A1"}})
+
+	if _, err := payments.GetByID(context.Background(), uuid.New()); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("unknown payment: error = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+
+	stored, err := payments.GetByID(context.Background(), created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.CurrentState() != entity.Pending || len(stored.States) != 1 || stored.Metadata["order"] != "A1" {
+		t.Fatalf("payment not stored: %+v", stored)
+	}
+
+	stored.AddState(entity.Succeeded)
+	stored.FeeLines = append(stored.FeeLines, entity.FeeLine{Type: entity.FeeProcessing, Amount: 3.2})
+	stored.FeeAmount, stored.NetAmount = 3.2, 96.8
+	updated, err := payments.Update(context.Background(), stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.CurrentState() != entity.Succeeded || len(updated.States) != 2 || len(updated.FeeLines) != 1 ||
+		updated.NetAmount != 96.8 {
+		t.Errorf("update not persisted: %+v", updated)
+	}
+}
+
+func TestPaymentRepositoryCards(t *testing.T) {
+	conn := database(t)
+	payments := repo.NewPaymentRepository(conn)
+	merchant := createMerchant(t, conn, "acme")
+	ctx := context.Background()
+
+	if err := payments.CreateCard(ctx, &entity.Card{Number: "4242424242424242", Balance: 500}); err != nil {
+		t.Fatal(err)
+	}
+	// an existing card is kept as is
+	if err := payments.CreateCard(ctx, &entity.Card{Number: "4242424242424242", Balance: 1}); err != nil {
+		t.Fatalf("creating an existing card: %v", err)
+	}
+	card, err := payments.GetCardByNumber(ctx, "4242424242424242")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Balance != 500 {
+		t.Fatalf("card balance = %v, want 500", card.Balance)
+	}
+	if _, err = payments.GetCardByNumber(ctx, "4000000000000002"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("unknown card: error = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+
+	payment := createPayment(t, payments, entity.Payment{MerchantID: merchant.ID, Amount: 100})
+	card.Balance -= 100
+	err = payments.UpdateCardAndBalance(ctx, card, merchant.ID, 96.8,
+		entity.BalanceTransaction{MerchantID: merchant.ID, PaymentID: &payment.ID, Type: entity.TransactionPayment, Amount: 100},
+		entity.BalanceTransaction{MerchantID: merchant.ID, PaymentID: &payment.ID, Type: entity.TransactionFee, Amount: -3.2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if card, err = payments.GetCardByNumber(ctx, card.Number); err != nil || card.Balance != 400 {
+		t.Errorf("card balance = %v (%v), want 400", card.Balance, err)
+	}
+	if stored, err := payments.GetMerchantByID(ctx, merchant.ID); err != nil || stored.Balance != 96.8 {
+		t.Errorf("merchant balance = %v (%v), want 96.8", stored.Balance, err)
+	}
+	var transactions int64
+	if err = conn.Model(&entity.BalanceTransaction{}).Where("payment_id = ?", payment.ID).Count(&transactions).Error; err != nil {
+		t.Fatal(err)
+	}
+	if transactions != 2 {
+		t.Errorf("balance transactions = %d, want 2", transactions)
+	}
+
+	// a debit larger than the balance changes nothing
+	err = payments.UpdateCardAndBalance(ctx, card, merchant.ID, -100,
+		entity.BalanceTransaction{MerchantID: merchant.ID, PaymentID: &payment.ID, Type: entity.TransactionRefund, Amount: -100})
+	if !errors.Is(err, repository.ErrInsufficientFunds) {
+		t.Fatalf("debit over the balance: error = %v, want %v", err, repository.ErrInsufficientFunds)
+	}
+	if stored, err := payments.GetMerchantByID(ctx, merchant.ID); err != nil || stored.Balance != 96.8 {
+		t.Errorf("merchant balance = %v (%v), want 96.8", stored.Balance, err)
+	}
+	if err = payments.UpdateCardAndBalance(ctx, card, merchant.ID, -96.8); err != nil {
+		t.Errorf("debit of the whole balance: %v", err)
+	}
+}
+
+func TestPaymentRepositoryList(t *testing.T) {
+	conn := database(t)
+	payments := repo.NewPaymentRepository(conn)
+	merchant := createMerchant(t, conn, "acme")
+	other := createMerchant(t, conn, "globex")
+	for _, amount := range []float64{30, 10, 50, 20, 40} {
+		createPayment(t, payments, entity.Payment{MerchantID: merchant.ID, Amount: amount, CardLast4: "4242",
+			CustomerName: "Jane Doe", CustomerPersonalID: 30111222})
+	}
+	createPayment(t, payments, entity.Payment{MerchantID: other.ID, Amount: 60})
+	minAmount := 25.0
+
+	tests := []struct {
+		name      string
+		filter    repository.PaymentFilter
+		wantPages [][]float64
+	}{
+		{
+			name:      "by amount ascending",
+			filter:    repository.PaymentFilter{SortBy: repository.SortByAmount, Limit: 2},
+			wantPages: [][]float64{{10, 20}, {30, 40}, {50}},
+		},
+		{
+			name:      "by amount descending",
+			filter:    repository.PaymentFilter{SortBy: repository.SortByAmount, Descending: true, Limit: 3},
+			wantPages: [][]float64{{50, 40, 30}, {20, 10}},
+		},
+		{
+			name:      "newest first",
+			filter:    repository.PaymentFilter{Descending: true, Limit: 4},
+			wantPages: [][]float64{{40, 20, 50, 10}, {30}},
+		},
+		{
+			name:      "minimum amount",
+			filter:    repository.PaymentFilter{SortBy: repository.SortByAmount, MinAmount: &minAmount, Limit: 10},
+			wantPages: [][]float64{{30, 40, 50}},
+		},
+		{
+			name:      "customer name",
+			filter:    repository.PaymentFilter{SortBy: repository.SortByAmount, Customer: "jane", Limit: 10},
+			wantPages: [][]float64{{10, 20, 30, 40, 50}},
+		},
+		{
+			name:      "customer personal id",
+			filter:    repository.PaymentFilter{SortBy: repository.SortByAmount, Customer: "1", Limit: 10},
+			wantPages: [][]float64{{}},
+		},
+		{
+			name:      "state",
+			filter:    repository.PaymentFilter{State: entity.Succeeded, Limit: 10},
+			wantPages: [][]float64{{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			filter.MerchantID = merchant.ID
+			for i, want := range tt.wantPages {
+				page, err := payments.List(context.Background(), filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := amounts(page)
+				if !equalAmounts(got, want) {
+					t.Fatalf("page %d = %v, want %v", i, got, want)
+				}
+				if len(page) > 0 {
+					last := page[len(page)-1]
+					filter.After = &repository.PaymentCursor{ID: last.ID, CreatedAt: last.CreatedAt, Amount: last.Amount}
+				}
+			}
+		})
+	}
+}
+
+func TestPaymentRepositorySearch(t *testing.T) {
+	conn := database(t)
+	payments := repo.NewPaymentRepository(conn)
+	merchant := createMerchant(t, conn, "acme")
+	createPayment(t, payments, entity.Payment{MerchantID: merchant.ID, Amount: 10, Description: "Coffee beans",
+		Reference: "INV-001", Metadata: entity.Metadata{"order": "A1"}})
+	createPayment(t, payments, entity.Payment{MerchantID: merchant.ID, Amount: 20, Description: "Green tea",
+		Reference: "INV-002", Metadata: entity.Metadata{"order": "B2", "channel": "web"}})
+
+	tests := []struct {
+		name     string
+		terms    []string
+		metadata map[string]string
+		want     []float64
+	}{
+		{name: "word", terms: []string{"coffee"}, want: []float64{10}},
+		{name: "prefix", terms: []string{"cof"}, want: []float64{10}},
+		{name: "every term", terms: []string{"green", "tea"}, want: []float64{20}},
+		{name: "hyphenated reference", terms: []string{"inv", "002"}, want: []float64{20}},
+		{name: "metadata value", terms: []string{"web"}, want: []float64{20}},
+		{name: "metadata pair", metadata: map[string]string{"order": "A1"}, want: []float64{10}},
+		{name: "no match", terms: []string{"milk"}},
+		{name: "everything newest first", want: []float64{20, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := payments.Search(context.Background(), repository.PaymentSearch{
+				MerchantID: merchant.ID, Terms: tt.terms, Metadata: tt.metadata, Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := amounts(found); !equalAmounts(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func amounts(payments []entity.Payment) []float64 {
+	got := []float64{}
+	for _, payment := range payments {
+		got = append(got, payment.Amount)
+	}
+	return got
+}
+
+func equalAmounts(got, want []float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
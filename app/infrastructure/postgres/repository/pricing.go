@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type pricingRepo struct {
+	conn *gorm.DB
+}
+
+func NewPricingRepository(conn *gorm.DB) repository.PricingRepository {
+	return &pricingRepo{conn: conn}
+}
+
+func (p *pricingRepo) GetByMerchant(ctx context.Context, merchantID uint) (*entity.PricingPlan, error) {
+	var plan entity.PricingPlan
+	err := p.conn.WithContext(ctx).Preload("Rules").Where("merchant_id = ?", merchantID).First(&plan).Error
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// Save creates or replaces the merchant plan together with its rules
+func (p *pricingRepo) Save(ctx context.Context, plan *entity.PricingPlan) error {
+	return p.conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing entity.PricingPlan
+		err := tx.Where("merchant_id = ?", plan.MerchantID).First(&existing).Error
+		switch {
+		case err == nil:
+			plan.ID, plan.CreatedAt = existing.ID, existing.CreatedAt
+			if err = tx.Where("plan_id = ?", existing.ID).Delete(&entity.PricingRule{}).Error; err != nil {
+				return err
+			}
+		case err != gorm.ErrRecordNotFound:
+			return err
+		}
+
+		for i := range plan.Rules {
+			plan.Rules[i].ID = 0
+		}
+		return tx.Save(plan).Error
+	})
+}
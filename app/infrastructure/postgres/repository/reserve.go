@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type reserveRepo struct {
+	conn *gorm.DB
+}
+
+func NewReserveRepository(conn *gorm.DB) repository.ReserveRepository {
+	return &reserveRepo{conn: conn}
+}
+
+func (r *reserveRepo) GetPolicy(ctx context.Context, merchantID uint) (*entity.ReservePolicy, error) {
+	var policy entity.ReservePolicy
+	if err := r.conn.WithContext(ctx).First(&policy, "merchant_id = ?", merchantID).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *reserveRepo) SavePolicy(ctx context.Context, policy *entity.ReservePolicy) error {
+	return r.conn.WithContext(ctx).Save(policy).Error
+}
+
+// CreateHold stores the hold and its ledger debit atomically
+func (r *reserveRepo) CreateHold(ctx context.Context, hold *entity.BalanceHold, transaction entity.BalanceTransaction) error {
+	return r.conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(hold).Error; err != nil {
+			return err
+		}
+		return tx.Create(&transaction).Error
+	})
+}
+
+func (r *reserveRepo) GetHold(ctx context.Context, id uuid.UUID) (*entity.BalanceHold, error) {
+	var hold entity.BalanceHold
+	if err := r.conn.WithContext(ctx).First(&hold, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// ReleaseHold marks the hold as released and credits the ledger back atomically
+func (r *reserveRepo) ReleaseHold(ctx context.Context, hold *entity.BalanceHold, transaction entity.BalanceTransaction) error {
+	return r.conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.BalanceHold{}).
+			Where("id = ? AND released_at IS NULL", hold.ID).
+			Update("released_at", hold.ReleasedAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(&transaction).Error
+	})
+}
+
+func (r *reserveRepo) ListHolds(ctx context.Context, merchantID uint) ([]entity.BalanceHold, error) {
+	var holds []entity.BalanceHold
+	err := r.conn.WithContext(ctx).Where("merchant_id = ?", merchantID).Order("created_at DESC").Find(&holds).Error
+	return holds, err
+}
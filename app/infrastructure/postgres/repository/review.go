@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type reviewRepo struct {
+	conn *gorm.DB
+}
+
+func NewReviewRepository(conn *gorm.DB) repository.ReviewRepository {
+	return &reviewRepo{conn: conn}
+}
+
+func (r *reviewRepo) Create(ctx context.Context, review *entity.Review) error {
+	return r.conn.WithContext(ctx).Create(review).Error
+}
+
+func (r *reviewRepo) GetByID(ctx context.Context, id uuid.UUID) (*entity.Review, error) {
+	var review entity.Review
+	if err := r.conn.WithContext(ctx).First(&review, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// List the reviews with the status, oldest first so the queue is worked in order
+func (r *reviewRepo) List(ctx context.Context, status entity.ReviewStatusEnum, limit int) ([]entity.Review, error) {
+	query := r.conn.WithContext(ctx).Order("created_at ASC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var reviews []entity.Review
+	err := query.Find(&reviews).Error
+	return reviews, err
+}
+
+// Decide stores the decision only while the review is still pending, so two reviewers can't decide on it
+func (r *reviewRepo) Decide(ctx context.Context, review *entity.Review) error {
+	result := r.conn.WithContext(ctx).Model(&entity.Review{}).
+		Where("id = ? AND status = ?", review.ID, entity.ReviewPending).
+		Updates(map[string]interface{}{
+			"status":     review.Status,
+			"reviewer":   review.Reviewer,
+			"note":       review.Note,
+			"decided_at": review.DecidedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *reviewRepo) Update(ctx context.Context, review *entity.Review) error {
+	return r.conn.WithContext(ctx).Save(review).Error
+}
+
+// Overdue the pending reviews past their deadline
+func (r *reviewRepo) Overdue(ctx context.Context, asOf time.Time) ([]entity.Review, error) {
+	var reviews []entity.Review
+	err := r.conn.WithContext(ctx).Where("status = ? AND due_at < ?", entity.ReviewPending, asOf).Find(&reviews).Error
+	return reviews, err
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type riskRepo struct {
+	conn *gorm.DB
+}
+
+func NewRiskRepository(conn *gorm.DB) repository.RiskRepository {
+	return &riskRepo{conn: conn}
+}
+
+func (r *riskRepo) CreateAttempt(ctx context.Context, attempt *entity.PaymentAttempt) error {
+	return r.conn.WithContext(ctx).Create(attempt).Error
+}
+
+// CountFailedAttempts the rejected attempts of the card since the given time
+func (r *riskRepo) CountFailedAttempts(ctx context.Context, cardFingerprint string, since time.Time) (int64, error) {
+	var count int64
+	err := r.conn.WithContext(ctx).Model(&entity.PaymentAttempt{}).
+		Where("card_fingerprint = ? AND status = ? AND created_at >= ?", cardFingerprint, entity.Rejected, since).
+		Count(&count).Error
+	return count, err
+}
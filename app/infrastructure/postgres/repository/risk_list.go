@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type riskListRepo struct {
+	conn *gorm.DB
+}
+
+func NewRiskListRepository(conn *gorm.DB) repository.RiskListRepository {
+	return &riskListRepo{conn: conn}
+}
+
+func (r *riskListRepo) Create(ctx context.Context, entry *entity.RiskListEntry) error {
+	if err := r.conn.WithContext(ctx).Create(entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return repository.ErrDuplicated
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *riskListRepo) Delete(ctx context.Context, id uuid.UUID, merchantID uint) error {
+	result := r.conn.WithContext(ctx).Where("id = ? AND merchant_id = ?", id, merchantID).Delete(&entity.RiskListEntry{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *riskListRepo) ListByMerchant(ctx context.Context, merchantID uint) ([]entity.RiskListEntry, error) {
+	var entries []entity.RiskListEntry
+	err := r.conn.WithContext(ctx).Where("merchant_id = ?", merchantID).Order("created_at DESC").Find(&entries).Error
+	return entries, err
+}
+
+func (r *riskListRepo) CountByMerchant(ctx context.Context, merchantID uint) (int64, error) {
+	var count int64
+	err := r.conn.WithContext(ctx).Model(&entity.RiskListEntry{}).Where("merchant_id = ?", merchantID).Count(&count).Error
+	return count, err
+}
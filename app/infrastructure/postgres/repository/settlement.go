@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type settlementRepo struct {
+	conn *gorm.DB
+}
+
+func NewSettlementRepository(conn *gorm.DB) repository.SettlementRepository {
+	return &settlementRepo{conn: conn}
+}
+
+// MerchantsWithAvailableFunds leaves out the suspended merchants, their funds are held until reactivated
+func (s *settlementRepo) MerchantsWithAvailableFunds(ctx context.Context, asOf time.Time) ([]uint, error) {
+	var ids []uint
+	err := s.conn.WithContext(ctx).Model(&entity.BalanceTransaction{}).
+		Where("payout_id IS NULL AND available_on <= ?", asOf).
+		Where("merchant_id NOT IN (SELECT id FROM merchants WHERE suspended_at IS NOT NULL)").
+		Distinct().
+		Pluck("merchant_id", &ids).Error
+	return ids, err
+}
+
+func (s *settlementRepo) AvailableTransactions(ctx context.Context, merchantID uint, asOf time.Time) ([]entity.BalanceTransaction, error) {
+	var transactions []entity.BalanceTransaction
+	err := s.conn.WithContext(ctx).
+		Where("merchant_id = ? AND payout_id IS NULL AND available_on <= ?", merchantID, asOf).
+		Order("id").
+		Find(&transactions).Error
+	return transactions, err
+}
+
+// PayoutExists batch dates are stored as the UTC day, the date is truncated to its UTC midnight to match them
+func (s *settlementRepo) PayoutExists(ctx context.Context, merchantID uint, batchDate time.Time) (bool, error) {
+	batchDate = batchDate.UTC()
+	batchDate = time.Date(batchDate.Year(), batchDate.Month(), batchDate.Day(), 0, 0, 0, 0, time.UTC)
+	var count int64
+	err := s.conn.WithContext(ctx).Model(&entity.Payout{}).
+		Where("merchant_id = ? AND batch_date = ?", merchantID, batchDate).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreatePayout stores the payout, marks the transactions as settled by it and debits the merchant balance atomically.
+// It fails without changes when any of the transactions was settled by another payout in the meantime
+func (s *settlementRepo) CreatePayout(ctx context.Context, payout *entity.Payout, transactions []entity.BalanceTransaction) error {
+	return s.conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(payout).Error; err != nil {
+			return err
+		}
+
+		ids := make([]uint, len(transactions))
+		for i, transaction := range transactions {
+			ids[i] = transaction.ID
+		}
+		result := tx.Model(&entity.BalanceTransaction{}).
+			Where("id IN ? AND payout_id IS NULL", ids).
+			Update("payout_id", payout.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(ids)) {
+			return repository.ErrAlreadySettled
+		}
+
+		debit := entity.BalanceTransaction{
+			MerchantID:  payout.MerchantID,
+			PayoutID:    &payout.ID,
+			Type:        entity.TransactionPayout,
+			Amount:      -payout.Amount,
+			AvailableOn: payout.CreatedAt,
+			CreatedAt:   payout.CreatedAt,
+		}
+		if err := tx.Create(&debit).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&entity.Merchant{}).
+			Where("id = ?", payout.MerchantID).
+			Update("balance", gorm.Expr("balance - ?", payout.Amount)).Error
+	})
+}
+
+func (s *settlementRepo) ListPayouts(ctx context.Context, merchantID uint, limit int) ([]entity.Payout, error) {
+	var payouts []entity.Payout
+	// the merchant reports tolerate a replication lag
+	err := s.conn.WithContext(repository.ReadOnly(ctx)).Where("merchant_id = ?", merchantID).
+		Order("batch_date DESC").
+		Limit(limit).
+		Find(&payouts).Error
+	return payouts, err
+}
+
+func (s *settlementRepo) Balance(ctx context.Context, merchantID uint, asOf time.Time) (*entity.MerchantBalance, error) {
+	reader := s.conn.WithContext(repository.ReadOnly(ctx))
+	var merchant entity.Merchant
+	if err := reader.Select("balance").First(&merchant, merchantID).Error; err != nil {
+		return nil, err
+	}
+
+	balance := &entity.MerchantBalance{}
+	err := reader.Model(&entity.BalanceTransaction{}).
+		Select("COALESCE(SUM(CASE WHEN available_on <= ? THEN amount ELSE 0 END), 0) AS available, "+
+			"COALESCE(SUM(CASE WHEN available_on > ? AND type <> ? THEN amount ELSE 0 END), 0) AS pending, "+
+			"COALESCE(SUM(CASE WHEN available_on > ? AND type = ? THEN amount ELSE 0 END), 0) AS reserved",
+			asOf, asOf, entity.TransactionReserveRelease, asOf, entity.TransactionReserveRelease).
+		Where("merchant_id = ? AND payout_id IS NULL", merchantID).
+		Scan(balance).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = reader.Model(&entity.BalanceHold{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("merchant_id = ? AND released_at IS NULL", merchantID).
+		Scan(&balance.Held).Error
+	if err != nil {
+		return nil, err
+	}
+	balance.Balance = merchant.Balance
+	return balance, nil
+}
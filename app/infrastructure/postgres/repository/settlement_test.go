@@ -0,0 +1,74 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	repo "github.com/alvarezcarlos/payment/app/infrastructure/postgres/repository"
+	"github.com/google/uuid"
+)
+
+func TestSettlementRepositoryCreatePayout(t *testing.T) {
+	conn := database(t)
+	settlements := repo.NewSettlementRepository(conn)
+	merchant := createMerchant(t, conn, "acme")
+	if err := conn.Model(merchant).Update("balance", 30).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	batchDate := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	transactions := []entity.BalanceTransaction{
+		{MerchantID: merchant.ID, Type: entity.TransactionPayment, Amount: 10, AvailableOn: batchDate},
+		{MerchantID: merchant.ID, Type: entity.TransactionPayment, Amount: 20, AvailableOn: batchDate},
+	}
+	if err := conn.Create(&transactions).Error; err != nil {
+		t.Fatal(err)
+	}
+	payout := func(date time.Time) *entity.Payout {
+		return &entity.Payout{ID: uuid.New(), MerchantID: merchant.ID, BatchDate: date, BankAccountID: uuid.New(),
+			Amount: 30, Status: entity.PayoutPaid, CreatedAt: time.Now()}
+	}
+
+	if err := settlements.CreatePayout(context.Background(), payout(batchDate), transactions); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("payout of the day", func(t *testing.T) {
+		// the same day seen from another time zone
+		local := batchDate.Add(15 * time.Hour).In(time.FixedZone("UTC-3", -3*60*60))
+		for _, date := range []time.Time{batchDate, local} {
+			exists, err := settlements.PayoutExists(context.Background(), merchant.ID, date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !exists {
+				t.Errorf("payout of %v not found", date)
+			}
+		}
+	})
+
+	t.Run("transactions already settled", func(t *testing.T) {
+		err := settlements.CreatePayout(context.Background(), payout(batchDate.AddDate(0, 0, 1)), transactions)
+		if !errors.Is(err, repository.ErrAlreadySettled) {
+			t.Fatalf("error = %v, want %v", err, repository.ErrAlreadySettled)
+		}
+		var payouts int64
+		if err = conn.Model(&entity.Payout{}).Count(&payouts).Error; err != nil {
+			t.Fatal(err)
+		}
+		if payouts != 1 {
+			t.Errorf("payouts = %d, want 1, the second payout must be rolled back", payouts)
+		}
+		var stored entity.Merchant
+		if err = conn.First(&stored, merchant.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored.Balance != 0 {
+			t.Errorf("merchant balance = %v, want 0", stored.Balance)
+		}
+	})
+}
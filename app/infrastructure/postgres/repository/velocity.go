@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"gorm.io/gorm"
+)
+
+type velocityRepo struct {
+	conn *gorm.DB
+}
+
+// NewVelocityStore keeps the velocity counters in postgres so every instance sees the same windows
+func NewVelocityStore(conn *gorm.DB) repository.VelocityStore {
+	return &velocityRepo{conn: conn}
+}
+
+func (v *velocityRepo) Add(ctx context.Context, key string, value float64, at time.Time, ttl time.Duration) error {
+	event := &entity.VelocityEvent{Key: key, Value: value, CreatedAt: at, ExpiresAt: at.Add(ttl)}
+	return v.conn.WithContext(ctx).Create(event).Error
+}
+
+func (v *velocityRepo) Sum(ctx context.Context, key string, since time.Time) (float64, error) {
+	var sum float64
+	err := v.conn.WithContext(ctx).Model(&entity.VelocityEvent{}).
+		Select("COALESCE(SUM(value), 0)").
+		Where("key = ? AND created_at >= ?", key, since).
+		Scan(&sum).Error
+	return sum, err
+}
+
+func (v *velocityRepo) Prune(ctx context.Context, asOf time.Time) error {
+	return v.conn.WithContext(ctx).Where("expires_at <= ?", asOf).Delete(&entity.VelocityEvent{}).Error
+}
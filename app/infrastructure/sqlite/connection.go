@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/config"
+	driver "github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// busyTimeout time a statement waits for the lock held by another writer before failing
+const busyTimeout = 5 * time.Second
+
+// Connection the SQLite counterpart of the postgres connection, the database is a local file
+type Connection struct {
+	dsn        string
+	settings   config.DBConfig
+	config     *gorm.Config
+	connection *gorm.DB
+	logger     *slog.Logger
+}
+
+// NewConnection the database file named by dbConfig.Name, created when it doesn't exist
+func NewConnection(dbConfig config.DBConfig, conf *gorm.Config, logger *slog.Logger) *Connection {
+	return &Connection{
+		dsn:      dsn(dbConfig.Name),
+		settings: dbConfig,
+		config:   conf,
+		logger:   logger,
+	}
+}
+
+// dsn the file with the foreign keys enforced and the write-ahead log, so readers don't block the writer.
+// Transactions take the write lock when they begin, waiting for it instead of failing to upgrade a read lock
+func dsn(path string) string {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+	params.Set("_txlock", "immediate")
+	return path + "?" + params.Encode()
+}
+
+func (s *Connection) GetConnection() *gorm.DB {
+	if s.connection != nil {
+		return s.connection
+	}
+	s.config.TranslateError = true
+	db, err := gorm.Open(driver.Open(s.dsn), s.config)
+	if err != nil {
+		panic(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic(err)
+	}
+	sqlDB.SetMaxOpenConns(s.settings.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(s.settings.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(s.settings.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(s.settings.ConnMaxIdleTime)
+	s.connection = db
+	return db
+}
+
+// WaitUntilAvailable opens the database file, there is no server to wait for
+func (s *Connection) WaitUntilAvailable(ctx context.Context) error {
+	sqlDB, err := s.GetConnection().DB()
+	if err != nil {
+		return err
+	}
+	if err = sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("opening the sqlite database %s: %w", s.settings.Name, err)
+	}
+	s.logger.Debug(" =======> db connected")
+	return nil
+}
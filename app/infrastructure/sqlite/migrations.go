@@ -0,0 +1,19 @@
+package sqlite
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql
+var files embed.FS
+
+// Migrations the postgres migrations translated to SQLite under the same versions, named
+// <version>_<name>.up.sql and <version>_<name>.down.sql
+func Migrations() fs.FS {
+	sub, err := fs.Sub(files, "migrations")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,3 @@
+-- The full-text search of the postgres schema has no SQLite counterpart, the payments are searched with LIKE
+-- patterns over the existing columns. The version is kept so both dialects apply the same ones
+SELECT 1;
@@ -0,0 +1 @@
+DELETE FROM states WHERE id BETWEEN 1 AND 7 AND id NOT IN (SELECT state_id FROM payment_states);
@@ -0,0 +1,10 @@
+-- Payment states, the ids are the ones entity.SetState assigns
+INSERT INTO states (id, name) VALUES
+    (1, 'Pending'),
+    (2, 'Rejected'),
+    (3, 'Succeeded'),
+    (4, 'Refunded'),
+    (5, 'Disputed'),
+    (6, 'InReview'),
+    (7, 'RequiresAction')
+ON CONFLICT (id) DO UPDATE SET name = excluded.name;
@@ -0,0 +1,2 @@
+ALTER TABLE merchants DROP COLUMN suspension_reason;
+ALTER TABLE merchants DROP COLUMN suspended_at;
@@ -0,0 +1,3 @@
+-- Merchants suspended by an operator from the admin command
+ALTER TABLE merchants ADD COLUMN suspended_at datetime;
+ALTER TABLE merchants ADD COLUMN suspension_reason text;
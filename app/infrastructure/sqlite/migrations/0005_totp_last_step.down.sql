@@ -0,0 +1 @@
+ALTER TABLE merchants DROP COLUMN totp_last_step;
@@ -0,0 +1,64 @@
+package velocity
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+)
+
+type event struct {
+	value     float64
+	at        time.Time
+	expiresAt time.Time
+}
+
+type memoryStore struct {
+	mu     sync.Mutex
+	events map[string][]event
+}
+
+// NewMemoryStore keeps the velocity counters in the process memory, they are lost on restart and
+// not shared between instances
+func NewMemoryStore() repository.VelocityStore {
+	return &memoryStore{events: map[string][]event{}}
+}
+
+func (m *memoryStore) Add(_ context.Context, key string, value float64, at time.Time, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events[key] = append(m.events[key], event{value: value, at: at, expiresAt: at.Add(ttl)})
+	return nil
+}
+
+func (m *memoryStore) Sum(_ context.Context, key string, since time.Time) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sum float64
+	for _, e := range m.events[key] {
+		if !e.at.Before(since) {
+			sum += e.value
+		}
+	}
+	return sum, nil
+}
+
+func (m *memoryStore) Prune(_ context.Context, asOf time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, events := range m.events {
+		kept := events[:0]
+		for _, e := range events {
+			if e.expiresAt.After(asOf) {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == 0 {
+			delete(m.events, key)
+			continue
+		}
+		m.events[key] = kept
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/spf13/cobra"
+)
+
+// NewKeysCommand rotates the encryption keys of the data at rest
+func NewKeysCommand(bankAccounts application.BankAccountUseCaseInterface) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage the encryption keys",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "rotate",
+		Short: "Re-encrypt the stored bank account numbers with ENCRYPTION_KEY_ID",
+		Long: "Re-encrypt the stored bank account numbers with the current key ENCRYPTION_KEY_ID. Add the new key to\n" +
+			"ENCRYPTION_KEYS and make it current first, the older keys can be removed once the rotation finished.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			rotated, err := bankAccounts.RotateKeys(cmd.Context())
+			cmd.Printf("%d bank accounts re-encrypted\n", rotated)
+			return err
+		},
+	})
+	return cmd
+}
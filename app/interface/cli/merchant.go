@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/spf13/cobra"
+)
+
+// NewMerchantCommand creates, suspends and reactivates merchants
+func NewMerchantCommand(useCase application.MerchantUseCaseInterface) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merchants",
+		Short: "Manage the merchant accounts",
+	}
+
+	create := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a merchant, the password is read from stdin unless --password is given",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			password, _ := cmd.Flags().GetString("password")
+			if password == "" {
+				line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				password = strings.TrimSpace(line)
+				if password == "" {
+					return errors.Join(errors.New("password not provided"), err)
+				}
+			}
+			merchant, err := useCase.Create(cmd.Context(), &entity.Merchant{Name: args[0], Password: password})
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd, merchant)
+		},
+	}
+	create.Flags().String("password", "", "password of the merchant, prefer stdin to keep it out of the shell history")
+	cmd.AddCommand(create)
+
+	suspend := &cobra.Command{
+		Use:   "suspend <name>",
+		Short: "Block the login and the payment creation of a merchant",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reason, _ := cmd.Flags().GetString("reason")
+			merchant, err := useCase.Suspend(cmd.Context(), args[0], reason)
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd, merchant)
+		},
+	}
+	suspend.Flags().String("reason", "", "reason of the suspension")
+	_ = suspend.MarkFlagRequired("reason")
+	cmd.AddCommand(suspend)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "reactivate <name>",
+		Short: "Lift the suspension of a merchant",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			merchant, err := useCase.Reactivate(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd, merchant)
+		},
+	})
+	return cmd
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/infrastructure/postgres/connection"
+	"github.com/spf13/cobra"
+)
+
+const databaseWaitTimeout = time.Minute
+
+// NewMigrateCommand applies and reverts the embedded database migrations
+func NewMigrateCommand(db connection.Connection, migrator connection.MigrateInterface) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the database schema migrations",
+		// the database can still be starting when the migrations run from a deployment job
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), databaseWaitTimeout)
+			defer cancel()
+			if err := db.WaitUntilAvailable(ctx); err != nil {
+				return fmt.Errorf("database unavailable: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "up",
+		Short: "Apply the pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			applied, err := migrator.Up()
+			cmd.Printf("%d migrations applied\n", applied)
+			return err
+		},
+	})
+
+	down := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the last applied migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			steps, _ := cmd.Flags().GetInt("steps")
+			if steps < 1 {
+				return fmt.Errorf("invalid number of migrations %d", steps)
+			}
+			reverted, err := migrator.Down(steps)
+			cmd.Printf("%d migrations reverted\n", reverted)
+			return err
+		},
+	}
+	down.Flags().IntP("steps", "n", 1, "number of migrations to revert")
+	cmd.AddCommand(down)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "List the migrations and when they were applied",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			statuses, err := migrator.Status()
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+			for _, status := range statuses {
+				appliedAt := "pending"
+				if status.AppliedAt != nil {
+					appliedAt = status.AppliedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+			}
+			return w.Flush()
+		},
+	})
+	return cmd
+}
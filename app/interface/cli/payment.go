@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// NewPaymentCommand inspects payments and repairs their state
+func NewPaymentCommand(useCase application.PaymentUseCaseInterface) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "payments",
+		Short: "Inspect and repair payments",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "inspect <payment id>",
+		Short: "Show a payment with its state history",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := uuid.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid payment id: %w", err)
+			}
+			payment, err := useCase.Inspect(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd, payment)
+		},
+	})
+
+	transition := &cobra.Command{
+		Use:   "transition <payment id>",
+		Short: "Force a payment into a state without going through the acquirer, no funds are moved",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := uuid.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid payment id: %w", err)
+			}
+			state, _ := cmd.Flags().GetString("to")
+			reason, _ := cmd.Flags().GetString("reason")
+			payment, err := useCase.ForceTransition(cmd.Context(), id, entity.StateEnum(state), operator(cmd), reason)
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd, payment)
+		},
+	}
+	transition.Flags().String("to", "", "target state: Pending, Rejected, Succeeded, Refunded, Disputed, InReview or RequiresAction")
+	transition.Flags().String("reason", "", "reason of the transition, recorded in the logs")
+	transition.Flags().String("operator", "", "operator running the transition, defaults to the system user")
+	_ = transition.MarkFlagRequired("to")
+	_ = transition.MarkFlagRequired("reason")
+	cmd.AddCommand(transition)
+	return cmd
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// NewRootCommand the command of the binary, serve runs the API server and is the default when no command is given
+func NewRootCommand(serve func(cmd *cobra.Command, args []string) error, commands ...*cobra.Command) *cobra.Command {
+	root := &cobra.Command{
+		Use:          "app",
+		Short:        "Payments platform API server and operations commands",
+		Args:         cobra.NoArgs,
+		RunE:         serve,
+		SilenceUsage: true,
+	}
+	root.AddCommand(&cobra.Command{
+		Use:   "serve",
+		Short: "Run the API server",
+		Args:  cobra.NoArgs,
+		RunE:  serve,
+	})
+	root.AddCommand(commands...)
+	return root
+}
+
+// printJSON writes v indented to the command output
+func printJSON(cmd *cobra.Command, v any) error {
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// operator identifies who runs a command in the audit logs, the --operator flag or the system user
+func operator(cmd *cobra.Command) string {
+	if name, _ := cmd.Flags().GetString("operator"); name != "" {
+		return name
+	}
+	return os.Getenv("USER")
+}
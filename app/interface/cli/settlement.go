@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/spf13/cobra"
+)
+
+// NewSettlementCommand runs the payout batch outside of the worker schedule
+func NewSettlementCommand(useCase application.SettlementUseCaseInterface) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "settlement",
+		Short: "Manage the merchant payouts",
+	}
+
+	run := &cobra.Command{
+		Use:   "run",
+		Short: "Create the payout batch, merchants already paid out that day are skipped",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			asOf := time.Now()
+			if date, _ := cmd.Flags().GetString("as-of"); date != "" {
+				parsed, err := time.ParseInLocation(time.DateOnly, date, time.Local)
+				if err != nil {
+					return fmt.Errorf("invalid date %s, expected YYYY-MM-DD", date)
+				}
+				// the whole day is settled, funds becoming available until its end included
+				asOf = parsed.Add(24*time.Hour - time.Nanosecond)
+			}
+			payouts, err := useCase.Run(cmd.Context(), asOf)
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd, payouts)
+		},
+	}
+	run.Flags().String("as-of", "", "day of the batch (YYYY-MM-DD), defaults to now")
+	cmd.AddCommand(run)
+	return cmd
+}
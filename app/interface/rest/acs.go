@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/interface/rest/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const challengePage = `<!DOCTYPE html>
+<html>
+<head><title>3-D Secure</title></head>
+<body>
+<h1>Verify your purchase</h1>
+<p>Enter the code sent by your bank to authorize the payment %s.</p>
+<form method="POST" action="%s">
+<input name="code" autocomplete="one-time-code" autofocus>
+<button type="submit">Submit</button>
+</form>
+</body>
+</html>`
+
+// ACSController simulated Access Control Server of the card issuer, it serves the 3-D Secure challenge page
+// and receives its result
+type ACSController struct {
+	useCase application.PaymentUseCaseInterface
+}
+
+func NewACSController(e *echo.Echo, useCase application.PaymentUseCaseInterface) *ACSController {
+	a := &ACSController{useCase: useCase}
+	g := e.Group("/api/3ds/acs")
+	g.GET("/:id/:transaction", a.Challenge)
+	g.POST("/:id/:transaction", a.Complete)
+	return a
+}
+
+func (a *ACSController) Challenge(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+	action := fmt.Sprintf("/api/3ds/acs/%s/%s", id, html.EscapeString(c.Param("transaction")))
+	return c.HTML(http.StatusOK, fmt.Sprintf(challengePage, id, action))
+}
+
+func (a *ACSController) Complete(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	payment, err := a.useCase.CompleteAuthentication(c.Request().Context(), id, c.Param("transaction"), c.FormValue("code"))
+	switch {
+	case errors.Is(err, application.ErrPaymentNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	case errors.Is(err, application.ErrNoPendingChallenge):
+		return c.JSON(http.StatusConflict, map[string]string{"message": err.Error()})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, models.PaymentStatusResp{
+		ID:             payment.ID.String(),
+		Amount:         payment.Amount,
+		Status:         string(payment.CurrentState()),
+		DeclineCode:    payment.DeclineCode,
+		ThreeDSStatus:  string(payment.ThreeDSStatus),
+		LiabilityShift: payment.LiabilityShift,
+		CreatedAt:      payment.CreatedAt,
+	})
+}
@@ -0,0 +1,160 @@
+package rest
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/interface/rest/middelware"
+	"github.com/alvarezcarlos/payment/app/interface/rest/models"
+	"github.com/alvarezcarlos/payment/app/interface/rest/validation"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type BankAccountController struct {
+	useCase         application.BankAccountUseCaseInterface
+	customValidator validation.Validator
+}
+
+func NewBankAccountController(e *echo.Echo, useCase application.BankAccountUseCaseInterface,
+	customValidator validation.Validator,
+	middleware middelware.Middleware) *BankAccountController {
+	g := e.Group("/api/merchants/bank-accounts", middleware.JwtMiddleware)
+	b := &BankAccountController{useCase: useCase, customValidator: customValidator}
+	g.POST("", b.Create)
+	g.GET("", b.List)
+	g.POST("/:id/verify", b.Verify)
+	g.POST("/:id/default", b.SetDefault)
+	g.DELETE("/:id", b.Delete)
+	return b
+}
+
+func (b *BankAccountController) Create(c echo.Context) error {
+	req := models.BankAccountCreateReq{}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := b.customValidator.ValidateStruct(req); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	account, err := b.useCase.Create(c.Request().Context(), &entity.BankAccount{
+		MerchantID:    merchantID,
+		Type:          entity.BankAccountTypeEnum(req.Type),
+		HolderName:    req.HolderName,
+		Currency:      req.Currency,
+		RoutingNumber: req.RoutingNumber,
+	}, req.AccountNumber)
+	if errors.Is(err, application.ErrInvalidBankAccount) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, account)
+}
+
+func (b *BankAccountController) List(c echo.Context) error {
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := b.useCase.List(c.Request().Context(), merchantID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"bank_accounts": accounts})
+}
+
+func (b *BankAccountController) Verify(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	req := models.BankAccountVerifyReq{}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := b.customValidator.ValidateStruct(req); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	account, err := b.useCase.Verify(c.Request().Context(), id, merchantID, [2]float64{req.Amounts[0], req.Amounts[1]})
+	if err != nil {
+		return bankAccountError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, account)
+}
+
+func (b *BankAccountController) SetDefault(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	account, err := b.useCase.SetDefault(c.Request().Context(), id, merchantID)
+	if err != nil {
+		return bankAccountError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, account)
+}
+
+func (b *BankAccountController) Delete(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	if err = b.useCase.Delete(c.Request().Context(), id, merchantID); err != nil {
+		return bankAccountError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func bankAccountError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, application.ErrBankAccountNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	case errors.Is(err, application.ErrVerificationFailed),
+		errors.Is(err, application.ErrVerificationExhausted),
+		errors.Is(err, application.ErrBankAccountNotVerified),
+		errors.Is(err, application.ErrPayoutCurrencyChange):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"message": err.Error()})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+}
@@ -0,0 +1,187 @@
+package rest
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/interface/rest/middelware"
+	"github.com/alvarezcarlos/payment/app/interface/rest/models"
+	"github.com/alvarezcarlos/payment/app/interface/rest/validation"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type DisputeController struct {
+	useCase         application.DisputeUseCaseInterface
+	customValidator validation.Validator
+}
+
+func NewDisputeController(e *echo.Echo, useCase application.DisputeUseCaseInterface,
+	customValidator validation.Validator,
+	middleware middelware.Middleware) *DisputeController {
+	d := &DisputeController{useCase: useCase, customValidator: customValidator}
+	g := e.Group("/api/disputes", middleware.JwtMiddleware)
+	g.GET("", d.List)
+	g.GET("/:id", d.GetByID)
+	g.POST("/:id/evidence", d.AddEvidence)
+	g.POST("/:id/submit", d.Submit)
+	g.POST("/:id/accept", d.Accept)
+	e.POST("/api/admin/disputes/:id/resolve", d.Resolve, middleware.AdminMiddleware)
+	return d
+}
+
+func (d *DisputeController) List(c echo.Context) error {
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	disputes, err := d.useCase.List(c.Request().Context(), merchantID, entity.DisputeStatusEnum(c.QueryParam("status")))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"disputes": disputes})
+}
+
+func (d *DisputeController) GetByID(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	dispute, err := d.useCase.GetByID(c.Request().Context(), id, merchantID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, dispute)
+}
+
+func (d *DisputeController) AddEvidence(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	req := models.DisputeEvidenceReq{}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := d.customValidator.ValidateStruct(req); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "file is required"})
+	}
+	file, err := header.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+	defer file.Close()
+
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	evidence, err := d.useCase.AddEvidence(c.Request().Context(), id, merchantID, &entity.DisputeEvidence{
+		Type:        req.Type,
+		Note:        req.Note,
+		FileName:    header.Filename,
+		ContentType: header.Header.Get(echo.HeaderContentType),
+		Size:        header.Size,
+	}, file)
+	if err != nil {
+		return disputeError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, evidence)
+}
+
+func (d *DisputeController) Submit(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	dispute, err := d.useCase.Submit(c.Request().Context(), id, merchantID)
+	if err != nil {
+		return disputeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, dispute)
+}
+
+func (d *DisputeController) Accept(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	dispute, err := d.useCase.Accept(c.Request().Context(), id, merchantID)
+	if err != nil {
+		return disputeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, dispute)
+}
+
+func (d *DisputeController) Resolve(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	req := models.DisputeResolveReq{}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := d.customValidator.ValidateStruct(req); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	dispute, err := d.useCase.Resolve(c.Request().Context(), id, req.Outcome == string(entity.DisputeWon))
+	if err != nil {
+		return disputeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, dispute)
+}
+
+func disputeError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, application.ErrDisputeNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	case errors.Is(err, application.ErrDisputeInvalidStatus), errors.Is(err, application.ErrDisputeDeadline):
+		return c.JSON(http.StatusConflict, map[string]string{"message": err.Error()})
+	case errors.Is(err, application.ErrEvidenceRequired), errors.Is(err, application.ErrInvalidEvidence):
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+}
@@ -0,0 +1,143 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/interface/rest/middelware"
+	"github.com/alvarezcarlos/payment/app/interface/rest/models"
+	"github.com/alvarezcarlos/payment/app/interface/rest/validation"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+var exportContentTypes = map[entity.ExportFormatEnum]string{
+	entity.ExportCSV:    "text/csv",
+	entity.ExportNDJSON: "application/x-ndjson",
+}
+
+type ExportController struct {
+	useCase         application.ExportUseCaseInterface
+	customValidator validation.Validator
+}
+
+func NewExportController(e *echo.Echo, useCase application.ExportUseCaseInterface,
+	customValidator validation.Validator,
+	middleware middelware.Middleware) *ExportController {
+	g := e.Group("/api/exports", middleware.JwtMiddleware)
+	ex := &ExportController{useCase: useCase, customValidator: customValidator}
+	g.POST("", ex.Create)
+	g.GET("/:id", ex.GetByID)
+	g.GET("/:id/download", ex.Download)
+	return ex
+}
+
+func (ex *ExportController) Create(c echo.Context) error {
+	req := models.ExportCreateReq{}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := ex.customValidator.ValidateStruct(req); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	from, _ := time.Parse(time.RFC3339, req.From)
+	to, _ := time.Parse(time.RFC3339, req.To)
+	export, err := ex.useCase.Create(c.Request().Context(), &entity.Export{
+		MerchantID: merchantID,
+		Format:     entity.ExportFormatEnum(req.Format),
+		From:       from,
+		To:         to,
+	})
+	if errors.Is(err, application.ErrInvalidDateSpan) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+	if errors.Is(err, application.ErrExportQueueFull) {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"message": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusAccepted, exportResponse(export))
+}
+
+func (ex *ExportController) GetByID(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	export, err := ex.useCase.GetByID(c.Request().Context(), id, merchantID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, exportResponse(export))
+}
+
+func (ex *ExportController) Download(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	export, file, err := ex.useCase.Open(c.Request().Context(), id, merchantID)
+	switch {
+	case errors.Is(err, application.ErrExportNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	case errors.Is(err, application.ErrExportNotReady):
+		return c.JSON(http.StatusConflict, map[string]string{"message": err.Error()})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+	defer file.Close()
+
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("transactions-%s.%s", export.ID, export.Format)))
+	return c.Stream(http.StatusOK, exportContentTypes[export.Format], file)
+}
+
+func exportResponse(export *entity.Export) map[string]interface{} {
+	resp := map[string]interface{}{"export": export}
+	if export.Status == entity.ExportCompleted {
+		resp["download_url"] = fmt.Sprintf("/api/exports/%s/download", export.ID)
+	}
+	return resp
+}
+
+// merchantIDFromToken the numeric id of the merchant authenticated by the request token
+func merchantIDFromToken(c echo.Context) (uint, error) {
+	merchId, err := getMerchantAttrFromToken(c, merchantIdAttr)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.Atoi(merchId)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,38 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/alvarezcarlos/payment/app/health"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+type HealthController struct {
+	monitor *health.Monitor
+}
+
+func NewHealthController(e *echo.Echo, monitor *health.Monitor) *HealthController {
+	h := &HealthController{monitor: monitor}
+	e.GET(LivenessPath, h.Liveness)
+	e.GET(ReadinessPath, h.Readiness)
+	return h
+}
+
+// Liveness answers as long as the process serves requests, dependencies are only checked by Readiness
+func (h *HealthController) Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": health.StatusUp})
+}
+
+// Readiness reports 503 until the migrations completed and while the database is unreachable
+func (h *HealthController) Readiness(c echo.Context) error {
+	ready, report := h.monitor.Ready(c.Request().Context())
+	if !ready {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
@@ -1,17 +1,17 @@
 package rest
 
 import (
-	"fmt"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/alvarezcarlos/payment/app/config"
 	"github.com/golang-jwt/jwt"
-	"golang.org/x/crypto/bcrypt"
 
 	"github.com/alvarezcarlos/payment/app/application"
 	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/interface/rest/middelware"
 	"github.com/alvarezcarlos/payment/app/interface/rest/models"
 	"github.com/alvarezcarlos/payment/app/interface/rest/validation"
 	"github.com/labstack/echo/v4"
@@ -25,24 +25,29 @@ const (
 type MerchantController struct {
 	useCase         application.MerchantUseCaseInterface
 	customValidator validation.Validator
+	secretKey       string
 }
 
-func NewMerchantController(e *echo.Echo, useCase application.MerchantUseCaseInterface, customValidator validation.Validator) *MerchantController {
+// NewMerchantController secretKey signs the tokens issued on login, the ones middleware verifies
+func NewMerchantController(e *echo.Echo, useCase application.MerchantUseCaseInterface, customValidator validation.Validator,
+	middleware middelware.Middleware, secretKey string) *MerchantController {
 	g := e.Group("/api/merchants")
-	m := &MerchantController{useCase: useCase, customValidator: customValidator}
+	m := &MerchantController{useCase: useCase, customValidator: customValidator, secretKey: secretKey}
 	g.POST("/create", m.Create)
 	g.POST("/login", m.Login)
-	g.GET("/details", m.GetDetails)
+	g.GET("/details", m.GetDetails, middleware.JwtMiddleware)
+	g.POST("/2fa/enroll", m.EnrollTOTP, middleware.JwtMiddleware)
+	g.POST("/2fa/verify", m.VerifyTOTP, middleware.JwtMiddleware)
 	return m
 }
 
 func (m *MerchantController) GetDetails(c echo.Context) error {
-	merchantName, err := getMerchantAttrFromToken(c.Request().Header.Get("Authorization"), merchantNameAttr)
+	merchantName, err := getMerchantAttrFromToken(c, merchantNameAttr)
 	if err != nil {
 		return err
 	}
 
-	merchant, err := m.useCase.GetByName(merchantName)
+	merchant, err := m.useCase.GetByName(c.Request().Context(), merchantName)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
@@ -56,22 +61,19 @@ func (m *MerchantController) Create(c echo.Context) error {
 	}
 
 	if err := m.customValidator.ValidateStruct(merch); err != nil {
-		c.Logger().Error(err)
-		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
-	}
-
-	password, err := hashPassword(merch.Password)
-	if err != nil {
-		c.Logger().Error(err)
+		slog.ErrorContext(c.Request().Context(), err.Error())
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
 	em := entity.Merchant{
 		Name:     merch.Name,
-		Password: password,
+		Password: merch.Password,
 	}
 
-	merchResult, err := m.useCase.Create(&em)
+	merchResult, err := m.useCase.Create(c.Request().Context(), &em)
+	if errors.Is(err, application.ErrWeakPassword) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
@@ -80,76 +82,118 @@ func (m *MerchantController) Create(c echo.Context) error {
 }
 
 func (m *MerchantController) Login(c echo.Context) error {
-	merch := models.Merchant{}
+	merch := models.MerchantLoginReq{}
 	if err := c.Bind(&merch); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
 	if err := m.customValidator.ValidateStruct(merch); err != nil {
-		c.Logger().Error(err)
+		slog.ErrorContext(c.Request().Context(), err.Error())
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
-	merchant, err := m.useCase.GetByName(merch.Name)
+	merchant, err := m.useCase.Login(c.Request().Context(), application.LoginRequest{
+		Name:      merch.Name,
+		Password:  merch.Password,
+		OTP:       merch.OTP,
+		IP:        c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	})
+	switch {
+	case errors.Is(err, application.ErrMerchantLocked):
+		return c.JSON(http.StatusLocked, map[string]string{"message": err.Error()})
+	case errors.Is(err, application.ErrMerchantSuspended):
+		return c.JSON(http.StatusForbidden, map[string]string{"message": err.Error()})
+	case errors.Is(err, application.ErrTOTPRequired), errors.Is(err, application.ErrInvalidTOTP):
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": err.Error(), "otp_required": "true"})
+	case err != nil:
+		return c.JSON(http.StatusUnauthorized, "Unauthorized")
+	}
+
+	token, err := m.generateToken(merchant)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
 
-	if err = bcrypt.CompareHashAndPassword([]byte(merchant.Password), []byte(merch.Password)); err != nil {
-		return c.JSON(http.StatusUnauthorized, "Unauthorized")
-	}
+	return c.JSON(http.StatusOK, map[string]string{"token": token})
+}
 
-	token, err := generateToken(merchant)
+func (m *MerchantController) EnrollTOTP(c echo.Context) error {
+	merchantName, err := getMerchantAttrFromToken(c, merchantNameAttr)
 	if err != nil {
+		return err
+	}
+
+	req := models.TOTPEnrollReq{}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := m.customValidator.ValidateStruct(req); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	secret, url, err := m.useCase.EnrollTOTP(c.Request().Context(), merchantName, req.Code)
+	switch {
+	case errors.Is(err, application.ErrTOTPRequired), errors.Is(err, application.ErrInvalidTOTP):
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error(), "otp_required": "true"})
+	case err != nil:
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{"token": token})
+	return c.JSON(http.StatusOK, map[string]string{"secret": secret, "otpauth_url": url})
 }
 
-func hashPassword(password string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (m *MerchantController) VerifyTOTP(c echo.Context) error {
+	merchantName, err := getMerchantAttrFromToken(c, merchantNameAttr)
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	req := models.TOTPVerifyReq{}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := m.customValidator.ValidateStruct(req); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	err = m.useCase.VerifyTOTP(c.Request().Context(), merchantName, req.Code)
+	switch {
+	case errors.Is(err, application.ErrInvalidTOTP), errors.Is(err, application.ErrTOTPNotEnrolled):
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
-	return string(hashedBytes), nil
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "two-factor authentication enabled"})
 }
 
-func generateToken(merchant *entity.Merchant) (string, error) {
+func (m *MerchantController) generateToken(merchant *entity.Merchant) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
 	claims[merchantIdAttr] = strconv.Itoa(int(merchant.ID))
 	claims[merchantNameAttr] = merchant.Name
 	claims["exp"] = time.Now().Add(time.Hour * 24).Unix()
-	secretKey := config.Config().SecretKey
-	tokenString, err := token.SignedString([]byte(secretKey))
+	tokenString, err := token.SignedString([]byte(m.secretKey))
 	if err != nil {
 		return "", err
 	}
 	return tokenString, nil
 }
 
-func getMerchantAttrFromToken(tokenString, claimAttr string) (string, error) {
-	if tokenString == "" {
+// getMerchantAttrFromToken reads a claim of the token verified by the JwtMiddleware of the route
+func getMerchantAttrFromToken(c echo.Context, claimAttr string) (string, error) {
+	claims, ok := c.Get(middelware.ClaimsKey).(jwt.MapClaims)
+	if !ok {
 		return "", echo.NewHTTPError(http.StatusUnauthorized, "Token not provided")
 	}
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(config.Config().SecretKey), nil
-	})
-	if err != nil {
-		return "", echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
-	}
-
-	if !token.Valid {
-		return "", echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
-	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
+	value, ok := claims[claimAttr].(string)
 	if !ok {
-		return "", echo.NewHTTPError(http.StatusInternalServerError, "Failed to parse claims")
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
 	}
-	return claims[claimAttr].(string), nil
+	return value, nil
 }
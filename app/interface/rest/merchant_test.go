@@ -0,0 +1,259 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/config"
+	"github.com/alvarezcarlos/payment/app/infrastructure/memory"
+	"github.com/alvarezcarlos/payment/app/interface/rest"
+	"github.com/alvarezcarlos/payment/app/interface/rest/middelware"
+	"github.com/alvarezcarlos/payment/app/interface/rest/validation"
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	testSecretKey = "test-secret-key"
+	testPassword  = "Corr3ct-Horse"
+)
+
+// testServer the merchant and payment routes over the in-memory repositories
+type testServer struct {
+	e         *echo.Echo
+	merchants application.MerchantUseCaseInterface
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	cfg, err := config.Defaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	db := memory.NewDatabase()
+	merchants := application.NewMerchantUseCase(memory.NewMerchantRepository(db), cfg.Security, logger)
+	// the routes under test never reach the acquirer, the risk engine nor the pricing, reserve and review repositories
+	payments := application.NewPaymentUseCase(memory.NewPaymentRepository(db), nil, nil, nil,
+		memory.NewBankAccountRepository(db), nil, nil, cfg, logger)
+
+	e := echo.New()
+	middleware := middelware.NewMiddleware(testSecretKey, "test-admin-key", merchants)
+	customValidator := validation.NewCustomValidator(nil)
+	rest.NewMerchantController(e, merchants, customValidator, middleware, testSecretKey)
+	rest.NewPaymentController(e, payments, customValidator, middleware)
+	return &testServer{e: e, merchants: merchants}
+}
+
+// request serves the request, body is sent as JSON unless it is already a string
+func (s *testServer) request(t *testing.T, method, path string, body interface{}, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	default:
+		raw, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// signUp creates the merchant through the API and returns its id and a token of its login
+func (s *testServer) signUp(t *testing.T, name string) (uint, string) {
+	t.Helper()
+	rec := s.request(t, http.MethodPost, "/api/merchants/create", map[string]string{"name": name, "password": testPassword}, "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create merchant: %d %s", rec.Code, rec.Body)
+	}
+	var merchant struct {
+		ID uint `json:"id"`
+	}
+	decode(t, rec, &merchant)
+
+	rec = s.request(t, http.MethodPost, "/api/merchants/login", map[string]string{"name": name, "password": testPassword}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", rec.Code, rec.Body)
+	}
+	var login map[string]string
+	decode(t, rec, &login)
+	return merchant.ID, login["token"]
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body, err)
+	}
+}
+
+func TestMerchantControllerCreate(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     interface{}
+		wantCode int
+	}{
+		{name: "valid", body: map[string]string{"name": "acme", "password": testPassword}, wantCode: http.StatusCreated},
+		{name: "missing password", body: map[string]string{"name": "acme"}, wantCode: http.StatusBadRequest},
+		{name: "weak password", body: map[string]string{"name": "acme", "password": "weak"}, wantCode: http.StatusBadRequest},
+		{name: "malformed body", body: `{"name":`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			rec := s.request(t, http.MethodPost, "/api/merchants/create", tt.body, "")
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode == http.StatusCreated && strings.Contains(rec.Body.String(), testPassword) {
+				t.Error("the response exposes the password")
+			}
+		})
+	}
+}
+
+func TestMerchantControllerLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		setup    func(t *testing.T, s *testServer)
+		wantCode int
+	}{
+		{name: "valid credentials", password: testPassword, wantCode: http.StatusOK},
+		{name: "wrong password", password: "Wrong-Passw0rd", wantCode: http.StatusUnauthorized},
+		{name: "missing password", wantCode: http.StatusBadRequest},
+		{
+			name:     "suspended merchant",
+			password: testPassword,
+			setup: func(t *testing.T, s *testServer) {
+				if _, err := s.merchants.Suspend(context.Background(), "acme", "fraud"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "locked merchant",
+			password: testPassword,
+			setup: func(t *testing.T, s *testServer) {
+				cfg, err := config.Defaults()
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < cfg.Security.MaxFailedLogins; i++ {
+					s.request(t, http.MethodPost, "/api/merchants/login", map[string]string{"name": "acme", "password": "Wrong-Passw0rd"}, "")
+				}
+			},
+			wantCode: http.StatusLocked,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.signUp(t, "acme")
+			if tt.setup != nil {
+				tt.setup(t, s)
+			}
+
+			rec := s.request(t, http.MethodPost, "/api/merchants/login", map[string]string{"name": "acme", "password": tt.password}, "")
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode == http.StatusOK {
+				var body map[string]string
+				decode(t, rec, &body)
+				if body["token"] == "" {
+					t.Error("expected a token")
+				}
+			}
+		})
+	}
+}
+
+func TestSuspendedMerchantToken(t *testing.T) {
+	s := newTestServer(t)
+	merchantID, token := s.signUp(t, "acme")
+	payment := s.createPayment(t, merchantID, token, 25)
+	if _, err := s.merchants.Suspend(context.Background(), "acme", "fraud"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+	}{
+		{name: "details", method: http.MethodGet, path: "/api/merchants/details"},
+		{name: "payments", method: http.MethodGet, path: "/api/payments"},
+		{name: "refund", method: http.MethodPost, path: "/api/payments/refund", body: map[string]string{"id": payment.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.request(t, tt.method, tt.path, tt.body, token)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusForbidden, rec.Body)
+			}
+		})
+	}
+}
+
+func TestMerchantControllerDetails(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp(t, "acme")
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"merchantId": "1", "merchantName": "acme", "exp": time.Now().Add(time.Hour).Unix()})
+	forgedToken, err := forged.SignedString([]byte("another-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+	}{
+		{name: "valid token", token: token, wantCode: http.StatusOK},
+		{name: "missing token", wantCode: http.StatusUnauthorized},
+		{name: "token signed with another key", token: forgedToken, wantCode: http.StatusUnauthorized},
+		{name: "malformed token", token: "not-a-token", wantCode: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.request(t, http.MethodGet, "/api/merchants/details", nil, tt.token)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode == http.StatusOK {
+				var merchant struct {
+					Name string `json:"name"`
+				}
+				decode(t, rec, &merchant)
+				if merchant.Name != "acme" {
+					t.Errorf("name = %q, want acme", merchant.Name)
+				}
+			}
+		})
+	}
+}
@@ -1,20 +1,39 @@
 package middelware
 
 import (
-	"github.com/alvarezcarlos/payment/app/config"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/logging"
 	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
 )
 
+const (
+	merchantIDClaim   = "merchantId"
+	merchantNameClaim = "merchantName"
+)
+
+// ClaimsKey the echo context key of the jwt.MapClaims of the token verified by JwtMiddleware
+const ClaimsKey = "claims"
+
 type Middleware interface {
 	JwtMiddleware(next echo.HandlerFunc) echo.HandlerFunc
+	AdminMiddleware(next echo.HandlerFunc) echo.HandlerFunc
 }
 
 type middleware struct {
+	secretKey   string
+	adminAPIKey string
+	merchants   application.MerchantUseCaseInterface
 }
 
-func NewMiddleware() Middleware {
-	return &middleware{}
+// NewMiddleware merchants is looked up on every authenticated request, so the tokens of a merchant suspended
+// after its login are rejected
+func NewMiddleware(secretKey, adminAPIKey string, merchants application.MerchantUseCaseInterface) Middleware {
+	return &middleware{secretKey: secretKey, adminAPIKey: adminAPIKey, merchants: merchants}
 }
 
 func (m *middleware) JwtMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
@@ -24,14 +43,41 @@ func (m *middleware) JwtMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 			return echo.ErrUnauthorized
 		}
 
-		claims := &jwt.StandardClaims{}
+		claims := jwt.MapClaims{}
 		jwtToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(config.Config().SecretKey), nil
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return []byte(m.secretKey), nil
 		})
 
 		if err != nil || !jwtToken.Valid {
 			return echo.ErrUnauthorized
 		}
+		merchantName, _ := claims[merchantNameClaim].(string)
+		merchant, err := m.merchants.GetByName(c.Request().Context(), merchantName)
+		if err != nil {
+			return echo.ErrUnauthorized
+		}
+		if merchant.IsSuspended() {
+			return echo.NewHTTPError(http.StatusForbidden, application.ErrMerchantSuspended.Error())
+		}
+		if merchantID, ok := claims[merchantIDClaim].(string); ok {
+			logging.SetMerchantID(c.Request().Context(), merchantID)
+		}
+		c.Set(ClaimsKey, claims)
+
+		return next(c)
+	}
+}
+
+// AdminMiddleware restricts platform operator endpoints to requests carrying the admin API key
+func (m *middleware) AdminMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get("X-Admin-Key")
+		if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(m.adminAPIKey)) != 1 {
+			return echo.ErrUnauthorized
+		}
 
 		return next(c)
 	}
@@ -1,13 +1,65 @@
 package models
 
+import "time"
+
 type Merchant struct {
 	Name     string `json:"name" validate:"required"`
 	Password string `json:"password" validate:"required"`
 }
 
+type MerchantLoginReq struct {
+	Name     string `json:"name" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	OTP      string `json:"otp" validate:"omitempty,len=6,numeric"`
+}
+
+type TOTPVerifyReq struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// TOTPEnrollReq the current code is required to enroll again once two-factor is enabled
+type TOTPEnrollReq struct {
+	Code string `json:"code" validate:"omitempty,len=6,numeric"`
+}
+
 type PaymentCreateReq struct {
-	MerchantID uint    `json:"merchant_id" validate:"required,gt=0"`
-	Amount     float64 `json:"amount" validate:"required,gt=0"`
+	MerchantID  uint              `json:"merchant_id" validate:"required,gt=0"`
+	Amount      float64           `json:"amount" validate:"required,gt=0"`
+	Currency    string            `json:"currency" validate:"omitempty,len=3,alpha"`
+	Description string            `json:"description" validate:"max=500"`
+	Reference   string            `json:"reference" validate:"max=100"`
+	Metadata    map[string]string `json:"metadata" validate:"max=20,dive,keys,min=1,max=40,endkeys,max=500"`
+}
+
+// PaymentStatusResp limited payment view exposed to the customer through the client secret
+type PaymentStatusResp struct {
+	ID             string    `json:"id"`
+	Amount         float64   `json:"amount"`
+	Status         string    `json:"status"`
+	DeclineCode    string    `json:"decline_code,omitempty"`
+	ThreeDSStatus  string    `json:"three_ds_status,omitempty"`
+	LiabilityShift bool      `json:"liability_shift"`
+	NextActionURL  string    `json:"next_action_url,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type PaymentListReq struct {
+	Limit     int      `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor    string   `query:"cursor"`
+	State     string   `query:"state" validate:"omitempty,oneof=Pending Rejected Succeeded Refunded Disputed InReview RequiresAction"`
+	MinAmount *float64 `query:"min_amount" validate:"omitempty,gte=0"`
+	MaxAmount *float64 `query:"max_amount" validate:"omitempty,gte=0"`
+	From      string   `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To        string   `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	CardLast4 string   `query:"card_last4" validate:"omitempty,len=4,numeric"`
+	Customer  string   `query:"customer" validate:"omitempty,max=100"`
+	Sort      string   `query:"sort" validate:"omitempty,oneof=created_at amount"`
+	Order     string   `query:"order" validate:"omitempty,oneof=asc desc"`
+}
+
+type PaymentSearchReq struct {
+	Query string `query:"q" validate:"max=200"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=100"`
 }
 
 type ProcessPaymentReq struct {
@@ -25,8 +77,81 @@ type Card struct {
 type Customer struct {
 	PersonalID uint   `json:"personal_id" validate:"required"`
 	Name       string `json:"name" validate:"required"`
+	Country    string `json:"country" validate:"omitempty,iso3166_1_alpha2"`
+	Email      string `json:"email" validate:"omitempty,email,max=254"`
 }
 
 type RefundPaymentReq struct {
 	PaymentID string `json:"payment_id" validate:"required,uuid"`
 }
+
+type ExportCreateReq struct {
+	Format string `json:"format" validate:"required,oneof=csv ndjson"`
+	From   string `json:"from" validate:"required,datetime=2006-01-02T15:04:05Z07:00"`
+	To     string `json:"to" validate:"required,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+type BankAccountCreateReq struct {
+	Type          string `json:"type" validate:"required,oneof=iban ach"`
+	HolderName    string `json:"holder_name" validate:"required,max=100"`
+	Currency      string `json:"currency" validate:"required,len=3,alpha"`
+	AccountNumber string `json:"account_number" validate:"required,min=4,max=34"`
+	RoutingNumber string `json:"routing_number" validate:"required_if=Type ach,omitempty,len=9,numeric"`
+}
+
+type BankAccountVerifyReq struct {
+	Amounts []float64 `json:"amounts" validate:"required,len=2,dive,gt=0,lt=1"`
+}
+
+type PricingPlanReq struct {
+	Name           string           `json:"name" validate:"required,max=100"`
+	PercentFee     float64          `json:"percent_fee" validate:"gte=0,lt=100"`
+	FixedFee       float64          `json:"fixed_fee" validate:"gte=0"`
+	RefundPolicy   string           `json:"refund_policy" validate:"required,oneof=retain return"`
+	RefundFixedFee float64          `json:"refund_fixed_fee" validate:"gte=0"`
+	Rules          []PricingRuleReq `json:"rules" validate:"max=50,dive"`
+}
+
+type PricingRuleReq struct {
+	CardBrand  string  `json:"card_brand" validate:"omitempty,oneof=visa mastercard amex discover unknown"`
+	Currency   string  `json:"currency" validate:"omitempty,len=3,alpha"`
+	PercentFee float64 `json:"percent_fee" validate:"gte=0,lt=100"`
+	FixedFee   float64 `json:"fixed_fee" validate:"gte=0"`
+}
+
+type ReservePolicyReq struct {
+	Percent float64 `json:"percent" validate:"gte=0,lte=100"`
+	Days    int     `json:"days" validate:"gte=0,lte=365"`
+}
+
+type BalanceHoldReq struct {
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+	Reason string  `json:"reason" validate:"required,max=500"`
+}
+
+type DisputeEvidenceReq struct {
+	Type string `form:"type" validate:"required,oneof=receipt customer_communication shipping_documentation refund_policy other"`
+	Note string `form:"note" validate:"max=1000"`
+}
+
+type DisputeResolveReq struct {
+	Outcome string `json:"outcome" validate:"required,oneof=won lost"`
+}
+
+type ReviewListReq struct {
+	Status string `query:"status" validate:"omitempty,oneof=pending approved rejected expired"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+type ReviewDecisionReq struct {
+	Reviewer string `json:"reviewer" validate:"required,max=100"`
+	Note     string `json:"note" validate:"max=1000"`
+}
+
+type RiskListEntryReq struct {
+	List       string `json:"list" validate:"required,oneof=allow block"`
+	Type       string `json:"type" validate:"required,oneof=card_fingerprint personal_id email_domain bin_range"`
+	Value      string `json:"value" validate:"required_without=CardNumber,max=100"`
+	CardNumber string `json:"card_number" validate:"omitempty,numeric,min=12,max=19"`
+	Note       string `json:"note" validate:"max=500"`
+}
@@ -1,13 +1,18 @@
 package rest
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/alvarezcarlos/payment/app/interface/rest/middelware"
 
 	"github.com/alvarezcarlos/payment/app/application"
 	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
 	"github.com/alvarezcarlos/payment/app/interface/rest/models"
 	"github.com/alvarezcarlos/payment/app/interface/rest/validation"
 	"github.com/google/uuid"
@@ -25,8 +30,11 @@ func NewPaymentController(e *echo.Echo, useCase application.PaymentUseCaseInterf
 	middleware middelware.Middleware) *PaymentController {
 	g := e.Group("/api/payments")
 	p := &PaymentController{useCase: useCase, customValidator: customValidator}
+	g.GET("", p.List, middleware.JwtMiddleware)
+	g.GET("/search", p.Search, middleware.JwtMiddleware)
 	g.POST("/create", p.Create, middleware.JwtMiddleware)
-	g.GET("/:id", p.GetByID)
+	g.GET("/:id", p.GetByID, middleware.JwtMiddleware)
+	g.GET("/:id/status", p.GetStatus)
 	g.POST("/process", p.Process)
 	g.POST("/refund", p.Refund, middleware.JwtMiddleware)
 	return p
@@ -37,7 +45,7 @@ func (p *PaymentController) Create(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
-	merchId, err := getMerchantAttrFromToken(c.Request().Header.Get("Authorization"), merchantIdAttr)
+	merchId, err := getMerchantAttrFromToken(c, merchantIdAttr)
 	if err != nil {
 		return err
 	}
@@ -47,21 +55,35 @@ func (p *PaymentController) Create(c echo.Context) error {
 	}
 
 	if err := p.customValidator.ValidateStruct(pay); err != nil {
-		c.Logger().Error(err)
+		slog.ErrorContext(c.Request().Context(), err.Error())
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
 	payment := &entity.Payment{
-		MerchantID: pay.MerchantID,
-		Amount:     pay.Amount,
+		MerchantID:  pay.MerchantID,
+		Amount:      pay.Amount,
+		Currency:    pay.Currency,
+		Description: pay.Description,
+		Reference:   pay.Reference,
+		Metadata:    pay.Metadata,
 	}
 
-	payment, err = p.useCase.Create(payment)
+	payment, err = p.useCase.Create(c.Request().Context(), payment)
+	if errors.Is(err, application.ErrMerchantSuspended) {
+		return c.JSON(http.StatusForbidden, map[string]string{"message": err.Error()})
+	}
+	if errors.Is(err, application.ErrCurrencyMismatch) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
 
-	return c.JSON(http.StatusCreated, map[string]string{"message": "created", "id": payment.ID.String()})
+	return c.JSON(http.StatusCreated, map[string]string{
+		"message":       "created",
+		"id":            payment.ID.String(),
+		"client_secret": payment.ClientSecret,
+	})
 }
 
 func (p *PaymentController) GetByID(c echo.Context) error {
@@ -71,7 +93,15 @@ func (p *PaymentController) GetByID(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
-	payment, err := p.useCase.GetByID(parsedUUID)
+	merchId, err := getMerchantAttrFromToken(c, merchantIdAttr)
+	if err != nil {
+		return err
+	}
+
+	payment, err := p.useCase.GetByID(c.Request().Context(), parsedUUID, merchId)
+	if errors.Is(err, application.ErrPaymentNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
@@ -79,6 +109,121 @@ func (p *PaymentController) GetByID(c echo.Context) error {
 	return c.JSON(http.StatusOK, payment)
 }
 
+// GetStatus customer facing view of the payment, authorized by the client secret returned at creation
+func (p *PaymentController) GetStatus(c echo.Context) error {
+	parsedUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	clientSecret := c.QueryParam("client_secret")
+	if clientSecret == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "client secret not provided"})
+	}
+
+	payment, err := p.useCase.GetByClientSecret(c.Request().Context(), parsedUUID, clientSecret)
+	switch {
+	case errors.Is(err, application.ErrPaymentNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	case errors.Is(err, application.ErrClientSecretExpired):
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": err.Error()})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, models.PaymentStatusResp{
+		ID:             payment.ID.String(),
+		Amount:         payment.Amount,
+		Status:         string(payment.CurrentState()),
+		DeclineCode:    payment.DeclineCode,
+		ThreeDSStatus:  string(payment.ThreeDSStatus),
+		LiabilityShift: payment.LiabilityShift,
+		NextActionURL:  payment.NextActionURL,
+		CreatedAt:      payment.CreatedAt,
+	})
+}
+
+func (p *PaymentController) List(c echo.Context) error {
+	listReq := models.PaymentListReq{}
+	if err := c.Bind(&listReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := p.customValidator.ValidateStruct(listReq); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchId, err := getMerchantAttrFromToken(c, merchantIdAttr)
+	if err != nil {
+		return err
+	}
+	merchantID, _ := strconv.Atoi(merchId)
+
+	filter := repository.PaymentFilter{
+		MerchantID: uint(merchantID),
+		State:      entity.StateEnum(listReq.State),
+		MinAmount:  listReq.MinAmount,
+		MaxAmount:  listReq.MaxAmount,
+		CardLast4:  listReq.CardLast4,
+		Customer:   listReq.Customer,
+		SortBy:     listReq.Sort,
+		Descending: listReq.Order != "asc",
+		Limit:      listReq.Limit,
+	}
+	if listReq.From != "" {
+		from, _ := time.Parse(time.RFC3339, listReq.From)
+		filter.From = &from
+	}
+	if listReq.To != "" {
+		to, _ := time.Parse(time.RFC3339, listReq.To)
+		filter.To = &to
+	}
+
+	page, err := p.useCase.List(c.Request().Context(), filter, listReq.Cursor)
+	if errors.Is(err, application.ErrInvalidCursor) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+// Search full-text search over the merchant payments, metadata filters are passed as metadata[key]=value
+func (p *PaymentController) Search(c echo.Context) error {
+	searchReq := models.PaymentSearchReq{}
+	if err := c.Bind(&searchReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := p.customValidator.ValidateStruct(searchReq); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchId, err := getMerchantAttrFromToken(c, merchantIdAttr)
+	if err != nil {
+		return err
+	}
+	merchantID, _ := strconv.Atoi(merchId)
+
+	metadata := map[string]string{}
+	for key, values := range c.QueryParams() {
+		if strings.HasPrefix(key, "metadata[") && strings.HasSuffix(key, "]") && len(values) > 0 {
+			metadata[key[len("metadata["):len(key)-1]] = values[0]
+		}
+	}
+
+	payments, err := p.useCase.Search(c.Request().Context(), uint(merchantID), searchReq.Query, metadata, searchReq.Limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"payments": payments})
+}
+
 func (p *PaymentController) Process(c echo.Context) error {
 	processReq := models.ProcessPaymentReq{}
 	if err := c.Bind(&processReq); err != nil {
@@ -86,7 +231,7 @@ func (p *PaymentController) Process(c echo.Context) error {
 	}
 
 	if err := p.customValidator.ValidateStruct(processReq); err != nil {
-		c.Logger().Error(err)
+		slog.ErrorContext(c.Request().Context(), err.Error())
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 	id, _ := uuid.Parse(processReq.PaymentID)
@@ -102,11 +247,18 @@ func (p *PaymentController) Process(c echo.Context) error {
 		Year:       processReq.Card.Year,
 	}
 
-	payment, err := p.useCase.ProcessPayment(processPay, customer)
+	payment, err := p.useCase.ProcessPayment(c.Request().Context(), processPay, customer, application.Customer{
+		Country: processReq.Customer.Country,
+		Email:   processReq.Customer.Email,
+	})
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
-	return c.JSON(http.StatusOK, map[string]interface{}{"id": payment.ID})
+	resp := map[string]interface{}{"id": payment.ID, "status": payment.CurrentState()}
+	if payment.CurrentState() == entity.RequiresAction {
+		resp["next_action_url"] = payment.NextActionURL
+	}
+	return c.JSON(http.StatusOK, resp)
 }
 
 func (p *PaymentController) Refund(c echo.Context) error {
@@ -116,18 +268,18 @@ func (p *PaymentController) Refund(c echo.Context) error {
 	}
 
 	if err := p.customValidator.ValidateStruct(refundReq); err != nil {
-		c.Logger().Error(err)
+		slog.ErrorContext(c.Request().Context(), err.Error())
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
 	uid, _ := uuid.Parse(refundReq.PaymentID)
 
-	merchId, err := getMerchantAttrFromToken(c.Request().Header.Get("Authorization"), merchantIdAttr)
+	merchId, err := getMerchantAttrFromToken(c, merchantIdAttr)
 	if err != nil {
 		return err
 	}
 
-	err = p.useCase.ProcessRefund(uid, merchId)
+	err = p.useCase.ProcessRefund(c.Request().Context(), uid, merchId)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
@@ -0,0 +1,202 @@
+package rest_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type createdPayment struct {
+	ID           string `json:"id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+func (s *testServer) createPayment(t *testing.T, merchantID uint, token string, amount float64) createdPayment {
+	t.Helper()
+	rec := s.request(t, http.MethodPost, "/api/payments/create", map[string]interface{}{"merchant_id": merchantID, "amount": amount}, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create payment: %d %s", rec.Code, rec.Body)
+	}
+	var payment createdPayment
+	decode(t, rec, &payment)
+	return payment
+}
+
+func TestPaymentControllerCreate(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      func(merchantID uint) interface{}
+		noToken   bool
+		suspended bool
+		wantCode  int
+	}{
+		{
+			name: "valid",
+			body: func(id uint) interface{} {
+				return map[string]interface{}{"merchant_id": id, "amount": 10.5, "currency": "usd"}
+			},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "missing token",
+			body:     func(id uint) interface{} { return map[string]interface{}{"merchant_id": id, "amount": 10.5} },
+			noToken:  true,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "another merchant",
+			body:     func(id uint) interface{} { return map[string]interface{}{"merchant_id": id + 1, "amount": 10.5} },
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "missing amount",
+			body:     func(id uint) interface{} { return map[string]interface{}{"merchant_id": id} },
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "invalid currency",
+			body: func(id uint) interface{} {
+				return map[string]interface{}{"merchant_id": id, "amount": 10.5, "currency": "US"}
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:      "suspended merchant",
+			body:      func(id uint) interface{} { return map[string]interface{}{"merchant_id": id, "amount": 10.5} },
+			suspended: true,
+			wantCode:  http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			merchantID, token := s.signUp(t, "acme")
+			if tt.suspended {
+				if _, err := s.merchants.Suspend(context.Background(), "acme", "fraud"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.noToken {
+				token = ""
+			}
+
+			rec := s.request(t, http.MethodPost, "/api/payments/create", tt.body(merchantID), token)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode == http.StatusCreated {
+				var payment createdPayment
+				decode(t, rec, &payment)
+				if _, err := uuid.Parse(payment.ID); err != nil || payment.ClientSecret == "" {
+					t.Errorf("unexpected response %s", rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestPaymentControllerGetByID(t *testing.T) {
+	s := newTestServer(t)
+	merchantID, token := s.signUp(t, "acme")
+	_, otherToken := s.signUp(t, "globex")
+	payment := s.createPayment(t, merchantID, token, 25)
+
+	tests := []struct {
+		name     string
+		id       string
+		token    string
+		wantCode int
+	}{
+		{name: "owner", id: payment.ID, token: token, wantCode: http.StatusOK},
+		{name: "another merchant", id: payment.ID, token: otherToken, wantCode: http.StatusNotFound},
+		{name: "unknown payment", id: uuid.NewString(), token: token, wantCode: http.StatusNotFound},
+		{name: "invalid id", id: "not-a-uuid", token: token, wantCode: http.StatusBadRequest},
+		{name: "missing token", id: payment.ID, wantCode: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.request(t, http.MethodGet, "/api/payments/"+tt.id, nil, tt.token)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+		})
+	}
+}
+
+func TestPaymentControllerGetStatus(t *testing.T) {
+	s := newTestServer(t)
+	merchantID, token := s.signUp(t, "acme")
+	payment := s.createPayment(t, merchantID, token, 25)
+
+	tests := []struct {
+		name       string
+		secret     string
+		wantCode   int
+		wantStatus string
+	}{
+		{name: "valid secret", secret: payment.ClientSecret, wantCode: http.StatusOK, wantStatus: "Pending"},
+		{name: "missing secret", wantCode: http.StatusUnauthorized},
+		{name: "wrong secret", secret: payment.ID + "_secret_wrong", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := fmt.Sprintf("/api/payments/%s/status?client_secret=%s", payment.ID, url.QueryEscape(tt.secret))
+			rec := s.request(t, http.MethodGet, path, nil, "")
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantStatus != "" {
+				var body map[string]interface{}
+				decode(t, rec, &body)
+				if body["status"] != tt.wantStatus {
+					t.Errorf("payment status = %v, want %s", body["status"], tt.wantStatus)
+				}
+			}
+		})
+	}
+}
+
+func TestPaymentControllerList(t *testing.T) {
+	s := newTestServer(t)
+	merchantID, token := s.signUp(t, "acme")
+	for _, amount := range []float64{10, 20, 30} {
+		s.createPayment(t, merchantID, token, amount)
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		wantCode    int
+		wantCount   int
+		wantHasMore bool
+	}{
+		{name: "every payment", wantCode: http.StatusOK, wantCount: 3},
+		{name: "first page", query: "limit=2&sort=amount&order=asc", wantCode: http.StatusOK, wantCount: 2, wantHasMore: true},
+		{name: "minimum amount", query: "min_amount=15", wantCode: http.StatusOK, wantCount: 2},
+		{name: "invalid sort", query: "sort=name", wantCode: http.StatusBadRequest},
+		{name: "invalid cursor", query: "cursor=not-a-cursor", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.request(t, http.MethodGet, "/api/payments?"+tt.query, nil, token)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var page struct {
+				Payments []map[string]interface{} `json:"payments"`
+				HasMore  bool                     `json:"has_more"`
+			}
+			decode(t, rec, &page)
+			if len(page.Payments) != tt.wantCount || page.HasMore != tt.wantHasMore {
+				t.Errorf("got %d payments, has more %v, want %d and %v", len(page.Payments), page.HasMore, tt.wantCount, tt.wantHasMore)
+			}
+		})
+	}
+}
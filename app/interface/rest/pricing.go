@@ -0,0 +1,103 @@
+package rest
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/interface/rest/middelware"
+	"github.com/alvarezcarlos/payment/app/interface/rest/models"
+	"github.com/alvarezcarlos/payment/app/interface/rest/validation"
+	"github.com/labstack/echo/v4"
+)
+
+type PricingController struct {
+	useCase         application.PricingUseCaseInterface
+	customValidator validation.Validator
+}
+
+func NewPricingController(e *echo.Echo, useCase application.PricingUseCaseInterface,
+	customValidator validation.Validator,
+	middleware middelware.Middleware) *PricingController {
+	p := &PricingController{useCase: useCase, customValidator: customValidator}
+	e.GET("/api/merchants/pricing", p.GetOwnPlan, middleware.JwtMiddleware)
+	admin := e.Group("/api/admin/merchants", middleware.AdminMiddleware)
+	admin.GET("/:id/pricing", p.GetPlan)
+	admin.PUT("/:id/pricing", p.SetPlan)
+	return p
+}
+
+func (p *PricingController) GetOwnPlan(c echo.Context) error {
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	plan, err := p.useCase.GetPlan(c.Request().Context(), merchantID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, plan)
+}
+
+func (p *PricingController) GetPlan(c echo.Context) error {
+	merchantID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || merchantID <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid merchant id"})
+	}
+
+	plan, err := p.useCase.GetPlan(c.Request().Context(), uint(merchantID))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, plan)
+}
+
+func (p *PricingController) SetPlan(c echo.Context) error {
+	merchantID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || merchantID <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid merchant id"})
+	}
+
+	req := models.PricingPlanReq{}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := p.customValidator.ValidateStruct(req); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	plan := &entity.PricingPlan{
+		MerchantID:     uint(merchantID),
+		Name:           req.Name,
+		PercentFee:     req.PercentFee,
+		FixedFee:       req.FixedFee,
+		RefundPolicy:   entity.RefundFeePolicyEnum(req.RefundPolicy),
+		RefundFixedFee: req.RefundFixedFee,
+	}
+	for _, rule := range req.Rules {
+		plan.Rules = append(plan.Rules, entity.PricingRule{
+			CardBrand:  rule.CardBrand,
+			Currency:   rule.Currency,
+			PercentFee: rule.PercentFee,
+			FixedFee:   rule.FixedFee,
+		})
+	}
+
+	plan, err = p.useCase.SetPlan(c.Request().Context(), plan)
+	if errors.Is(err, application.ErrInvalidPricingPlan) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, plan)
+}
@@ -0,0 +1,134 @@
+package rest
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/interface/rest/middelware"
+	"github.com/alvarezcarlos/payment/app/interface/rest/models"
+	"github.com/alvarezcarlos/payment/app/interface/rest/validation"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type ReserveController struct {
+	useCase         application.ReserveUseCaseInterface
+	customValidator validation.Validator
+}
+
+func NewReserveController(e *echo.Echo, useCase application.ReserveUseCaseInterface,
+	customValidator validation.Validator,
+	middleware middelware.Middleware) *ReserveController {
+	r := &ReserveController{useCase: useCase, customValidator: customValidator}
+	g := e.Group("/api/admin", middleware.AdminMiddleware)
+	g.GET("/merchants/:id/reserve", r.GetPolicy)
+	g.PUT("/merchants/:id/reserve", r.SetPolicy)
+	g.GET("/merchants/:id/holds", r.ListHolds)
+	g.POST("/merchants/:id/holds", r.PlaceHold)
+	g.POST("/holds/:id/release", r.ReleaseHold)
+	return r
+}
+
+func (r *ReserveController) GetPolicy(c echo.Context) error {
+	merchantID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || merchantID <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid merchant id"})
+	}
+
+	policy, err := r.useCase.GetPolicy(c.Request().Context(), uint(merchantID))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+func (r *ReserveController) SetPolicy(c echo.Context) error {
+	merchantID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || merchantID <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid merchant id"})
+	}
+
+	req := models.ReservePolicyReq{}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := r.customValidator.ValidateStruct(req); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	policy, err := r.useCase.SetPolicy(c.Request().Context(), &entity.ReservePolicy{
+		MerchantID: uint(merchantID),
+		Percent:    req.Percent,
+		Days:       req.Days,
+	})
+	if errors.Is(err, application.ErrInvalidReservePolicy) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, policy)
+}
+
+func (r *ReserveController) ListHolds(c echo.Context) error {
+	merchantID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || merchantID <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid merchant id"})
+	}
+
+	holds, err := r.useCase.ListHolds(c.Request().Context(), uint(merchantID))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"holds": holds})
+}
+
+func (r *ReserveController) PlaceHold(c echo.Context) error {
+	merchantID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || merchantID <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid merchant id"})
+	}
+
+	req := models.BalanceHoldReq{}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := r.customValidator.ValidateStruct(req); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	hold, err := r.useCase.PlaceHold(c.Request().Context(), uint(merchantID), req.Amount, req.Reason)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, hold)
+}
+
+func (r *ReserveController) ReleaseHold(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	hold, err := r.useCase.ReleaseHold(c.Request().Context(), id)
+	if errors.Is(err, application.ErrHoldNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, hold)
+}
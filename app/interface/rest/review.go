@@ -0,0 +1,90 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/interface/rest/middelware"
+	"github.com/alvarezcarlos/payment/app/interface/rest/models"
+	"github.com/alvarezcarlos/payment/app/interface/rest/validation"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type ReviewController struct {
+	useCase         application.ReviewUseCaseInterface
+	customValidator validation.Validator
+}
+
+func NewReviewController(e *echo.Echo, useCase application.ReviewUseCaseInterface,
+	customValidator validation.Validator,
+	middleware middelware.Middleware) *ReviewController {
+	r := &ReviewController{useCase: useCase, customValidator: customValidator}
+	g := e.Group("/api/admin/reviews", middleware.AdminMiddleware)
+	g.GET("", r.List)
+	g.POST("/:id/approve", r.Approve)
+	g.POST("/:id/reject", r.Reject)
+	return r
+}
+
+func (r *ReviewController) List(c echo.Context) error {
+	listReq := models.ReviewListReq{}
+	if err := c.Bind(&listReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := r.customValidator.ValidateStruct(listReq); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+	if listReq.Status == "" {
+		listReq.Status = string(entity.ReviewPending)
+	}
+
+	reviews, err := r.useCase.List(c.Request().Context(), entity.ReviewStatusEnum(listReq.Status), listReq.Limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"reviews": reviews})
+}
+
+func (r *ReviewController) Approve(c echo.Context) error {
+	return r.decide(c, r.useCase.Approve)
+}
+
+func (r *ReviewController) Reject(c echo.Context) error {
+	return r.decide(c, r.useCase.Reject)
+}
+
+func (r *ReviewController) decide(c echo.Context,
+	decision func(ctx context.Context, id uuid.UUID, reviewer, note string) (*entity.Review, *entity.Payment, error)) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	req := models.ReviewDecisionReq{}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := r.customValidator.ValidateStruct(req); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	review, payment, err := decision(c.Request().Context(), id, req.Reviewer, req.Note)
+	if errors.Is(err, application.ErrReviewNotPending) {
+		return c.JSON(http.StatusConflict, map[string]string{"message": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"review": review, "payment": payment})
+}
@@ -0,0 +1,75 @@
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/interface/rest"
+	"github.com/alvarezcarlos/payment/app/interface/rest/middelware"
+	"github.com/alvarezcarlos/payment/app/interface/rest/validation"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// reviewUseCaseStub records the filters of the listed reviews
+type reviewUseCaseStub struct {
+	status entity.ReviewStatusEnum
+	limit  int
+}
+
+func (r *reviewUseCaseStub) List(_ context.Context, status entity.ReviewStatusEnum, limit int) ([]entity.Review, error) {
+	r.status, r.limit = status, limit
+	return nil, nil
+}
+
+func (r *reviewUseCaseStub) Approve(context.Context, uuid.UUID, string, string) (*entity.Review, *entity.Payment, error) {
+	return nil, nil, nil
+}
+
+func (r *reviewUseCaseStub) Reject(context.Context, uuid.UUID, string, string) (*entity.Review, *entity.Payment, error) {
+	return nil, nil, nil
+}
+
+func (r *reviewUseCaseStub) ExpireOverdue(context.Context, time.Time) error {
+	return nil
+}
+
+func TestReviewControllerList(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantFilter entity.ReviewStatusEnum
+		wantLimit  int
+	}{
+		{name: "pending by default", wantStatus: http.StatusOK, wantFilter: entity.ReviewPending},
+		{name: "status and limit", query: "?status=expired&limit=10", wantStatus: http.StatusOK,
+			wantFilter: entity.ReviewExpired, wantLimit: 10},
+		{name: "unknown status", query: "?status=approve", wantStatus: http.StatusBadRequest},
+		{name: "limit not a number", query: "?limit=ten", wantStatus: http.StatusBadRequest},
+		{name: "limit out of range", query: "?limit=101", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviews := &reviewUseCaseStub{}
+			e := echo.New()
+			rest.NewReviewController(e, reviews, validation.NewCustomValidator(nil),
+				middelware.NewMiddleware(testSecretKey, "test-admin-key", nil))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/reviews"+tt.query, nil)
+			req.Header.Set("X-Admin-Key", "test-admin-key")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if reviews.status != tt.wantFilter || reviews.limit != tt.wantLimit {
+				t.Errorf("listed %q limit %d, want %q limit %d", reviews.status, reviews.limit, tt.wantFilter, tt.wantLimit)
+			}
+		})
+	}
+}
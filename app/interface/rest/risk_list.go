@@ -0,0 +1,98 @@
+package rest
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/interface/rest/middelware"
+	"github.com/alvarezcarlos/payment/app/interface/rest/models"
+	"github.com/alvarezcarlos/payment/app/interface/rest/validation"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type RiskListController struct {
+	useCase         application.RiskListUseCaseInterface
+	customValidator validation.Validator
+}
+
+func NewRiskListController(e *echo.Echo, useCase application.RiskListUseCaseInterface,
+	customValidator validation.Validator,
+	middleware middelware.Middleware) *RiskListController {
+	g := e.Group("/api/merchants/risk-lists", middleware.JwtMiddleware)
+	r := &RiskListController{useCase: useCase, customValidator: customValidator}
+	g.POST("", r.Create)
+	g.GET("", r.List)
+	g.DELETE("/:id", r.Delete)
+	return r
+}
+
+func (r *RiskListController) Create(c echo.Context) error {
+	req := models.RiskListEntryReq{}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := r.customValidator.ValidateStruct(req); err != nil {
+		slog.ErrorContext(c.Request().Context(), err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	entry, err := r.useCase.Create(c.Request().Context(), &entity.RiskListEntry{
+		MerchantID: merchantID,
+		List:       entity.RiskListEnum(req.List),
+		Type:       entity.RiskListEntryTypeEnum(req.Type),
+		Value:      req.Value,
+		Note:       req.Note,
+	}, req.CardNumber)
+	switch {
+	case errors.Is(err, application.ErrInvalidListEntry):
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	case errors.Is(err, application.ErrListEntryExists), errors.Is(err, application.ErrRiskListFull):
+		return c.JSON(http.StatusConflict, map[string]string{"message": err.Error()})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, entry)
+}
+
+func (r *RiskListController) List(c echo.Context) error {
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	entries, err := r.useCase.List(c.Request().Context(), merchantID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"entries": entries})
+}
+
+func (r *RiskListController) Delete(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	if err = r.useCase.Delete(c.Request().Context(), id, merchantID); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
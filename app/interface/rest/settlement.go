@@ -0,0 +1,50 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/alvarezcarlos/payment/app/application"
+	"github.com/alvarezcarlos/payment/app/interface/rest/middelware"
+	"github.com/labstack/echo/v4"
+)
+
+type SettlementController struct {
+	useCase application.SettlementUseCaseInterface
+}
+
+func NewSettlementController(e *echo.Echo, useCase application.SettlementUseCaseInterface,
+	middleware middelware.Middleware) *SettlementController {
+	g := e.Group("/api/merchants", middleware.JwtMiddleware)
+	s := &SettlementController{useCase: useCase}
+	g.GET("/payouts", s.ListPayouts)
+	g.GET("/balance", s.GetBalance)
+	return s
+}
+
+func (s *SettlementController) ListPayouts(c echo.Context) error {
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	payouts, err := s.useCase.ListPayouts(c.Request().Context(), merchantID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"payouts": payouts})
+}
+
+func (s *SettlementController) GetBalance(c echo.Context) error {
+	merchantID, err := merchantIDFromToken(c)
+	if err != nil {
+		return err
+	}
+
+	balance, err := s.useCase.GetBalance(c.Request().Context(), merchantID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, balance)
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+type fieldsKey struct{}
+
+// fields request scoped values added to every log line of the request, the merchant is only known once
+// its token is verified so it is set after the request was started
+type fields struct {
+	mu         sync.RWMutex
+	requestID  string
+	merchantID string
+}
+
+// WithRequestID starts the request scoped log fields of ctx
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, &fields{requestID: requestID})
+}
+
+// RequestID returns the correlation id of the request in ctx, empty outside a request
+func RequestID(ctx context.Context) string {
+	f, ok := ctx.Value(fieldsKey{}).(*fields)
+	if !ok {
+		return ""
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.requestID
+}
+
+// SetMerchantID records the authenticated merchant of the request in ctx
+func SetMerchantID(ctx context.Context, merchantID string) {
+	f, ok := ctx.Value(fieldsKey{}).(*fields)
+	if !ok {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.merchantID = merchantID
+}
+
+func attrs(ctx context.Context) []slog.Attr {
+	f, ok := ctx.Value(fieldsKey{}).(*fields)
+	if !ok {
+		return nil
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	attrs := []slog.Attr{slog.String("request_id", f.requestID)}
+	if f.merchantID != "" {
+		attrs = append(attrs, slog.String("merchant_id", f.merchantID))
+	}
+	return attrs
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// GormLogger sends the gorm logs to slog with the request fields of the query context. Queries are logged
+// without their bound values so card data never reaches the logs, every query at debug level and the slow
+// or failed ones at warn and error
+type GormLogger struct {
+	Logger        *slog.Logger
+	SlowThreshold time.Duration
+	level         logger.LogLevel
+}
+
+func NewGormLogger(l *slog.Logger, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{Logger: l, SlowThreshold: slowThreshold, level: logger.Info}
+}
+
+func (g *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *g
+	copied.level = level
+	return &copied
+}
+
+func (g *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= logger.Info {
+		g.Logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (g *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= logger.Warn {
+		g.Logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (g *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= logger.Error {
+		g.Logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (g *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if g.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	sql, rows := fc()
+	attrs := []slog.Attr{slog.String("sql", sql), slog.Int64("rows", rows), slog.Duration("elapsed", elapsed)}
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && g.level >= logger.Error:
+		attrs = append(attrs, slog.String("error", err.Error()))
+		g.Logger.LogAttrs(ctx, slog.LevelError, "query failed", attrs...)
+	case g.SlowThreshold > 0 && elapsed > g.SlowThreshold && g.level >= logger.Warn:
+		g.Logger.LogAttrs(ctx, slog.LevelWarn, "slow query", attrs...)
+	case g.level >= logger.Info:
+		g.Logger.LogAttrs(ctx, slog.LevelDebug, "query", attrs...)
+	}
+}
+
+// ParamsFilter keeps the bound values out of the logged statements
+func (g *GormLogger) ParamsFilter(_ context.Context, sql string, _ ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
@@ -0,0 +1,178 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// card numbers with optional space or dash separators, masked when they pass the Luhn check
+	panPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	// key/value pairs of secrets embedded in text, like JSON bodies or SQL dumps
+	secretPairPattern = regexp.MustCompile(
+		`(?i)("?\b(?:password|cvv|cvc|code|token|secret|client_secret|authorization)"?\s*[:=]\s*"?)[^"\s,}&]+`)
+	bearerPattern = regexp.MustCompile(`(?i)\bbearer\s+[\w\-.~+/]+=*`)
+	jwtPattern    = regexp.MustCompile(`\beyJ[\w-]+\.[\w-]+\.[\w-]+`)
+)
+
+var sensitiveKeys = map[string]bool{
+	"password":       true,
+	"cvv":            true,
+	"cvc":            true,
+	"code":           true,
+	"number":         true,
+	"card_number":    true,
+	"pan":            true,
+	"token":          true,
+	"authorization":  true,
+	"secret":         true,
+	"client_secret":  true,
+	"totp_secret":    true,
+	"account_number": true,
+}
+
+type redactHandler struct {
+	next slog.Handler
+}
+
+// NewRedactHandler masks the card numbers, card codes, passwords, tokens and secrets of the records before
+// they reach next, wherever they appear: the message, attribute values, nested groups or logged structs
+func NewRedactHandler(next slog.Handler) slog.Handler {
+	return &redactHandler{next: next}
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	record := slog.NewRecord(r.Time, r.Level, Mask(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		record.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, record)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redactedAttrs[i] = redactAttr(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(redactedAttrs)}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name)}
+}
+
+// Mask hides the card numbers and the secrets found in the text, a card keeps its last 4 digits
+func Mask(text string) string {
+	text = panPattern.ReplaceAllStringFunc(text, maskPAN)
+	text = secretPairPattern.ReplaceAllString(text, "${1}"+redacted)
+	text = bearerPattern.ReplaceAllString(text, "Bearer "+redacted)
+	return jwtPattern.ReplaceAllString(text, redacted)
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if isSensitive(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Mask(a.Value.String()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		attrs := make([]any, len(group))
+		for i, member := range group {
+			attrs[i] = redactAttr(member)
+		}
+		return slog.Group(a.Key, attrs...)
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Mask(err.Error()))
+		}
+		return slog.Any(a.Key, redactAny(a.Value.Any()))
+	default:
+		return a
+	}
+}
+
+// redactAny walks the JSON form of a logged value, so structs are redacted by their field names
+func redactAny(value any) any {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return redacted
+	}
+	var decoded any
+	if err = json.Unmarshal(raw, &decoded); err != nil {
+		return redacted
+	}
+	return redactDecoded(decoded)
+}
+
+func redactDecoded(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, member := range v {
+			if isSensitive(key) {
+				v[key] = redacted
+			} else {
+				v[key] = redactDecoded(member)
+			}
+		}
+		return v
+	case []any:
+		for i, member := range v {
+			v[i] = redactDecoded(member)
+		}
+		return v
+	case string:
+		return Mask(v)
+	default:
+		return v
+	}
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	return sensitiveKeys[key] || strings.Contains(key, "password") || strings.HasSuffix(key, "_token") ||
+		strings.HasSuffix(key, "_secret")
+}
+
+func maskPAN(candidate string) string {
+	digits := make([]byte, 0, len(candidate))
+	for i := 0; i < len(candidate); i++ {
+		if candidate[i] >= '0' && candidate[i] <= '9' {
+			digits = append(digits, candidate[i])
+		}
+	}
+	if !luhn(digits) {
+		return candidate
+	}
+	return strings.Repeat("*", len(digits)-4) + string(digits[len(digits)-4:])
+}
+
+func luhn(digits []byte) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
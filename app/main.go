@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -13,67 +12,136 @@ import (
 	"github.com/alvarezcarlos/payment/app/interface/rest/middelware"
 	"github.com/labstack/echo/v4/middleware"
 
-	"github.com/alvarezcarlos/payment/app/application"
 	"github.com/alvarezcarlos/payment/app/config"
-	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/health"
 	"github.com/alvarezcarlos/payment/app/infrastructure/postgres/connection"
-	repo "github.com/alvarezcarlos/payment/app/infrastructure/postgres/repository"
+	"github.com/alvarezcarlos/payment/app/interface/cli"
 	"github.com/alvarezcarlos/payment/app/interface/rest"
 	"github.com/alvarezcarlos/payment/app/interface/rest/validation"
+	"github.com/alvarezcarlos/payment/app/logging"
+	"github.com/alvarezcarlos/payment/app/metrics"
+	"github.com/alvarezcarlos/payment/app/tracing"
+	"github.com/alvarezcarlos/payment/app/worker"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
+	"github.com/spf13/cobra"
 	"gorm.io/gorm/logger"
 )
 
+const metricsPath = "/metrics"
+
 var validate *validator.Validate
 
 func main() {
-	config.Environment()
-
-	file := setLogger()
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%s\n", err)
+		os.Exit(1)
+	}
+	file := setLogger(cfg.Environment)
 	defer file.Close()
 
-	//DBConnection
-	db := connection.NewPostgresConnection(&gorm.Config{Logger: dbLogger()}, slog.Default())
-	conn := db.GetConnection()
-	migrator := connection.NewMigrate(conn, slog.Default())
-	initDBMigrations(migrator)
-	//Repositories
-	merchantRepo := repo.NewMerchantRepository(conn)
-	paymentRepo := repo.NewPaymentRepository(conn)
-	//UseCases
-	merchantUseCase := application.NewMerchantUseCase(merchantRepo, slog.Default())
-	paymentUseCase := application.NewPaymentUseCase(paymentRepo, slog.Default())
+	app, err := newContainer(cfg)
+	if err != nil {
+		slog.Error("error initializing the application", "error", err.Error())
+		os.Exit(1)
+	}
+	root := cli.NewRootCommand(app.serve,
+		cli.NewMigrateCommand(app.db, app.migrator),
+		cli.NewMerchantCommand(app.merchants),
+		cli.NewPaymentCommand(app.payments),
+		cli.NewSettlementCommand(app.settlement),
+		cli.NewKeysCommand(app.bankAccounts),
+	)
+	if err = root.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// serve runs the API server and the background workers until interrupted
+func (app *container) serve(_ *cobra.Command, _ []string) error {
+	shutdownTracing, err := tracing.Setup(context.Background(), app.cfg.Tracing, app.cfg.AppName, app.cfg.Environment)
+	if err != nil {
+		return err
+	}
+
+	workers := worker.NewRunner(slog.Default())
+	monitor := health.NewMonitor(app.conn, workers)
 	e := echo.New()
 
 	// Middleware
-	e.Use(middleware.Logger())
+	e.Use(tracing.Middleware)
+	e.Use(logging.Middleware(slog.Default()))
 	e.Use(middleware.Recover())
-	authMiddleware := middelware.NewMiddleware()
+	e.Use(metrics.Middleware)
+	e.Use(monitor.Gate(rest.LivenessPath, rest.ReadinessPath, metricsPath))
+	e.GET(metricsPath, metrics.Handler())
+	authMiddleware := middelware.NewMiddleware(app.cfg.SecretKey, app.cfg.AdminAPIKey, app.merchants)
 
 	//Controllers
 	customValidator := validation.NewCustomValidator(validate)
-	rest.NewMerchantController(e, merchantUseCase, customValidator)
-	rest.NewPaymentController(e, paymentUseCase, customValidator, authMiddleware)
-
-	go startServer(e)
+	rest.NewMerchantController(e, app.merchants, customValidator, authMiddleware, app.cfg.SecretKey)
+	rest.NewPaymentController(e, app.payments, customValidator, authMiddleware)
+	rest.NewACSController(e, app.payments)
+	rest.NewExportController(e, app.exports, customValidator, authMiddleware)
+	rest.NewSettlementController(e, app.settlement, authMiddleware)
+	rest.NewBankAccountController(e, app.bankAccounts, customValidator, authMiddleware)
+	rest.NewPricingController(e, app.pricing, customValidator, authMiddleware)
+	rest.NewReserveController(e, app.reserves, customValidator, authMiddleware)
+	rest.NewDisputeController(e, app.disputes, customValidator, authMiddleware)
+	rest.NewReviewController(e, app.reviews, customValidator, authMiddleware)
+	rest.NewRiskListController(e, app.riskLists, customValidator, authMiddleware)
+	rest.NewHealthController(e, monitor)
+
+	//Workers
+	workers.Add(worker.Job{
+		Name:     "settlement",
+		Interval: app.cfg.Settlement.Interval,
+		Run: func(ctx context.Context, now time.Time) error {
+			_, err := app.settlement.Run(ctx, now)
+			return err
+		},
+	})
+	workers.Add(worker.Job{
+		Name:     "disputes",
+		Interval: app.cfg.Disputes.Interval,
+		Run:      app.disputes.Sync,
+	})
+	workers.Add(worker.Job{
+		Name:     "velocity_prune",
+		Interval: app.cfg.Risk.Velocity.PruneInterval,
+		Run:      app.riskEngine.PruneVelocity,
+	})
+	workers.Add(worker.Job{
+		Name:     "export_recovery",
+		Interval: app.cfg.Exports.Interval,
+		Run:      app.exports.FailAbandoned,
+	})
+	workers.Add(worker.Job{
+		Name:     "review_expiry",
+		Interval: app.cfg.Reviews.Interval,
+		Run:      app.reviews.ExpireOverdue,
+	})
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	app.exports.Start(workersCtx)
+	go bootstrap(workersCtx, app.db, app.migrator, app.cfg.Database.AutoMigrate, monitor, workers)
+
+	go startServer(e, app.cfg.Port)
 	gracefulShutdown(e)
+	stopWorkers()
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("error flushing traces", "error", err)
+	}
+	return nil
 }
 
 func dbLogger() logger.Interface {
-	return logger.New(
-		log.New(os.Stdout, "\r\n", log.LstdFlags),
-		logger.Config{
-			SlowThreshold:             100,
-			LogLevel:                  logger.Info,
-			IgnoreRecordNotFoundError: true,
-			Colorful:                  false,
-		},
-	)
+	return logging.NewGormLogger(slog.Default(), 100*time.Millisecond)
 }
 
-func setLogger() *os.File {
+func setLogger(environment string) *os.File {
 	file, err := os.OpenFile("app.log", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
 	if err != nil {
 		panic(err)
@@ -94,7 +162,7 @@ func setLogger() *os.File {
 	}
 
 	var level slog.Level
-	if config.Config().Environment == "local" {
+	if environment == config.LocalEnvironment {
 		level = slog.LevelDebug
 	} else {
 		level = slog.LevelInfo
@@ -106,14 +174,14 @@ func setLogger() *os.File {
 		ReplaceAttr: replace,
 	}
 
-	sLogger := slog.New(slog.NewJSONHandler(w, &slogOptions))
+	sLogger := slog.New(logging.NewContextHandler(logging.NewRedactHandler(slog.NewJSONHandler(w, &slogOptions))))
 	slog.SetDefault(sLogger)
 	return file
 }
 
-func startServer(e *echo.Echo) {
-	if err := e.Start(fmt.Sprintf(":%s", config.Config().Port)); err != nil {
-		e.Logger.Info("shutting down the server")
+func startServer(e *echo.Echo, port string) {
+	if err := e.Start(fmt.Sprintf(":%s", port)); err != nil {
+		slog.Info("shutting down the server", "reason", err.Error())
 	}
 }
 
@@ -126,15 +194,42 @@ func gracefulShutdown(e *echo.Echo) {
 	defer cancel()
 
 	if err := e.Shutdown(ctx); err != nil {
-		e.Logger.Fatal(err)
+		slog.Error("error shutting down the server", "error", err)
+		os.Exit(1)
+	}
+}
+
+// bootstrap waits for the database and migrates it before starting the workers, the server is already
+// answering meanwhile with its readiness failing
+func bootstrap(ctx context.Context, db connection.Connection, migrator connection.MigrateInterface,
+	autoMigrate bool, monitor *health.Monitor, workers *worker.Runner) {
+	if err := db.WaitUntilAvailable(ctx); err != nil {
+		return
+	}
+	err := migrateOnStartup(migrator, autoMigrate)
+	monitor.MigrationsFinished(err)
+	if err != nil {
+		slog.Error("the database schema is not up to date, stopping", "error", err.Error())
+		os.Exit(1)
 	}
+	slog.Info("database migrated, starting workers")
+	workers.Start(ctx)
 }
 
-func initDBMigrations(migrator connection.MigrateInterface) {
-	tables := []interface{}{
-		&entity.Merchant{},
-		&entity.Payment{},
-		&entity.Card{},
+// migrateOnStartup applies the pending migrations when DB_AUTO_MIGRATE is set, the service refuses to run on a
+// schema that is not up to date
+func migrateOnStartup(migrator connection.MigrateInterface, autoMigrate bool) error {
+	if autoMigrate {
+		if _, err := migrator.Up(); err != nil {
+			return err
+		}
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w, first pending %d_%s", connection.ErrPendingMigrations, pending[0].Version, pending[0].Name)
 	}
-	migrator.AutoMigrateAll(tables...)
+	return nil
 }
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+)
+
+type instrumentedAcquirer struct {
+	next repository.Acquirer
+}
+
+// InstrumentAcquirer observes the latency and result of every call to the acquirer
+func InstrumentAcquirer(next repository.Acquirer) repository.Acquirer {
+	return &instrumentedAcquirer{next: next}
+}
+
+func (a *instrumentedAcquirer) Capture(ctx context.Context, payment *entity.Payment, card *entity.Card) repository.AcquirerResponse {
+	start := time.Now()
+	resp := a.next.Capture(ctx, payment, card)
+	observeAcquirer("capture", approval(resp), start)
+	return resp
+}
+
+func (a *instrumentedAcquirer) Refund(ctx context.Context, payment *entity.Payment, card *entity.Card) repository.AcquirerResponse {
+	start := time.Now()
+	resp := a.next.Refund(ctx, payment, card)
+	observeAcquirer("refund", approval(resp), start)
+	return resp
+}
+
+func (a *instrumentedAcquirer) Authenticate(ctx context.Context, payment *entity.Payment, card *entity.Card) repository.ThreeDSResult {
+	start := time.Now()
+	result := a.next.Authenticate(ctx, payment, card)
+	observeAcquirer("authenticate", string(result.Status), start)
+	return result
+}
+
+func (a *instrumentedAcquirer) VerifyChallenge(ctx context.Context, transactionID, code string) bool {
+	start := time.Now()
+	ok := a.next.VerifyChallenge(ctx, transactionID, code)
+	result := "failed"
+	if ok {
+		result = "authenticated"
+	}
+	observeAcquirer("verify_challenge", result, start)
+	return ok
+}
+
+func (a *instrumentedAcquirer) Disputes() []repository.DisputeNotice {
+	start := time.Now()
+	notices := a.next.Disputes()
+	observeAcquirer("disputes", "ok", start)
+	return notices
+}
+
+func (a *instrumentedAcquirer) AckDispute(paymentID uuid.UUID) {
+	start := time.Now()
+	a.next.AckDispute(paymentID)
+	observeAcquirer("ack_dispute", "ok", start)
+}
+
+func approval(resp repository.AcquirerResponse) string {
+	if resp.Approved {
+		return "approved"
+	}
+	return "declined"
+}
+
+func observeAcquirer(operation, result string, start time.Time) {
+	acquirerDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
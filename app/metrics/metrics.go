@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "payments"
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the HTTP requests by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	paymentOutcomes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payment_outcomes_total",
+		Help:      "Payments processed by resulting state and decline code.",
+	}, []string{"state", "decline_code"})
+
+	acquirerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "acquirer_request_duration_seconds",
+		Help:      "Latency of the calls to the acquirer by operation and result.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"operation", "result"})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of the database queries by operation and table.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table", "result"})
+)
+
+// PaymentOutcome counts a payment reaching the state, declineCode is empty unless it was declined
+func PaymentOutcome(state, declineCode string) {
+	paymentOutcomes.WithLabelValues(state, declineCode).Inc()
+}
+
+func observeHTTP(method, route string, status int, seconds float64) {
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(seconds)
+}
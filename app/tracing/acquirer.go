@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/alvarezcarlos/payment/app/domain/entity"
+	"github.com/alvarezcarlos/payment/app/domain/repository"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedAcquirer struct {
+	next repository.Acquirer
+}
+
+// TraceAcquirer opens a client span around every call to the acquirer
+func TraceAcquirer(next repository.Acquirer) repository.Acquirer {
+	return &tracedAcquirer{next: next}
+}
+
+func (a *tracedAcquirer) Capture(ctx context.Context, payment *entity.Payment, card *entity.Card) repository.AcquirerResponse {
+	ctx, span := startAcquirer(ctx, "capture", payment)
+	defer span.End()
+	resp := a.next.Capture(ctx, payment, card)
+	span.SetAttributes(attribute.Bool("acquirer.approved", resp.Approved), attribute.String("acquirer.decline_code", resp.DeclineCode))
+	return resp
+}
+
+func (a *tracedAcquirer) Refund(ctx context.Context, payment *entity.Payment, card *entity.Card) repository.AcquirerResponse {
+	ctx, span := startAcquirer(ctx, "refund", payment)
+	defer span.End()
+	resp := a.next.Refund(ctx, payment, card)
+	span.SetAttributes(attribute.Bool("acquirer.approved", resp.Approved), attribute.String("acquirer.decline_code", resp.DeclineCode))
+	return resp
+}
+
+func (a *tracedAcquirer) Authenticate(ctx context.Context, payment *entity.Payment, card *entity.Card) repository.ThreeDSResult {
+	ctx, span := startAcquirer(ctx, "authenticate", payment)
+	defer span.End()
+	result := a.next.Authenticate(ctx, payment, card)
+	span.SetAttributes(attribute.String("acquirer.three_ds_status", string(result.Status)))
+	return result
+}
+
+func (a *tracedAcquirer) VerifyChallenge(ctx context.Context, transactionID, code string) bool {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "acquirer.verify_challenge", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	ok := a.next.VerifyChallenge(ctx, transactionID, code)
+	span.SetAttributes(attribute.Bool("acquirer.authenticated", ok))
+	return ok
+}
+
+func (a *tracedAcquirer) Disputes() []repository.DisputeNotice {
+	return a.next.Disputes()
+}
+
+func (a *tracedAcquirer) AckDispute(paymentID uuid.UUID) {
+	a.next.AckDispute(paymentID)
+}
+
+func startAcquirer(ctx context.Context, operation string, payment *entity.Payment) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "acquirer."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("payment.id", payment.ID.String()),
+			attribute.Float64("payment.amount", payment.Amount),
+			attribute.String("payment.currency", payment.Currency),
+		))
+}
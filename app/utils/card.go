@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+const (
+	BrandVisa       = "visa"
+	BrandMastercard = "mastercard"
+	BrandAmex       = "amex"
+	BrandDiscover   = "discover"
+	BrandUnknown    = "unknown"
+)
+
+// CardBrand detects the card network from the leading digits of the number
+func CardBrand(number string) string {
+	prefix := func(n int) int {
+		if len(number) < n {
+			return -1
+		}
+		v, err := strconv.Atoi(number[:n])
+		if err != nil {
+			return -1
+		}
+		return v
+	}
+
+	switch {
+	case prefix(1) == 4:
+		return BrandVisa
+	case prefix(2) >= 51 && prefix(2) <= 55, prefix(4) >= 2221 && prefix(4) <= 2720:
+		return BrandMastercard
+	case prefix(2) == 34, prefix(2) == 37:
+		return BrandAmex
+	case prefix(4) == 6011, prefix(2) == 65:
+		return BrandDiscover
+	default:
+		return BrandUnknown
+	}
+}
+
+// binCountries issuing country of the simulated BIN table, the first 6 digits of the card number
+var binCountries = map[string]string{
+	"400000": "US",
+	"411111": "US",
+	"424242": "US",
+	"555555": "US",
+	"378282": "US",
+	"601111": "US",
+	"400005": "GB",
+	"400007": "BR",
+	"400010": "AR",
+	"400012": "DE",
+	"400014": "FR",
+}
+
+// CardCountry ISO 3166-1 alpha-2 code of the card issuing country, empty when the BIN is unknown
+func CardCountry(number string) string {
+	if len(number) < 6 {
+		return ""
+	}
+	return binCountries[number[:6]]
+}
+
+// CardFingerprint identifies a card number without storing it, the same number and key always give the same value
+func CardFingerprint(key, number string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(number))
+	return hex.EncodeToString(mac.Sum(nil))
+}
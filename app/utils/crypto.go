@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Cipher encrypts sensitive values with AES-256-GCM. Ciphertexts are prefixed with the id of the key used so
+// older keys can still decrypt after a rotation
+type Cipher struct {
+	keys    map[string][]byte
+	current string
+}
+
+// NewCipher parses a key ring in the form "id1:base64key1,id2:base64key2", current is the id used to encrypt
+func NewCipher(keyRing, current string) (*Cipher, error) {
+	keys := map[string][]byte{}
+	for _, entry := range strings.Split(keyRing, ",") {
+		id, encoded, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || id == "" {
+			return nil, errors.New("invalid encryption key ring entry")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %s must be 32 bytes base64 encoded", id)
+		}
+		keys[id] = key
+	}
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("current encryption key %s not found in the key ring", current)
+	}
+	return &Cipher{keys: keys, current: current}, nil
+}
+
+// Encrypt seals the plaintext with the current key
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	aead, err := c.aead(c.current)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return c.current + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a ciphertext produced by Encrypt with any key of the ring
+func (c *Cipher) Decrypt(ciphertext string) (string, error) {
+	id, encoded, found := strings.Cut(ciphertext, ":")
+	if !found {
+		return "", errors.New("invalid ciphertext")
+	}
+	aead, err := c.aead(id)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("invalid ciphertext")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// KeyID returns the id of the key a ciphertext was encrypted with
+func (c *Cipher) KeyID(ciphertext string) string {
+	id, _, _ := strings.Cut(ciphertext, ":")
+	return id
+}
+
+// CurrentKeyID the id of the key used for new encryptions
+func (c *Cipher) CurrentKeyID() string {
+	return c.current
+}
+
+func (c *Cipher) aead(id string) (cipher.AEAD, error) {
+	key, ok := c.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %s", id)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -1,9 +1,22 @@
 package utils
 
-import "math/rand"
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"math/rand"
+)
 
 func RandomFloat() float64 {
 	randomNum := rand.Float64()*90000 + 10000
 	randomNum = float64(int(randomNum*100)) / 100
 	return randomNum
 }
+
+// RandomToken returns a hex encoded cryptographically secure random token of n bytes
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
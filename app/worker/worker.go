@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/alvarezcarlos/payment/app/tracing"
+)
+
+// Job background task executed periodically by the Runner
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context, now time.Time) error
+}
+
+// Status last execution result of a job
+type Status struct {
+	Name      string        `json:"name"`
+	Interval  time.Duration `json:"-"`
+	Runs      int           `json:"runs"`
+	LastRun   time.Time     `json:"last_run"`
+	LastError string        `json:"last_error,omitempty"`
+}
+
+// Stalled reports a started job that missed several runs in a row
+func (s Status) Stalled(now time.Time) bool {
+	return s.Runs > 0 && now.Sub(s.LastRun) > stallIntervals*s.Interval
+}
+
+// stallIntervals intervals a job can go without running before it is reported as stalled, long runs of a
+// job delay its next tick
+const stallIntervals = 3
+
+type Runner struct {
+	jobs   []Job
+	logger *slog.Logger
+	mu     sync.RWMutex
+	status map[string]*Status
+}
+
+func NewRunner(logger *slog.Logger) *Runner {
+	return &Runner{logger: logger, status: map[string]*Status{}}
+}
+
+// Add registers a job, it must be called before Start
+func (r *Runner) Add(job Job) {
+	r.jobs = append(r.jobs, job)
+	r.status[job.Name] = &Status{Name: job.Name, Interval: job.Interval}
+}
+
+// Start runs every job right away and then on its interval until the context is cancelled
+func (r *Runner) Start(ctx context.Context) {
+	for _, job := range r.jobs {
+		go r.loop(ctx, job)
+	}
+}
+
+// Statuses returns a snapshot of the status of every job
+func (r *Runner) Statuses() []Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	statuses := make([]Status, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		statuses = append(statuses, *r.status[job.Name])
+	}
+	return statuses
+}
+
+func (r *Runner) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		r.execute(ctx, job)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// execute runs the job once under its own span, the statements of the job are traced as its children
+func (r *Runner) execute(ctx context.Context, job Job) {
+	ctx, span := tracing.Start(ctx, "worker."+job.Name)
+	defer span.End()
+	now := time.Now()
+	err := job.Run(ctx, now)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := r.status[job.Name]
+	status.Runs++
+	status.LastRun = now
+	status.LastError = ""
+	if err != nil {
+		status.LastError = err.Error()
+		tracing.Fail(span, err)
+		r.logger.ErrorContext(ctx, "worker job failed", "job", job.Name, "error", err.Error())
+	}
+}